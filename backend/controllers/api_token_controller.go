@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+type APITokenController struct {
+	tokenService *services.APITokenService
+	userService  *services.UserService
+	rbac         *services.RBACService
+}
+
+func NewAPITokenController(tokenService *services.APITokenService, userService *services.UserService, rbac *services.RBACService) *APITokenController {
+	return &APITokenController{
+		tokenService: tokenService,
+		userService:  userService,
+		rbac:         rbac,
+	}
+}
+
+// GetTokens 获取当前用户的 API token
+func (tc *APITokenController) GetTokens(c *gin.Context) {
+	username, _ := middleware.GetCurrentUsername(c)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(tc.tokenService.List(username)))
+}
+
+// GetAllTokens 获取所有用户的 API token（需要 user:manage 权限）
+func (tc *APITokenController) GetAllTokens(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(tc.tokenService.List("")))
+}
+
+// CreateToken 为当前用户创建 API token
+func (tc *APITokenController) CreateToken(c *gin.Context) {
+	username, _ := middleware.GetCurrentUsername(c)
+	tc.createToken(c, username)
+}
+
+// CreateUserToken 为指定用户（如 CI 使用的服务账号）创建 API token（需要 user:manage 权限）
+func (tc *APITokenController) CreateUserToken(c *gin.Context) {
+	tc.createToken(c, c.Param("username"))
+}
+
+func (tc *APITokenController) createToken(c *gin.Context, username string) {
+	// API token 不能用来签发新的令牌，避免泄露的令牌自我续期
+	if _, viaToken := middleware.GetCurrentAPIToken(c); viaToken {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(403, "不能使用API token创建新的API token"))
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	user, err := tc.userService.GetUser(username)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, services.ErrUserDisabled.Error()))
+		return
+	}
+
+	if err := tc.rbac.ValidateTokenScopes(user, req.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	createdBy, _ := middleware.GetCurrentUsername(c)
+	token, err := tc.tokenService.Create(user, req, createdBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(token))
+}
+
+// RevokeToken 吊销 API token，拥有 user:manage 权限时可以吊销其他用户的令牌
+func (tc *APITokenController) RevokeToken(c *gin.Context) {
+	owner, _ := middleware.GetCurrentUsername(c)
+	if middleware.Authorize(c, tc.rbac, models.PermUserManage, services.Scope{}) {
+		owner = ""
+	}
+
+	if err := tc.tokenService.Revoke(c.Param("id"), owner); err != nil {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+// 导出时默认返回的最大条数
+const auditExportLimit = 10000
+
+type AuditController struct {
+	auditService *services.AuditService
+}
+
+func NewAuditController(auditService *services.AuditService) *AuditController {
+	return &AuditController{auditService: auditService}
+}
+
+// GetAuditRecords 查询审计记录（需要 audit:read 权限），format=csv/json 时以附件形式导出
+func (ac *AuditController) GetAuditRecords(c *gin.Context) {
+	var query models.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "查询参数错误: "+err.Error()))
+		return
+	}
+	if query.Format != "" && query.Limit == 0 {
+		query.Limit = auditExportLimit
+	}
+
+	list, err := ac.auditService.Query(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102-150405"), query.Format)
+	switch query.Format {
+	case "csv":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := writeAuditCSV(c.Writer, list.Items); err != nil {
+			fmt.Printf("导出审计日志失败: %v\n", err)
+		}
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.JSON(http.StatusOK, list.Items)
+	default:
+		c.JSON(http.StatusOK, models.NewSuccessResponse(list))
+	}
+}
+
+// VerifyAuditLog 校验审计日志哈希链是否完整（需要 audit:read 权限）
+func (ac *AuditController) VerifyAuditLog(c *gin.Context) {
+	result, err := ac.auditService.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// writeAuditCSV 按固定列输出审计记录，目标对象格式化为 key=value 列表
+func writeAuditCSV(w io.Writer, records []models.AuditRecord) error {
+	writer := csv.NewWriter(w)
+	header := []string{"seq", "time", "actor", "authMethod", "apiTokenId", "sourceIp", "cluster", "method", "route", "path", "target", "request", "status", "outcome", "error", "durationMs", "prevHash", "hash"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		targets := make([]string, 0, len(record.Target))
+		for key, value := range record.Target {
+			targets = append(targets, key+"="+value)
+		}
+		sort.Strings(targets)
+
+		row := []string{
+			strconv.FormatUint(record.Seq, 10),
+			record.Time.Format(time.RFC3339Nano),
+			record.Actor,
+			record.AuthMethod,
+			record.APITokenID,
+			record.SourceIP,
+			record.Cluster,
+			record.Method,
+			record.Route,
+			record.Path,
+			strings.Join(targets, ";"),
+			string(record.Request),
+			strconv.Itoa(record.Status),
+			record.Outcome,
+			record.Error,
+			strconv.FormatInt(record.DurationMs, 10),
+			record.PrevHash,
+			record.Hash,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
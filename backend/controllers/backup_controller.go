@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+type BackupController struct {
+	backupService    *services.NamespaceBackupService
+	operationTracker *services.OperationTracker
+	rbac             *services.RBACService
+}
+
+func NewBackupController(backupService *services.NamespaceBackupService, operationTracker *services.OperationTracker, rbac *services.RBACService) *BackupController {
+	return &BackupController{
+		backupService:    backupService,
+		operationTracker: operationTracker,
+		rbac:             rbac,
+	}
+}
+
+// GetNamespaceBackups 获取当前集群的命名空间备份列表，只返回有查看权限的命名空间
+// 可选查询参数 storage（local、s3）和 namespace
+func (c *BackupController) GetNamespaceBackups(ctx *gin.Context) {
+	allow := middleware.NamespaceFilter(ctx, c.rbac, models.PermRead)
+	namespace := ctx.Query("namespace")
+	filter := func(ns string) bool {
+		return (namespace == "" || ns == namespace) && (allow == nil || allow(ns))
+	}
+
+	backups, err := c.backupService.List(ctx.Request.Context(), ctx.Query("storage"), filter)
+	if err != nil {
+		c.respondBackupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"backups":  backups,
+		"storages": c.backupService.Storages(),
+	}))
+}
+
+// GetNamespaceBackup 获取备份详情（快照和资源列表，不返回清单内容）
+func (c *BackupController) GetNamespaceBackup(ctx *gin.Context) {
+	summary, err := c.backupService.Summary(ctx.Request.Context(), ctx.Query("storage"), ctx.Param("namespace"), ctx.Param("id"))
+	if err != nil {
+		c.respondBackupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(summary))
+}
+
+// CreateNamespaceBackup 备份命名空间：为所有已绑定的 PVC 创建快照并导出资源清单
+// 快照就绪后才写入备份包，进度通过 /api/operations/:id 查询
+func (c *BackupController) CreateNamespaceBackup(ctx *gin.Context) {
+	var req models.NamespaceBackupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	if err := c.backupService.ValidateBackupRequest(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+	req.CreatedBy = username
+
+	op := c.operationTracker.Start("namespace-backup", middleware.GetCurrentCluster(ctx), req.Namespace, req.Namespace, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	// 后台执行，脱离请求生命周期但保留目标集群
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		summary, err := c.backupService.Backup(bgCtx, req, report)
+		if err != nil {
+			fmt.Printf("Namespace backup of %s failed: %v\n", req.Namespace, err)
+		} else {
+			fmt.Printf("Namespace backup %s created by %s (%d snapshots, %d resources)\n", summary.ID, username, len(summary.Snapshots), len(summary.Resources))
+		}
+		c.operationTracker.Finish(op.ID, err)
+	}()
+
+	op, _ = c.operationTracker.Get(op.ID)
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
+// DeleteNamespaceBackup 删除备份包；deleteSnapshots=true 时同时删除其快照，需要 snapshot:delete 权限
+func (c *BackupController) DeleteNamespaceBackup(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	id := ctx.Param("id")
+	deleteSnapshots := ctx.Query("deleteSnapshots") == "true"
+
+	if deleteSnapshots {
+		scope := services.Scope{Cluster: middleware.GetCurrentCluster(ctx), Namespace: namespace}
+		if !middleware.Authorize(ctx, c.rbac, models.PermSnapshotDelete, scope) {
+			ctx.JSON(http.StatusForbidden, models.NewErrorResponse(403, "权限不足: 删除备份快照需要 "+models.PermSnapshotDelete))
+			return
+		}
+	}
+
+	if err := c.backupService.Delete(ctx.Request.Context(), ctx.Query("storage"), namespace, id, deleteSnapshots); err != nil {
+		c.respondBackupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"message":          "备份已删除",
+		"id":               id,
+		"deletedSnapshots": deleteSnapshots,
+	}))
+}
+
+// RestoreNamespaceBackup 从备份恢复命名空间，可通过 targetNamespace 恢复到新名称
+// 恢复到其他命名空间时还需要目标命名空间的 backup:restore 权限
+func (c *BackupController) RestoreNamespaceBackup(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	id := ctx.Param("id")
+
+	// 请求体可省略，默认恢复到原命名空间
+	var req models.NamespaceRestoreRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	if req.TargetNamespace == "" {
+		req.TargetNamespace = namespace
+	}
+	if errs := validation.IsDNS1123Label(req.TargetNamespace); len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "无效的目标命名空间: "+strings.Join(errs, "; ")))
+		return
+	}
+	if req.TargetNamespace != namespace {
+		scope := services.Scope{Cluster: middleware.GetCurrentCluster(ctx), Namespace: req.TargetNamespace}
+		if !middleware.Authorize(ctx, c.rbac, models.PermBackupRestore, scope) {
+			ctx.JSON(http.StatusForbidden, models.NewErrorResponse(403, "权限不足: 缺少 "+models.PermBackupRestore+"，命名空间 "+req.TargetNamespace))
+			return
+		}
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+	req.CreatedBy = username
+
+	bundle, err := c.backupService.Get(ctx.Request.Context(), ctx.Query("storage"), namespace, id)
+	if err != nil {
+		c.respondBackupError(ctx, err)
+		return
+	}
+
+	op := c.operationTracker.Start("namespace-restore", middleware.GetCurrentCluster(ctx), req.TargetNamespace, id, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		err := c.backupService.Restore(bgCtx, bundle, req.TargetNamespace, report)
+		c.operationTracker.Finish(op.ID, err)
+	}()
+
+	op, _ = c.operationTracker.Get(op.ID)
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
+func (c *BackupController) respondBackupError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrNamespaceBackupNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+		return
+	}
+	if errors.Is(err, services.ErrBackupStorageNotConfigured) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 
+	"k8s-volume-snapshots/middleware"
 	"k8s-volume-snapshots/models"
 	"k8s-volume-snapshots/services"
 
@@ -11,11 +12,13 @@ import (
 
 type ClusterController struct {
 	multiClusterService *services.MultiClusterK8sService
+	userService         *services.UserService
 }
 
-func NewClusterController(multiClusterService *services.MultiClusterK8sService) *ClusterController {
+func NewClusterController(multiClusterService *services.MultiClusterK8sService, userService *services.UserService) *ClusterController {
 	return &ClusterController{
 		multiClusterService: multiClusterService,
+		userService:         userService,
 	}
 }
 
@@ -29,14 +32,15 @@ func (cc *ClusterController) GetClusters(c *gin.Context) {
 
 	response := gin.H{
 		"clusters": clusters,
-		"current":  cc.multiClusterService.GetCurrentCluster(),
+		"current":  middleware.GetCurrentCluster(c),
+		"default":  cc.multiClusterService.GetDefaultCluster(),
 	}
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response))
 }
 
-// GetCurrentCluster 获取当前集群信息
+// GetCurrentCluster 获取本次请求的目标集群信息
 func (cc *ClusterController) GetCurrentCluster(c *gin.Context) {
-	currentCluster := cc.multiClusterService.GetCurrentCluster()
+	currentCluster := middleware.GetCurrentCluster(c)
 	
 	clusters, err := cc.multiClusterService.GetClusters()
 	if err != nil {
@@ -64,7 +68,19 @@ func (cc *ClusterController) GetCurrentCluster(c *gin.Context) {
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response))
 }
 
-// SwitchCluster 切换集群
+// SwitchScope 解析切换集群的权限范围（目标集群），供 RequirePermission 使用
+func (cc *ClusterController) SwitchScope(c *gin.Context) ([]services.Scope, error) {
+	var body struct {
+		ClusterName string `json:"cluster_name"`
+	}
+	if err := middleware.PeekJSONBody(c, &body); err != nil {
+		return nil, err
+	}
+	return []services.Scope{{Cluster: body.ClusterName}}, nil
+}
+
+// SwitchCluster 设置当前用户的默认集群
+// 只影响该用户未显式指定集群（X-Cluster 请求头或 cluster 查询参数）的请求，不影响其他用户
 func (cc *ClusterController) SwitchCluster(c *gin.Context) {
 	var req models.ClusterSwitchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -72,11 +88,22 @@ func (cc *ClusterController) SwitchCluster(c *gin.Context) {
 		return
 	}
 
-	if err := cc.multiClusterService.SwitchCluster(req.ClusterName); err != nil {
+	if err := cc.multiClusterService.ValidateCluster(req.ClusterName); err != nil {
 		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
 		return
 	}
 
+	username, exists := middleware.GetCurrentUsername(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+
+	if err := cc.userService.SetDefaultCluster(username, req.ClusterName); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
 	response := gin.H{
 		"message": "Cluster switched successfully",
 		"current": req.ClusterName,
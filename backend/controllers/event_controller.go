@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SSE 心跳间隔，防止代理因连接空闲而断开
+const eventHeartbeatInterval = 30 * time.Second
+
+type EventController struct {
+	multiClusterService *services.MultiClusterK8sService
+	rbac                *services.RBACService
+	tickets             *services.StreamTicketService
+}
+
+func NewEventController(multiClusterService *services.MultiClusterK8sService, rbac *services.RBACService, tickets *services.StreamTicketService) *EventController {
+	return &EventController{
+		multiClusterService: multiClusterService,
+		rbac:                rbac,
+		tickets:             tickets,
+	}
+}
+
+// CreateStreamTicket 签发事件流的一次性票据，通过 /api/events/stream?ticket=<票据> 建立连接
+func (ec *EventController) CreateStreamTicket(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+
+	ticket, err := ec.tickets.Issue(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"ticket":    ticket,
+		"expiresIn": int(services.StreamTicketTTL.Seconds()),
+	}))
+}
+
+// Stream 通过 Server-Sent Events 推送资源变更事件
+// 订阅请求所在的集群（X-Cluster 请求头或 cluster 查询参数），查询参数：namespace（为空或 all 表示所有命名空间），kinds（逗号分隔的对象类型）
+// 每个事件以 data 行发送 ResourceEvent 的 JSON，连接建立后先发送 ready 事件
+// 客户端消费过慢时服务端会断开连接，客户端重连后应重新加载列表
+func (ec *EventController) Stream(c *gin.Context) {
+	filter := services.EventFilter{
+		Cluster:   middleware.GetCurrentCluster(c),
+		Namespace: c.Query("namespace"),
+		Kinds:     services.ParseEventKinds(c.Query("kinds")),
+		// 只推送用户有权查看的命名空间中的事件
+		AllowNamespace: middleware.NamespaceFilter(c, ec.rbac, models.PermRead),
+	}
+	if filter.AllowNamespace != nil && filter.Namespace != "" && filter.Namespace != "all" && !filter.AllowNamespace(filter.Namespace) {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(403, "无权查看命名空间 "+filter.Namespace))
+		return
+	}
+
+	events := ec.multiClusterService.Events()
+	sub := events.Subscribe(filter)
+	defer events.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 关闭 nginx 等反向代理的响应缓冲
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// 告知客户端订阅已建立及生效的集群
+	fmt.Fprintf(c.Writer, "event: ready\ndata: {\"cluster\":%q}\n\n", filter.Cluster)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				fmt.Printf("序列化资源事件失败: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+			c.Writer.Flush()
+		}
+	}
+}
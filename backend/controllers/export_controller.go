@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+type ExportController struct {
+	dataMover        *services.DataMoverService
+	operationTracker *services.OperationTracker
+	rbac             *services.RBACService
+}
+
+func NewExportController(dataMover *services.DataMoverService, operationTracker *services.OperationTracker, rbac *services.RBACService) *ExportController {
+	return &ExportController{
+		dataMover:        dataMover,
+		operationTracker: operationTracker,
+		rbac:             rbac,
+	}
+}
+
+// GetExports 获取当前集群备份目录中的导出记录，只返回有查看权限的命名空间
+// 可选查询参数 namespace
+func (c *ExportController) GetExports(ctx *gin.Context) {
+	allow := middleware.NamespaceFilter(ctx, c.rbac, models.PermRead)
+	namespace := ctx.Query("namespace")
+	filter := func(ns string) bool {
+		return (namespace == "" || ns == namespace) && (allow == nil || allow(ns))
+	}
+
+	exports, err := c.dataMover.List(ctx.Request.Context(), filter)
+	if err != nil {
+		c.respondExportError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(exports))
+}
+
+// GetExport 获取导出记录详情
+func (c *ExportController) GetExport(ctx *gin.Context) {
+	artifact, err := c.dataMover.Get(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("id"))
+	if err != nil {
+		c.respondExportError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(artifact))
+}
+
+// ExportVolumeSnapshot 将快照数据导出到对象存储：从快照恢复临时 PVC，由搬运 Job 写入 restic 仓库
+// 进度通过 /api/operations/:id 查询
+func (c *ExportController) ExportVolumeSnapshot(ctx *gin.Context) {
+	if !c.dataMover.Enabled() {
+		c.respondExportError(ctx, services.ErrDataMoverDisabled)
+		return
+	}
+
+	if !c.authorizeSharedCredentials(ctx, models.PermBackupCreate) {
+		return
+	}
+
+	namespace := ctx.Param("namespace")
+	name := ctx.Param("name")
+
+	// 请求体可省略，默认沿用源 PVC 的存储类
+	var req models.ExportSnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+	req.CreatedBy = username
+
+	op := c.operationTracker.Start("export", middleware.GetCurrentCluster(ctx), namespace, name, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	// 后台执行，脱离请求生命周期但保留目标集群
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		artifact, err := c.dataMover.Export(bgCtx, namespace, name, req, report)
+		if err != nil {
+			fmt.Printf("Export of snapshot %s/%s failed: %v\n", namespace, name, err)
+		} else {
+			fmt.Printf("Snapshot %s/%s exported as %s by %s (%d bytes added)\n", namespace, name, artifact.ID, username, artifact.AddedBytes)
+		}
+		c.operationTracker.Finish(op.ID, err)
+	}()
+
+	op, _ = c.operationTracker.Get(op.ID)
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
+// DeleteExport 删除导出记录；purge=true 时通过搬运 Job 从 restic 仓库中删除数据，异步执行
+func (c *ExportController) DeleteExport(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	id := ctx.Param("id")
+
+	if ctx.Query("purge") != "true" {
+		if err := c.dataMover.Delete(ctx.Request.Context(), namespace, id, false, nil); err != nil {
+			c.respondExportError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+			"message": "导出记录已删除",
+			"id":      id,
+		}))
+		return
+	}
+
+	if !c.authorizeSharedCredentials(ctx, models.PermBackupDelete) {
+		return
+	}
+
+	// 先确认记录存在，避免为不存在的导出启动后台操作
+	if _, err := c.dataMover.Get(ctx.Request.Context(), namespace, id); err != nil {
+		c.respondExportError(ctx, err)
+		return
+	}
+
+	username, _ := middleware.GetCurrentUsername(ctx)
+	op := c.operationTracker.Start("export-purge", middleware.GetCurrentCluster(ctx), namespace, id, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		err := c.dataMover.Delete(bgCtx, namespace, id, true, report)
+		c.operationTracker.Finish(op.ID, err)
+	}()
+
+	op, _ = c.operationTracker.Get(op.ID)
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
+// authorizeSharedCredentials 搬运 Job 使用共享密钥时，命名空间管理员能从 Job 中读取全局凭据，
+// 因此要求不限制集群和命名空间的权限；无权限时写入 403 并返回 false
+func (c *ExportController) authorizeSharedCredentials(ctx *gin.Context, verb string) bool {
+	if !c.dataMover.SharedCredentials() || middleware.Authorize(ctx, c.rbac, verb, services.Scope{}) {
+		return true
+	}
+	ctx.JSON(http.StatusForbidden, models.NewErrorResponse(403, "权限不足: 搬运 Job 使用共享凭据，需要全局 "+verb+" 权限"))
+	return false
+}
+
+func (c *ExportController) respondExportError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrExportNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+		return
+	}
+	if errors.Is(err, services.ErrDataMoverDisabled) {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+}
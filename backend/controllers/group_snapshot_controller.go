@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+type GroupSnapshotController struct {
+	k8sService       services.K8sServiceInterface
+	operationTracker *services.OperationTracker
+}
+
+func NewGroupSnapshotController(k8sService services.K8sServiceInterface, operationTracker *services.OperationTracker) *GroupSnapshotController {
+	return &GroupSnapshotController{
+		k8sService:       k8sService,
+		operationTracker: operationTracker,
+	}
+}
+
+// GetGroupSnapshots 获取命名空间中的快照组列表
+func (c *GroupSnapshotController) GetGroupSnapshots(ctx *gin.Context) {
+	groups, err := c.k8sService.GetGroupSnapshots(ctx.Request.Context(), ctx.Param("namespace"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(groups))
+}
+
+// GetGroupSnapshot 获取快照组详情及汇总状态
+func (c *GroupSnapshotController) GetGroupSnapshot(ctx *gin.Context) {
+	group, err := c.k8sService.GetGroupSnapshot(ctx.Request.Context(), ctx.Param("namespace"), ctx.Param("name"))
+	if err != nil {
+		c.respondGroupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(group))
+}
+
+// CreateGroupSnapshot 在同一时间点为多个 PVC 创建快照组
+func (c *GroupSnapshotController) CreateGroupSnapshot(ctx *gin.Context) {
+	var req models.CreateGroupSnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	if err := services.ValidateGroupSnapshotRequest(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+	req.CreatedBy = username
+
+	group, hookResults, err := c.k8sService.CreateGroupSnapshot(ctx.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrGroupSnapshotExists) {
+			ctx.JSON(http.StatusConflict, models.NewErrorResponse(409, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "创建快照组失败: " + err.Error(),
+			Data:    models.GroupSnapshotInfo{Name: req.Name, Namespace: req.Namespace, Hooks: hookResults},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.NewSuccessResponse(group))
+}
+
+// DeleteGroupSnapshot 删除快照组及其所有成员快照
+func (c *GroupSnapshotController) DeleteGroupSnapshot(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	name := ctx.Param("name")
+
+	if err := c.k8sService.DeleteGroupSnapshot(ctx.Request.Context(), namespace, name); err != nil {
+		c.respondGroupError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
+		"message": "删除请求已提交，成员快照删除可能需要一些时间完成",
+		"name":    name,
+	}))
+}
+
+// RestoreGroupSnapshot 从快照组恢复出一组新的 PVC
+// 快照组必须整体就绪；PVC 创建后在后台等待绑定，进度通过 /api/operations/:id 查询
+func (c *GroupSnapshotController) RestoreGroupSnapshot(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	name := ctx.Param("name")
+
+	var req models.RestoreGroupSnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+	req.CreatedBy = username
+
+	group, err := c.k8sService.GetGroupSnapshot(ctx.Request.Context(), namespace, name)
+	if err != nil {
+		c.respondGroupError(ctx, err)
+		return
+	}
+	if group.Phase != models.GroupSnapshotPhaseReady {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, fmt.Sprintf("快照组状态为 %s，全部成员就绪后才能恢复", group.Phase)))
+		return
+	}
+
+	targets, err := groupRestoreTargets(group.Members, req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	op := c.operationTracker.Start("group-restore", middleware.GetCurrentCluster(ctx), namespace, name, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	// 先创建所有 PVC，任一失败时停止并报告已创建的 PVC
+	var created []string
+	for i, member := range group.Members {
+		step := "创建 PVC " + targets[i]
+		report(step, models.OperationStatusRunning, "正在从快照 "+member.SnapshotName+" 创建 PVC "+targets[i])
+		_, err := c.k8sService.RestoreVolumeSnapshot(ctx.Request.Context(), namespace, member.SnapshotName, models.RestoreVolumeSnapshotRequest{
+			PVCName:          targets[i],
+			StorageClassName: req.StorageClassName,
+			CreatedBy:        username,
+		})
+		if err != nil {
+			report(step, models.OperationStatusFailed, err.Error())
+			c.operationTracker.Finish(op.ID, err)
+			message := "恢复快照组失败: " + err.Error()
+			if len(created) > 0 {
+				message += "（已创建 PVC: " + strings.Join(created, ", ") + "）"
+			}
+			ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, message))
+			return
+		}
+		report(step, models.OperationStatusSucceeded, "PVC "+targets[i]+" 已创建")
+		created = append(created, targets[i])
+	}
+
+	// 后台等待所有 PVC 绑定，脱离请求生命周期但保留目标集群
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		var firstErr error
+		for _, pvcName := range created {
+			if err := services.WaitForPVCBound(bgCtx, c.k8sService, namespace, pvcName, report); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		c.operationTracker.Finish(op.ID, firstErr)
+	}()
+
+	op, _ = c.operationTracker.Get(op.ID)
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
+// groupRestoreTargets 按成员顺序计算恢复出的 PVC 名称
+func groupRestoreTargets(members []models.GroupSnapshotMember, req models.RestoreGroupSnapshotRequest) ([]string, error) {
+	targets := make([]string, len(members))
+	used := make(map[string]bool)
+	for i, member := range members {
+		target := req.PVCNames[member.SnapshotName]
+		if member.PVCName != "" && req.PVCNames[member.PVCName] != "" {
+			target = req.PVCNames[member.PVCName]
+		}
+		if target == "" && req.Suffix != "" {
+			base := member.PVCName
+			if base == "" {
+				base = member.SnapshotName
+			}
+			target = base + "-" + req.Suffix
+		}
+		if target == "" {
+			return nil, fmt.Errorf("未指定快照 %s 恢复出的 PVC 名称，请设置 suffix 或 pvcNames", member.SnapshotName)
+		}
+		if used[target] {
+			return nil, fmt.Errorf("PVC 名称 %s 重复", target)
+		}
+		used[target] = true
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+func (c *GroupSnapshotController) respondGroupError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrGroupSnapshotNotFound) {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+type JWTKeyController struct {
+	keyManager *services.JWTKeyManager
+}
+
+func NewJWTKeyController(keyManager *services.JWTKeyManager) *JWTKeyController {
+	return &JWTKeyController{keyManager: keyManager}
+}
+
+// GetJWKS 公开的 JWKS，供其他服务验证 RS256/EdDSA 签名的访问令牌
+// 按 RFC 7517 直接返回密钥集，不使用统一响应结构
+func (kc *JWTKeyController) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, kc.keyManager.JWKS())
+}
+
+// GetKeys 获取签名密钥列表（不包含密钥内容，需要 user:manage 权限）
+func (kc *JWTKeyController) GetKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(kc.keyManager.Keys()))
+}
+
+// RotateKey 立即轮换签名密钥（需要 user:manage 权限），已签发的令牌在旧密钥退役前仍然有效
+func (kc *JWTKeyController) RotateKey(c *gin.Context) {
+	if err := kc.keyManager.Rotate(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(kc.keyManager.Keys()))
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+type RBACController struct {
+	rbac        *services.RBACService
+	userService *services.UserService
+}
+
+func NewRBACController(rbac *services.RBACService, userService *services.UserService) *RBACController {
+	return &RBACController{
+		rbac:        rbac,
+		userService: userService,
+	}
+}
+
+// GetPermissions 获取当前用户的授权来源（角色和匹配的角色绑定）
+func (rc *RBACController) GetPermissions(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"verbs":       models.AllPermissions,
+		"permissions": rc.rbac.EffectivePermissions(user),
+	}))
+}
+
+// GetRoles 获取所有角色
+func (rc *RBACController) GetRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rc.rbac.GetRoles()))
+}
+
+// CreateRole 创建自定义角色
+func (rc *RBACController) CreateRole(c *gin.Context) {
+	var req models.Role
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	role, err := rc.rbac.CreateRole(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(role))
+}
+
+// UpdateRole 更新自定义角色
+func (rc *RBACController) UpdateRole(c *gin.Context) {
+	var req models.Role
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	role, err := rc.rbac.UpdateRole(c.Param("name"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(role))
+}
+
+// DeleteRole 删除自定义角色
+func (rc *RBACController) DeleteRole(c *gin.Context) {
+	if err := rc.rbac.DeleteRole(c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
+
+// GetBindings 获取所有角色绑定
+func (rc *RBACController) GetBindings(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(rc.rbac.GetBindings()))
+}
+
+// CreateBinding 创建角色绑定
+func (rc *RBACController) CreateBinding(c *gin.Context) {
+	var req models.RoleBinding
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	username, _ := middleware.GetCurrentUsername(c)
+	binding, err := rc.rbac.CreateBinding(req, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(binding))
+}
+
+// UpdateBinding 更新角色绑定
+func (rc *RBACController) UpdateBinding(c *gin.Context) {
+	var req models.RoleBinding
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	binding, err := rc.rbac.UpdateBinding(c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(binding))
+}
+
+// DeleteBinding 删除角色绑定
+func (rc *RBACController) DeleteBinding(c *gin.Context) {
+	if err := rc.rbac.DeleteBinding(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
+
+// UpdateUserGroups 设置用户所属组
+func (rc *RBACController) UpdateUserGroups(c *gin.Context) {
+	var req models.UpdateUserGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	user, err := rc.userService.SetGroups(c.Param("username"), req.Groups)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(user))
+}
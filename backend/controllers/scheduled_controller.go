@@ -2,19 +2,25 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
 	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"k8s-volume-snapshots/middleware"
 	"k8s-volume-snapshots/models"
@@ -24,10 +30,17 @@ import (
 const (
 	// 定时任务数据存储文件路径
 	TaskDataFile = "/data/scheduled_tasks.json"
+	// 选择器任务在单个集群中同时创建和等待的快照数
+	selectorSnapshotConcurrency = 10
 )
 
 type ScheduledController struct {
 	k8sService     services.K8sServiceInterface
+	runHistory     *services.RunHistoryService
+	tracker        *services.SnapshotTracker
+	dataMover      *services.DataMoverService
+	events         *services.EventHub
+	rbac           *services.RBACService
 	cron           *cron.Cron
 	scheduledTasks map[string]*models.ScheduledSnapshot
 	cronEntries    map[string]cron.EntryID
@@ -35,12 +48,17 @@ type ScheduledController struct {
 	dataFile       string
 }
 
-func NewScheduledController(k8sService services.K8sServiceInterface) *ScheduledController {
+func NewScheduledController(k8sService services.K8sServiceInterface, runHistory *services.RunHistoryService, tracker *services.SnapshotTracker, dataMover *services.DataMoverService, events *services.EventHub, rbac *services.RBACService) *ScheduledController {
 	c := cron.New(cron.WithSeconds())
 	c.Start()
 
 	controller := &ScheduledController{
 		k8sService:     k8sService,
+		runHistory:     runHistory,
+		tracker:        tracker,
+		dataMover:      dataMover,
+		events:         events,
+		rbac:           rbac,
 		cron:           c,
 		scheduledTasks: make(map[string]*models.ScheduledSnapshot),
 		cronEntries:    make(map[string]cron.EntryID),
@@ -82,6 +100,23 @@ func (c *ScheduledController) loadTasks() {
 	}
 
 	fmt.Printf("成功加载 %d 个定时任务\n", len(tasks))
+
+	// 旧版本创建的任务未指定目标集群，执行时依赖全局当前集群；迁移为固定到默认集群
+	if multiClusterService, ok := c.k8sService.(services.MultiClusterK8sServiceInterface); ok {
+		migrated := 0
+		for _, task := range c.scheduledTasks {
+			if len(task.TargetClusters) == 0 {
+				task.TargetClusters = []string{multiClusterService.GetDefaultCluster()}
+				migrated++
+			}
+		}
+		if migrated > 0 {
+			fmt.Printf("已将 %d 个未指定目标集群的定时任务固定到默认集群 %s\n", migrated, multiClusterService.GetDefaultCluster())
+			if err := c.saveTasks(); err != nil {
+				fmt.Printf("保存定时任务数据失败: %v\n", err)
+			}
+		}
+	}
 }
 
 // saveTasks 保存任务数据到文件
@@ -136,6 +171,10 @@ func (c *ScheduledController) GetScheduledSnapshots(ctx *gin.Context) {
 	// 初始化为空切片而不是nil，确保JSON序列化为[]而不是null
 	tasks := []models.ScheduledSnapshot{}
 	for _, task := range c.scheduledTasks {
+		// 只返回用户在任一目标集群中有权查看的任务
+		if !c.canReadTask(ctx, task) {
+			continue
+		}
 		// 更新下次执行时间
 		if entryID, exists := c.cronEntries[task.ID]; exists && task.Enabled {
 			if entry := c.cron.Entry(entryID); entry.Valid() {
@@ -172,8 +211,37 @@ func (c *ScheduledController) CreateScheduledSnapshot(ctx *gin.Context) {
 		return
 	}
 
-	// 生成唯一 ID
-	req.ID = fmt.Sprintf("%s-%s-%d", req.Namespace, req.Name, time.Now().Unix())
+	// 验证保留策略
+	if err := services.ValidateRetentionPolicy(req.Retention); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 验证目标 PVC
+	if err := services.ValidateScheduledTarget(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 验证快照钩子
+	if err := services.ValidateSnapshotHooks(req.Hooks); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	if req.ExportData && !c.dataMover.Enabled() {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, services.ErrDataMoverDisabled.Error()))
+		return
+	}
+
+	c.pinTargetClusters(ctx, &req)
+
+	// 生成唯一 ID（同时用作快照标签值，命名空间选择器任务没有命名空间前缀）
+	if req.Namespace != "" {
+		req.ID = fmt.Sprintf("%s-%s-%d", req.Namespace, req.Name, time.Now().Unix())
+	} else {
+		req.ID = fmt.Sprintf("%s-%d", req.Name, time.Now().Unix())
+	}
 	req.CreatedBy = username // 设置创建者
 	req.CreatedAt = time.Now()
 	req.UpdatedAt = time.Now()
@@ -235,16 +303,45 @@ func (c *ScheduledController) UpdateScheduledSnapshot(ctx *gin.Context) {
 		return
 	}
 
+	// 验证保留策略
+	if err := services.ValidateRetentionPolicy(req.Retention); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 验证目标 PVC
+	if err := services.ValidateScheduledTarget(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 验证快照钩子
+	if err := services.ValidateSnapshotHooks(req.Hooks); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	if req.ExportData && !c.dataMover.Enabled() {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, services.ErrDataMoverDisabled.Error()))
+		return
+	}
+
+	c.pinTargetClusters(ctx, &req)
+
 	// 移除旧的定时任务
 	if entryID, exists := c.cronEntries[id]; exists {
 		c.cron.Remove(entryID)
 		delete(c.cronEntries, id)
 	}
 
-	// 更新任务信息
+	// 更新任务信息（保留执行状态）
 	req.ID = id
 	req.CreatedAt = existingTask.CreatedAt
+	req.CreatedBy = existingTask.CreatedBy
 	req.UpdatedAt = time.Now()
+	req.LastExecuted = existingTask.LastExecuted
+	req.LastStatus = existingTask.LastStatus
+	req.ConsecutiveFailures = existingTask.ConsecutiveFailures
 
 	// 更新内存中的任务
 	c.scheduledTasks[id] = &req
@@ -290,8 +387,11 @@ func (c *ScheduledController) DeleteScheduledSnapshot(ctx *gin.Context) {
 		delete(c.cronEntries, id)
 	}
 
-	// 删除任务记录
+	// 删除任务记录及执行历史
 	delete(c.scheduledTasks, id)
+	if err := c.runHistory.DeleteTask(id); err != nil {
+		fmt.Printf("删除定时任务执行历史失败: %v\n", err)
+	}
 
 	// 保存数据到文件
 	if err := c.saveTasks(); err != nil {
@@ -302,6 +402,33 @@ func (c *ScheduledController) DeleteScheduledSnapshot(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(nil))
 }
 
+// GetScheduledRuns 分页获取定时任务的执行历史
+func (c *ScheduledController) GetScheduledRuns(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	c.mutex.RLock()
+	_, exists := c.scheduledTasks[id]
+	c.mutex.RUnlock()
+
+	if !exists {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "Scheduled task not found"))
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "Invalid page"))
+		return
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "Invalid pageSize (1-100)"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(c.runHistory.List(id, page, pageSize)))
+}
+
 // ToggleScheduledSnapshot 启用/禁用定时任务
 func (c *ScheduledController) ToggleScheduledSnapshot(ctx *gin.Context) {
 	id := ctx.Param("id")
@@ -347,7 +474,7 @@ func (c *ScheduledController) ToggleScheduledSnapshot(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(task))
 }
 
-// executeSnapshot 执行快照创建
+// executeSnapshot 执行快照创建，并记录执行历史
 func (c *ScheduledController) executeSnapshot(task *models.ScheduledSnapshot) {
 	now := time.Now()
 
@@ -358,61 +485,128 @@ func (c *ScheduledController) executeSnapshot(task *models.ScheduledSnapshot) {
 	// 生成快照名称（添加时间戳）
 	snapshotName := fmt.Sprintf("%s-%d", task.Name, now.Unix())
 
-	// 确定目标集群列表
-	targetClusters := task.TargetClusters
-	if len(targetClusters) == 0 {
-		// 如果没有指定目标集群，只在当前集群执行
-		c.executeSnapshotInCurrentCluster(task, snapshotName, now)
-		return
+	run := models.ScheduledRun{
+		ID:           fmt.Sprintf("%s-%d", task.ID, now.UnixNano()),
+		TaskID:       task.ID,
+		TaskName:     task.Name,
+		SnapshotName: snapshotName,
+		Status:       models.RunStatusRunning,
+		StartedAt:    now,
+		Clusters:     []models.ClusterRunResult{},
 	}
+	c.recordRun(task, run, models.EventTypeAdded)
 
-	// 检查是否支持多集群操作
-	multiClusterService, ok := c.k8sService.(services.MultiClusterK8sServiceInterface)
-	if !ok {
-		// 如果不是多集群服务，只在当前集群执行
-		fmt.Printf("Multi-cluster operation not supported, executing in current cluster only\n")
-		c.executeSnapshotInCurrentCluster(task, snapshotName, now)
-		return
-	}
+	// 确定目标集群列表
+	targetClusters := c.targetClusters(task)
 
 	// 并发在多个集群中创建快照
+	results := make([]models.ClusterRunResult, len(targetClusters))
 	var wg sync.WaitGroup
-	errorChan := make(chan error, len(targetClusters))
-
-	for _, clusterName := range targetClusters {
+	for i, clusterName := range targetClusters {
 		wg.Add(1)
-		go func(cluster string) {
+		go func(i int, cluster string) {
 			defer wg.Done()
-			err := c.executeSnapshotInCluster(multiClusterService, task, snapshotName, cluster, now)
-			if err != nil {
-				errorChan <- fmt.Errorf("cluster %s: %v", cluster, err)
-			}
-		}(clusterName)
+			results[i] = c.executeSnapshotInCluster(task, snapshotName, cluster, now)
+		}(i, clusterName)
 	}
-
 	wg.Wait()
-	close(errorChan)
 
-	// 收集错误信息
+	run.Clusters = results
+	c.finishRun(task, &run)
+}
+
+// finishRun 汇总各集群结果，更新任务状态并保存执行记录
+func (c *ScheduledController) finishRun(task *models.ScheduledSnapshot, run *models.ScheduledRun) {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
 	var errors []string
-	for err := range errorChan {
-		errors = append(errors, err.Error())
+	succeeded, partial := 0, 0
+	for _, result := range run.Clusters {
+		if result.Status == models.RunStatusSuccess {
+			succeeded++
+			continue
+		}
+		if result.Status == models.RunStatusPartial {
+			partial++
+		}
+		if result.Cluster != "" {
+			errors = append(errors, fmt.Sprintf("cluster %s: %s", result.Cluster, result.Error))
+		} else {
+			errors = append(errors, result.Error)
+		}
+	}
+
+	switch {
+	case succeeded == len(run.Clusters):
+		run.Status = models.RunStatusSuccess
+	case succeeded == 0 && partial == 0:
+		run.Status = models.RunStatusFailed
+	default:
+		run.Status = models.RunStatusPartial
 	}
+	run.ErrorMessage = strings.Join(errors, "; ")
+
+	c.recordRun(task, *run, models.EventTypeModified)
 
 	if len(errors) > 0 {
-		fmt.Printf("Failed to create scheduled snapshot %s in some clusters: %v\n", snapshotName, errors)
+		fmt.Printf("Failed to create scheduled snapshot %s in some clusters: %v\n", run.SnapshotName, errors)
 	} else {
-		fmt.Printf("Successfully created scheduled snapshot %s in all target clusters: %v\n", snapshotName, targetClusters)
+		fmt.Printf("Successfully created scheduled snapshot %s (created by: %s)\n", run.SnapshotName, task.CreatedBy)
+	}
+
+	// 更新任务状态（任务可能已被更新替换，按 ID 查找当前对象）
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	current, exists := c.scheduledTasks[task.ID]
+	if !exists {
+		return
+	}
+	current.LastStatus = run.Status
+	if run.Status == models.RunStatusSuccess {
+		current.ConsecutiveFailures = 0
+	} else {
+		current.ConsecutiveFailures++
+	}
+
+	if err := c.saveTasks(); err != nil {
+		fmt.Printf("保存定时任务数据失败: %v\n", err)
 	}
 }
 
-// executeSnapshotInCurrentCluster 在当前集群中执行快照创建
-func (c *ScheduledController) executeSnapshotInCurrentCluster(task *models.ScheduledSnapshot, snapshotName string, now time.Time) {
+// recordRun 保存执行记录并推送执行记录变更事件
+func (c *ScheduledController) recordRun(task *models.ScheduledSnapshot, run models.ScheduledRun, eventType string) {
+	if err := c.runHistory.Record(run); err != nil {
+		fmt.Printf("保存定时任务执行记录失败: %v\n", err)
+	}
+
+	c.events.Publish(models.ResourceEvent{
+		Type:      eventType,
+		Kind:      models.EventKindScheduledRun,
+		Namespace: task.Namespace,
+		Name:      run.ID,
+		Object:    run,
+		Timestamp: time.Now(),
+	})
+}
+
+// executeSnapshotInCluster 在指定集群（单集群服务时为空）中执行快照创建
+func (c *ScheduledController) executeSnapshotInCluster(task *models.ScheduledSnapshot, snapshotName, clusterName string, now time.Time) models.ClusterRunResult {
+	if services.IsSelectorTask(task) {
+		return c.executeSelectorInCluster(task, snapshotName, clusterName, now)
+	}
+
+	result := models.ClusterRunResult{
+		Cluster: clusterName,
+		Status:  models.RunStatusFailed,
+	}
+
 	// 验证PVC是否存在
-	pvcs, err := c.k8sService.GetPVCs(context.Background(), task.Namespace)
+	pvcs, err := c.getPVCsInCluster(clusterName, task.Namespace)
 	if err != nil {
-		fmt.Printf("Failed to get PVCs for scheduled snapshot %s: %v\n", snapshotName, err)
-		return
+		result.Error = fmt.Sprintf("failed to get PVCs: %v", err)
+		return result
 	}
 
 	pvcExists := false
@@ -424,64 +618,438 @@ func (c *ScheduledController) executeSnapshotInCurrentCluster(task *models.Sched
 	}
 
 	if !pvcExists {
-		fmt.Printf("Failed to create scheduled snapshot %s: PVC '%s' not found in namespace '%s'\n", snapshotName, task.PVCName, task.Namespace)
-		return
+		result.Error = fmt.Sprintf("PVC '%s' not found in namespace '%s'", task.PVCName, task.Namespace)
+		return result
+	}
+
+	pvcResult := c.snapshotPVC(task, clusterName, task.Namespace, task.PVCName, snapshotName, now)
+	result.Hooks = pvcResult.Hooks
+	result.Error = pvcResult.Error
+	result.BoundContentName = pvcResult.BoundContentName
+	result.RestoreSize = pvcResult.RestoreSize
+	result.ExportArtifactID = pvcResult.ExportArtifactID
+	if pvcResult.Status != models.RunStatusSuccess {
+		return result
 	}
+	result.ReadyToUse = true
+	result.ReadyDurationSeconds = pvcResult.ReadyDurationSeconds
+	result.Status = models.RunStatusSuccess
 
-	// 创建 VolumeSnapshot
-	vs := c.createVolumeSnapshotSpec(task, snapshotName, now)
+	// 按保留策略清理旧快照
+	result.Pruned = c.pruneAndLog(task, clusterName)
+	return result
+}
 
-	_, err = c.k8sService.CreateVolumeSnapshot(context.Background(), task.Namespace, vs)
+// executeSelectorInCluster 解析选择器得到集群中当前匹配的 PVC，逐个创建快照并记录每个 PVC 的结果
+func (c *ScheduledController) executeSelectorInCluster(task *models.ScheduledSnapshot, snapshotName, clusterName string, now time.Time) models.ClusterRunResult {
+	result := models.ClusterRunResult{
+		Cluster: clusterName,
+		Status:  models.RunStatusFailed,
+	}
+
+	pvcs, err := c.resolveTaskPVCs(task, clusterName)
 	if err != nil {
-		fmt.Printf("Failed to create scheduled snapshot %s: %v\n", snapshotName, err)
-	} else {
-		fmt.Printf("Successfully created scheduled snapshot: %s (created by: %s)\n", snapshotName, task.CreatedBy)
+		result.Error = err.Error()
+		return result
+	}
+	if len(pvcs) == 0 {
+		result.Error = "no bound PVCs match the selector"
+		return result
+	}
+
+	// 限制并发，避免一次向 CSI 驱动提交过多快照
+	results := make([]models.PVCRunResult, len(pvcs))
+	semaphore := make(chan struct{}, selectorSnapshotConcurrency)
+	var wg sync.WaitGroup
+	for i, pvc := range pvcs {
+		wg.Add(1)
+		go func(i int, pvc corev1.PersistentVolumeClaim) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = c.snapshotPVC(task, clusterName, pvc.Namespace, pvc.Name, selectorSnapshotName(snapshotName, pvc.Name), now)
+		}(i, pvc)
+	}
+	wg.Wait()
+	result.PVCs = results
+
+	var errors []string
+	for _, pvcResult := range results {
+		if pvcResult.Status != models.RunStatusSuccess {
+			errors = append(errors, fmt.Sprintf("%s/%s: %s", pvcResult.Namespace, pvcResult.PVCName, pvcResult.Error))
+		}
+	}
+
+	switch {
+	case len(errors) == 0:
+		result.Status = models.RunStatusSuccess
+		result.ReadyToUse = true
+	case len(errors) < len(results):
+		result.Status = models.RunStatusPartial
+	}
+	if len(errors) > 0 {
+		result.Error = fmt.Sprintf("%d of %d PVCs failed: %s", len(errors), len(results), strings.Join(errors, "; "))
 	}
+
+	// 至少一个快照成功时按保留策略清理，每个 PVC 分别计算
+	if len(errors) < len(results) {
+		result.Pruned = c.pruneAndLog(task, clusterName)
+	}
+	return result
 }
 
-// executeSnapshotInCluster 在指定集群中执行快照创建
-func (c *ScheduledController) executeSnapshotInCluster(multiClusterService services.MultiClusterK8sServiceInterface, task *models.ScheduledSnapshot, snapshotName, clusterName string, now time.Time) error {
-	// 验证指定集群中的PVC是否存在
-	pvcs, err := multiClusterService.GetPVCsInCluster(context.Background(), clusterName, task.Namespace)
+// resolveTaskPVCs 在指定集群中解析选择器任务当前匹配的 PVC，每次执行时重新解析以纳入新建的 PVC
+func (c *ScheduledController) resolveTaskPVCs(task *models.ScheduledSnapshot, clusterName string) ([]corev1.PersistentVolumeClaim, error) {
+	namespace := task.Namespace
+	var namespaces []corev1.Namespace
+	if task.NamespaceSelector != "" {
+		namespace = "all"
+		var err error
+		namespaces, err = c.k8sService.GetNamespaces(services.WithCluster(context.Background(), clusterName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %v", err)
+		}
+	}
+
+	pvcs, err := c.getPVCsInCluster(clusterName, namespace)
 	if err != nil {
-		return fmt.Errorf("failed to get PVCs: %v", err)
+		return nil, fmt.Errorf("failed to get PVCs: %v", err)
 	}
+	return services.SelectPVCs(task, pvcs, namespaces)
+}
 
-	pvcExists := false
-	for _, pvc := range pvcs {
-		if pvc.Name == task.PVCName {
-			pvcExists = true
-			break
+// selectorSnapshotName 选择器任务的快照名称：<任务快照名>-<PVC 名>
+// 超出 Kubernetes 名称长度时截断并追加完整名称的短哈希，避免前缀相同的长 PVC 名得到同一个快照名
+func selectorSnapshotName(snapshotName, pvcName string) string {
+	name := snapshotName + "-" + pvcName
+	if len(name) <= 253 {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:10]
+	return strings.TrimRight(name[:253-len(suffix)-1], "-.") + "-" + suffix
+}
+
+// snapshotPVC 为单个 PVC 创建快照并等待就绪，CSI 驱动可能在创建后才报告错误
+func (c *ScheduledController) snapshotPVC(task *models.ScheduledSnapshot, clusterName, namespace, pvcName, snapshotName string, now time.Time) models.PVCRunResult {
+	result := models.PVCRunResult{
+		Namespace:    namespace,
+		PVCName:      pvcName,
+		SnapshotName: snapshotName,
+		Status:       models.RunStatusFailed,
+	}
+
+	vs := c.createVolumeSnapshotSpec(task, namespace, pvcName, snapshotName, now)
+	hookResults, err := c.createVolumeSnapshotInCluster(clusterName, namespace, vs, task.Hooks)
+	result.Hooks = hookResults
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create snapshot: %v", err)
+		return result
+	}
+
+	c.tracker.Track(clusterName, namespace, snapshotName)
+	tracking, err := c.tracker.Wait(context.Background(), clusterName, namespace, snapshotName)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.BoundContentName = tracking.BoundVolumeSnapshotContentName
+	result.RestoreSize = tracking.RestoreSize
+	if tracking.Phase != models.TrackingPhaseReady {
+		result.Error = fmt.Sprintf("snapshot %s: %s", tracking.Phase, tracking.Error)
+		return result
+	}
+	result.ReadyToUse = true
+	result.ReadyDurationSeconds = tracking.DurationSeconds
+	// 快照已就绪但 post 钩子失败时应用可能仍处于静默状态，记为失败以便告警
+	if failed := services.FailedHook(hookResults, models.HookPhasePost); failed != nil {
+		result.Error = fmt.Sprintf("post hook failed in pod %s: %s", failed.Pod, failed.Error)
+		return result
+	}
+	if task.ExportData {
+		artifact, err := c.exportSnapshotInCluster(task, clusterName, namespace, snapshotName)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to export snapshot data: %v", err)
+			return result
 		}
+		result.ExportArtifactID = artifact.ID
 	}
+	result.Status = models.RunStatusSuccess
+	return result
+}
 
-	if !pvcExists {
-		return fmt.Errorf("PVC '%s' not found in namespace '%s'", task.PVCName, task.Namespace)
+// exportSnapshotInCluster 将已就绪的快照数据导出到对象存储，各步骤写入服务日志
+func (c *ScheduledController) exportSnapshotInCluster(task *models.ScheduledSnapshot, clusterName, namespace, snapshotName string) (*models.ExportArtifact, error) {
+	ctx := services.WithCluster(context.Background(), clusterName)
+	report := func(step, status, message string) {
+		if status != models.OperationStatusRunning {
+			fmt.Printf("Scheduled task %s export of %s/%s: %s %s %s\n", task.ID, namespace, snapshotName, step, status, message)
+		}
 	}
+	return c.dataMover.Export(ctx, namespace, snapshotName, models.ExportSnapshotRequest{CreatedBy: task.CreatedBy}, report)
+}
 
-	// 创建 VolumeSnapshot
-	vs := c.createVolumeSnapshotSpec(task, snapshotName, now)
+// getPVCsInCluster 获取指定集群（为空时为当前集群）中的PVC列表
+func (c *ScheduledController) getPVCsInCluster(clusterName, namespace string) ([]corev1.PersistentVolumeClaim, error) {
+	if multiClusterService, ok := c.k8sService.(services.MultiClusterK8sServiceInterface); ok && clusterName != "" {
+		return multiClusterService.GetPVCsInCluster(context.Background(), clusterName, namespace)
+	}
+	return c.k8sService.GetPVCs(context.Background(), namespace)
+}
 
-	// 在指定集群中创建快照
-	_, err = multiClusterService.CreateVolumeSnapshotInCluster(context.Background(), clusterName, task.Namespace, vs)
+// createVolumeSnapshotInCluster 在指定集群（为空时为当前集群）中创建快照，配置了钩子时一并执行并返回钩子结果
+func (c *ScheduledController) createVolumeSnapshotInCluster(clusterName, namespace string, vs *snapshotv1.VolumeSnapshot, hooks *models.SnapshotHooks) ([]models.HookResult, error) {
+	ctx := services.WithCluster(context.Background(), clusterName)
+	_, hookResults, err := c.k8sService.CreateVolumeSnapshotWithHooks(ctx, namespace, vs, hooks)
+	return hookResults, err
+}
+
+// pruneAndLog 应用保留策略并记录结果，返回清理的快照数
+func (c *ScheduledController) pruneAndLog(task *models.ScheduledSnapshot, clusterName string) int {
+	if task.Retention == nil {
+		return 0
+	}
+
+	result, err := c.applyRetention(task, clusterName, false)
 	if err != nil {
-		return fmt.Errorf("failed to create snapshot: %v", err)
+		fmt.Printf("Failed to apply retention policy for task %s in cluster %q: %v\n", task.Name, clusterName, err)
+		return 0
 	}
 
-	fmt.Printf("Successfully created scheduled snapshot %s in cluster %s (created by: %s)\n", snapshotName, clusterName, task.CreatedBy)
-	return nil
+	pruned := len(result.Prune) - len(result.Errors)
+	if len(result.Prune) > 0 || len(result.Errors) > 0 {
+		fmt.Printf("Retention for task %s in cluster %q: kept %d, pruned %d, errors: %v\n", task.Name, clusterName, len(result.Keep), pruned, result.Errors)
+	}
+	return pruned
+}
+
+// applyRetention 在指定集群（为空时为当前集群）中按任务的保留策略清理快照
+// 任务的快照通过 scheduled-task-id 标签查找，按源 PVC 分别计算；dryRun 为 true 时只计算不删除
+// 命名空间选择器任务的 Namespace 为空，在所有命名空间中查找
+func (c *ScheduledController) applyRetention(task *models.ScheduledSnapshot, clusterName string, dryRun bool) (*models.RetentionResult, error) {
+	selector := labels.Set{"scheduled-task-id": task.ID}.AsSelector().String()
+
+	multiClusterService, isMultiCluster := c.k8sService.(services.MultiClusterK8sServiceInterface)
+
+	var snapshots []snapshotv1.VolumeSnapshot
+	var err error
+	if clusterName != "" && isMultiCluster {
+		snapshots, err = multiClusterService.GetVolumeSnapshotsInCluster(context.Background(), clusterName, task.Namespace, selector)
+	} else {
+		snapshots, err = c.k8sService.GetVolumeSnapshotsBySelector(context.Background(), task.Namespace, selector)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %v", err)
+	}
+
+	result := services.ApplyRetentionPolicyPerPVC(snapshots, task.Retention, time.Now())
+	result.Cluster = clusterName
+	result.DryRun = dryRun
+	if dryRun {
+		return result, nil
+	}
+
+	for _, decision := range result.Prune {
+		if clusterName != "" && isMultiCluster {
+			err = multiClusterService.DeleteVolumeSnapshotInCluster(context.Background(), clusterName, decision.Namespace, decision.Name)
+		} else {
+			err = c.k8sService.DeleteVolumeSnapshot(context.Background(), decision.Namespace, decision.Name)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", decision.Name, err))
+		}
+	}
+
+	return result, nil
+}
+
+// PreviewRetention 预览保留策略（dry-run），返回每个目标集群中将被保留和清理的快照
+func (c *ScheduledController) PreviewRetention(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	c.mutex.RLock()
+	task, exists := c.scheduledTasks[id]
+	c.mutex.RUnlock()
+
+	if !exists {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "Scheduled task not found"))
+		return
+	}
+
+	if task.Retention == nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "该任务未配置保留策略"))
+		return
+	}
+
+	results := []models.RetentionResult{}
+	for _, clusterName := range c.targetClusters(task) {
+		result, err := c.applyRetention(task, clusterName, true)
+		if err != nil {
+			results = append(results, models.RetentionResult{
+				Cluster: clusterName,
+				DryRun:  true,
+				Keep:    []models.RetentionDecision{},
+				Prune:   []models.RetentionDecision{},
+				Errors:  []string{err.Error()},
+			})
+			continue
+		}
+		results = append(results, *result)
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(results))
+}
+
+// TaskScopes 解析定时任务接口的权限范围，供 RequirePermission 使用
+// 包括路径 id 指定的现有任务和请求体中的新命名空间、目标集群；任务的每个目标集群都需要授权
+// 使用命名空间选择器的任务没有命名空间，按集群级范围检查
+func (c *ScheduledController) TaskScopes(ctx *gin.Context) ([]services.Scope, error) {
+	var scopes []services.Scope
+
+	if id := ctx.Param("id"); id != "" {
+		c.mutex.RLock()
+		task, exists := c.scheduledTasks[id]
+		if exists {
+			scopes = append(scopes, taskScopes(ctx, task.Namespace, task.TargetClusters)...)
+		}
+		c.mutex.RUnlock()
+		// 任务不存在时交给处理函数返回 404
+	}
+
+	var body struct {
+		Namespace         string   `json:"namespace"`
+		NamespaceSelector string   `json:"namespaceSelector"`
+		TargetClusters    []string `json:"targetClusters"`
+	}
+	if err := middleware.PeekJSONBody(ctx, &body); err != nil {
+		return nil, err
+	}
+	if body.Namespace != "" {
+		scopes = append(scopes, taskScopes(ctx, body.Namespace, body.TargetClusters)...)
+	}
+	// 命名空间选择器可能匹配任意命名空间，需要集群级授权
+	if body.NamespaceSelector != "" {
+		scopes = append(scopes, taskScopes(ctx, "", body.TargetClusters)...)
+	}
+
+	return scopes, nil
+}
+
+// ExportScopes 请求体开启 exportData 时解析 backup:create 的权限范围，范围与 HookScopes 相同
+// 搬运 Job 使用共享凭据时要求全局权限
+func (c *ScheduledController) ExportScopes(ctx *gin.Context) ([]services.Scope, error) {
+	var body struct {
+		ExportData bool `json:"exportData"`
+	}
+	if err := middleware.PeekJSONBody(ctx, &body); err != nil {
+		return nil, err
+	}
+	if !body.ExportData {
+		return nil, nil
+	}
+	if c.dataMover.SharedCredentials() {
+		return []services.Scope{{}}, nil
+	}
+	return c.bodyTaskScopes(ctx)
+}
+
+// HookScopes 请求体带有 hooks 时解析 pod:exec 的权限范围，没有 hooks 时不需要额外授权
+func (c *ScheduledController) HookScopes(ctx *gin.Context) ([]services.Scope, error) {
+	var body struct {
+		Hooks *models.SnapshotHooks `json:"hooks"`
+	}
+	if err := middleware.PeekJSONBody(ctx, &body); err != nil {
+		return nil, err
+	}
+	if body.Hooks == nil {
+		return nil, nil
+	}
+	return c.bodyTaskScopes(ctx)
+}
+
+// bodyTaskScopes 按请求体中的目标解析任务的权限范围
+// 请求体未指定目标命名空间时沿用路径 id 指定的现有任务
+func (c *ScheduledController) bodyTaskScopes(ctx *gin.Context) ([]services.Scope, error) {
+	var body struct {
+		Namespace         string   `json:"namespace"`
+		NamespaceSelector string   `json:"namespaceSelector"`
+		TargetClusters    []string `json:"targetClusters"`
+	}
+	if err := middleware.PeekJSONBody(ctx, &body); err != nil {
+		return nil, err
+	}
+
+	if body.Namespace == "" && body.NamespaceSelector == "" {
+		c.mutex.RLock()
+		if task, exists := c.scheduledTasks[ctx.Param("id")]; exists {
+			body.Namespace, body.NamespaceSelector = task.Namespace, task.NamespaceSelector
+			if len(body.TargetClusters) == 0 {
+				body.TargetClusters = task.TargetClusters
+			}
+		}
+		c.mutex.RUnlock()
+	}
+	// 命名空间选择器可能匹配任意命名空间，需要集群级授权
+	if body.NamespaceSelector != "" {
+		return taskScopes(ctx, "", body.TargetClusters), nil
+	}
+	return taskScopes(ctx, body.Namespace, body.TargetClusters), nil
+}
+
+// canReadTask 判断用户是否在任务的任一目标集群中有权查看该命名空间
+func (c *ScheduledController) canReadTask(ctx *gin.Context, task *models.ScheduledSnapshot) bool {
+	for _, scope := range taskScopes(ctx, task.Namespace, task.TargetClusters) {
+		if middleware.Authorize(ctx, c.rbac, models.PermRead, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// taskScopes 未指定目标集群的任务会被固定到请求的集群，按请求集群检查
+func taskScopes(ctx *gin.Context, namespace string, clusters []string) []services.Scope {
+	if len(clusters) == 0 {
+		clusters = []string{middleware.GetCurrentCluster(ctx)}
+	}
+	scopes := make([]services.Scope, 0, len(clusters))
+	for _, cluster := range clusters {
+		scopes = append(scopes, services.Scope{Cluster: cluster, Namespace: namespace})
+	}
+	return scopes
+}
+
+// pinTargetClusters 未指定目标集群时固定为本次请求的集群，任务执行不再依赖任何全局状态
+func (c *ScheduledController) pinTargetClusters(ctx *gin.Context, task *models.ScheduledSnapshot) {
+	if _, ok := c.k8sService.(services.MultiClusterK8sServiceInterface); ok && len(task.TargetClusters) == 0 {
+		task.TargetClusters = []string{middleware.GetCurrentCluster(ctx)}
+	}
+}
+
+// targetClusters 返回任务的目标集群列表，单集群服务时返回 [""]
+func (c *ScheduledController) targetClusters(task *models.ScheduledSnapshot) []string {
+	multiClusterService, ok := c.k8sService.(services.MultiClusterK8sServiceInterface)
+	if !ok {
+		if len(task.TargetClusters) > 0 {
+			fmt.Printf("Multi-cluster operation not supported, executing in current cluster only\n")
+		}
+		return []string{""}
+	}
+	if len(task.TargetClusters) == 0 {
+		return []string{multiClusterService.GetDefaultCluster()}
+	}
+	return task.TargetClusters
 }
 
 // createVolumeSnapshotSpec 创建VolumeSnapshot规格
-func (c *ScheduledController) createVolumeSnapshotSpec(task *models.ScheduledSnapshot, snapshotName string, now time.Time) *snapshotv1.VolumeSnapshot {
+func (c *ScheduledController) createVolumeSnapshotSpec(task *models.ScheduledSnapshot, namespace, pvcName, snapshotName string, now time.Time) *snapshotv1.VolumeSnapshot {
 	return &snapshotv1.VolumeSnapshot{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      snapshotName,
-			Namespace: task.Namespace,
+			Namespace: namespace,
 			Labels: map[string]string{
 				"scheduled-task-id":   task.ID,
 				"scheduled-task-name": task.Name,
-				"created-by":          task.CreatedBy,
+				"created-by":          services.CreatedByLabelValue(task.CreatedBy),
 				"app":                 "k8s-volume-snapshots",
 			},
 			Annotations: map[string]string{
@@ -492,7 +1060,7 @@ func (c *ScheduledController) createVolumeSnapshotSpec(task *models.ScheduledSna
 		},
 		Spec: snapshotv1.VolumeSnapshotSpec{
 			Source: snapshotv1.VolumeSnapshotSource{
-				PersistentVolumeClaimName: &task.PVCName,
+				PersistentVolumeClaimName: &pvcName,
 			},
 			VolumeSnapshotClassName: &task.VolumeSnapshotClassName,
 		},
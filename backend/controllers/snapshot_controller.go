@@ -2,10 +2,15 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -15,25 +20,35 @@ import (
 )
 
 type SnapshotController struct {
-	k8sService services.K8sServiceInterface
+	k8sService       services.K8sServiceInterface
+	operationTracker *services.OperationTracker
+	snapshotTracker  *services.SnapshotTracker
+	rbac             *services.RBACService
+	// 正在回滚的 PVC（namespace/name），防止同一 PVC 并发回滚
+	rollingBack   map[string]bool
+	rollbackMutex sync.Mutex
 }
 
-func NewSnapshotController(k8sService services.K8sServiceInterface) *SnapshotController {
+func NewSnapshotController(k8sService services.K8sServiceInterface, operationTracker *services.OperationTracker, snapshotTracker *services.SnapshotTracker, rbac *services.RBACService) *SnapshotController {
 	return &SnapshotController{
-		k8sService: k8sService,
+		k8sService:       k8sService,
+		operationTracker: operationTracker,
+		snapshotTracker:  snapshotTracker,
+		rbac:             rbac,
+		rollingBack:      make(map[string]bool),
 	}
 }
 
 // GetVolumeSnapshotClasses 获取 VolumeSnapshotClass 列表
 func (c *SnapshotController) GetVolumeSnapshotClasses(ctx *gin.Context) {
-	vscList, err := c.k8sService.GetVolumeSnapshotClasses(context.Background())
+	vscList, err := c.k8sService.GetVolumeSnapshotClasses(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
 		return
 	}
 
 	// 获取相关的 StorageClass 信息
-	scList, err := c.k8sService.GetStorageClasses(context.Background())
+	scList, err := c.k8sService.GetStorageClasses(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
 		return
@@ -65,44 +80,36 @@ func (c *SnapshotController) GetVolumeSnapshotClasses(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
 }
 
-// GetVolumeSnapshots 获取 VolumeSnapshot 列表
+// GetVolumeSnapshots 获取 VolumeSnapshot 列表，支持过滤、排序和分页
 func (c *SnapshotController) GetVolumeSnapshots(ctx *gin.Context) {
-	namespace := ctx.Query("namespace")
-
-	vsList, err := c.k8sService.GetVolumeSnapshots(context.Background(), namespace)
+	listOptions, err := parseListOptions(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
 		return
 	}
 
-	var result []models.VolumeSnapshotInfo
-	for _, vs := range vsList {
-		info := models.VolumeSnapshotInfo{
-			VolumeSnapshot: vs,
-		}
-
-		// 获取对应的 VolumeSnapshotContent
-		if vs.Status != nil && vs.Status.BoundVolumeSnapshotContentName != nil {
-			vsc, err := c.k8sService.GetVolumeSnapshotContent(context.Background(), *vs.Status.BoundVolumeSnapshotContentName)
-			if err == nil {
-				info.VolumeSnapshotContent = vsc
-			}
+	opts := models.VolumeSnapshotListOptions{
+		ListOptions:     listOptions,
+		PVCName:         ctx.Query("pvcName"),
+		ScheduledTaskID: ctx.Query("scheduledTaskId"),
+	}
+	if value := ctx.Query("ready"); value != "" {
+		ready, err := strconv.ParseBool(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "无效的 ready 参数: "+value))
+			return
 		}
+		opts.Ready = &ready
+	}
 
-		// 获取对应的 PVC 信息
-		if vs.Spec.Source.PersistentVolumeClaimName != nil {
-			pvcs, err := c.k8sService.GetPVCs(context.Background(), vs.Namespace)
-			if err == nil {
-				for _, pvc := range pvcs {
-					if pvc.Name == *vs.Spec.Source.PersistentVolumeClaimName {
-						info.PVC = &pvc
-						break
-					}
-				}
-			}
-		}
+	if !c.applyNamespaceFilter(ctx, &opts.ListOptions) {
+		return
+	}
 
-		result = append(result, info)
+	result, err := c.k8sService.GetVolumeSnapshotInfos(ctx.Request.Context(), opts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
 	}
 
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(result))
@@ -115,6 +122,10 @@ func (c *SnapshotController) CreateVolumeSnapshot(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
 		return
 	}
+	if err := services.ValidateSnapshotHooks(req.Hooks); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
 
 	// 获取当前用户信息
 	username, exists := middleware.GetCurrentUsername(ctx)
@@ -132,7 +143,7 @@ func (c *SnapshotController) CreateVolumeSnapshot(ctx *gin.Context) {
 			Name:      req.Name,
 			Namespace: req.Namespace,
 			Labels: map[string]string{
-				"created-by": username,
+				"created-by": services.CreatedByLabelValue(username),
 				"app":        "k8s-volume-snapshots",
 			},
 			Annotations: map[string]string{
@@ -147,13 +158,67 @@ func (c *SnapshotController) CreateVolumeSnapshot(ctx *gin.Context) {
 		},
 	}
 
-	createdVS, err := c.k8sService.CreateVolumeSnapshot(context.Background(), req.Namespace, vs)
+	// 配置了钩子时在挂载 PVC 的 Pod 中执行 pre/post 命令，结果随响应返回
+	createdVS, hookResults, err := c.k8sService.CreateVolumeSnapshotWithHooks(ctx.Request.Context(), req.Namespace, vs, req.Hooks)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: err.Error(),
+			Data:    models.CreateVolumeSnapshotResponse{Hooks: hookResults},
+		})
+		return
+	}
+
+	// 创建成功不代表快照可用，跟踪直到 ReadyToUse 或失败
+	response := models.CreateVolumeSnapshotResponse{
+		VolumeSnapshot: createdVS,
+		Tracking:       c.snapshotTracker.Track(middleware.GetCurrentCluster(ctx), createdVS.Namespace, createdVS.Name),
+		Hooks:          hookResults,
+	}
+
+	// ?wait=true 时同步等待跟踪结果
+	if ctx.Query("wait") != "true" {
+		ctx.JSON(http.StatusCreated, models.NewSuccessResponse(response))
+		return
+	}
+
+	tracking, err := c.snapshotTracker.Wait(ctx.Request.Context(), response.Tracking.Cluster, createdVS.Namespace, createdVS.Name)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
 		return
 	}
+	response.Tracking = tracking
+
+	switch tracking.Phase {
+	case models.TrackingPhaseFailed:
+		ctx.JSON(http.StatusInternalServerError, models.APIResponse{
+			Code:    500,
+			Message: "快照创建失败: " + tracking.Error,
+			Data:    response,
+		})
+	case models.TrackingPhaseTimeout:
+		ctx.JSON(http.StatusGatewayTimeout, models.APIResponse{
+			Code:    504,
+			Message: tracking.Error,
+			Data:    response,
+		})
+	default:
+		ctx.JSON(http.StatusCreated, models.NewSuccessResponse(response))
+	}
+}
+
+// GetVolumeSnapshotTracking 获取新建快照的就绪跟踪结果
+func (c *SnapshotController) GetVolumeSnapshotTracking(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	name := ctx.Param("name")
 
-	ctx.JSON(http.StatusCreated, models.NewSuccessResponse(createdVS))
+	tracking, exists := c.snapshotTracker.Get(middleware.GetCurrentCluster(ctx), namespace, name)
+	if !exists {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "未找到该快照的跟踪记录"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(tracking))
 }
 
 // DeleteVolumeSnapshot 删除 VolumeSnapshot
@@ -162,14 +227,14 @@ func (c *SnapshotController) DeleteVolumeSnapshot(ctx *gin.Context) {
 	name := ctx.Param("name")
 
 	// 首先检查快照是否存在
-	_, err := c.k8sService.GetVolumeSnapshot(context.Background(), namespace, name)
+	_, err := c.k8sService.GetVolumeSnapshot(ctx.Request.Context(), namespace, name)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "快照不存在"))
 		return
 	}
 
 	// 执行删除操作
-	err = c.k8sService.DeleteVolumeSnapshot(context.Background(), namespace, name)
+	err = c.k8sService.DeleteVolumeSnapshot(ctx.Request.Context(), namespace, name)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "删除快照失败: "+err.Error()))
 		return
@@ -187,7 +252,7 @@ func (c *SnapshotController) ForceDeleteVolumeSnapshot(ctx *gin.Context) {
 	name := ctx.Param("name")
 
 	// 获取快照详情
-	vs, err := c.k8sService.GetVolumeSnapshot(context.Background(), namespace, name)
+	vs, err := c.k8sService.GetVolumeSnapshot(ctx.Request.Context(), namespace, name)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "快照不存在"))
 		return
@@ -200,7 +265,7 @@ func (c *SnapshotController) ForceDeleteVolumeSnapshot(ctx *gin.Context) {
 	}
 
 	// 尝试强制清理 finalizers
-	err = c.k8sService.ForceDeleteVolumeSnapshot(context.Background(), namespace, name)
+	err = c.k8sService.ForceDeleteVolumeSnapshot(ctx.Request.Context(), namespace, name)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "强制删除失败: "+err.Error()))
 		return
@@ -212,27 +277,187 @@ func (c *SnapshotController) ForceDeleteVolumeSnapshot(ctx *gin.Context) {
 	}))
 }
 
+// RestoreVolumeSnapshot 从 VolumeSnapshot 恢复出新的 PVC
+// PVC 创建后在后台等待绑定，进度通过 /api/operations/:id 查询
+func (c *SnapshotController) RestoreVolumeSnapshot(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	name := ctx.Param("name")
+
+	var req models.RestoreVolumeSnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+	req.CreatedBy = username
+
+	// 首先检查快照是否存在
+	if _, err := c.k8sService.GetVolumeSnapshot(ctx.Request.Context(), namespace, name); err != nil {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "快照不存在"))
+		return
+	}
+
+	op := c.operationTracker.Start("restore", middleware.GetCurrentCluster(ctx), namespace, req.PVCName, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	report("创建 PVC", models.OperationStatusRunning, "正在从快照 "+name+" 创建 PVC "+req.PVCName)
+	pvc, err := c.k8sService.RestoreVolumeSnapshot(ctx.Request.Context(), namespace, name, req)
+	if err != nil {
+		report("创建 PVC", models.OperationStatusFailed, err.Error())
+		c.operationTracker.Finish(op.ID, err)
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "恢复快照失败: "+err.Error()))
+		return
+	}
+	report("创建 PVC", models.OperationStatusSucceeded, "PVC "+pvc.Name+" 已创建")
+
+	// 后台等待 PVC 绑定，脱离请求生命周期但保留目标集群
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		err := services.WaitForPVCBound(bgCtx, c.k8sService, namespace, pvc.Name, report)
+		c.operationTracker.Finish(op.ID, err)
+	}()
+
+	op, _ = c.operationTracker.Get(op.ID)
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
+// RollbackVolumeSnapshot 将快照的源 PVC 原地回滚到该快照
+// 会缩容挂载该 PVC 的工作负载并重建 PVC，进度通过 /api/operations/:id 查询
+func (c *SnapshotController) RollbackVolumeSnapshot(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	name := ctx.Param("name")
+
+	var req models.RollbackVolumeSnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+
+	vs, err := c.k8sService.GetVolumeSnapshot(ctx.Request.Context(), namespace, name)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "快照不存在"))
+		return
+	}
+	if vs.Spec.Source.PersistentVolumeClaimName == nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "快照不是从 PVC 创建的，无法原地回滚"))
+		return
+	}
+	pvcName := *vs.Spec.Source.PersistentVolumeClaimName
+
+	// 需要输入 PVC 名称确认，避免误操作
+	if req.Confirm != pvcName {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "确认信息与 PVC 名称不一致"))
+		return
+	}
+
+	pvc, err := c.k8sService.GetPVC(ctx.Request.Context(), namespace, pvcName)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "源 PVC 不存在: "+err.Error()))
+		return
+	}
+
+	lockKey := middleware.GetCurrentCluster(ctx) + "/" + namespace + "/" + pvcName
+	c.rollbackMutex.Lock()
+	if c.rollingBack[lockKey] {
+		c.rollbackMutex.Unlock()
+		ctx.JSON(http.StatusConflict, models.NewErrorResponse(409, "该 PVC 正在回滚中"))
+		return
+	}
+	c.rollingBack[lockKey] = true
+	c.rollbackMutex.Unlock()
+
+	info := &models.VolumeSnapshotInfo{
+		VolumeSnapshot: *vs,
+		PVC:            pvc,
+	}
+
+	op := c.operationTracker.Start("rollback", middleware.GetCurrentCluster(ctx), namespace, pvcName, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	// 回滚在后台执行，脱离请求生命周期但保留目标集群
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		defer func() {
+			c.rollbackMutex.Lock()
+			delete(c.rollingBack, lockKey)
+			c.rollbackMutex.Unlock()
+		}()
+
+		err := c.k8sService.RollbackToVolumeSnapshot(bgCtx, info, username, report)
+		c.operationTracker.Finish(op.ID, err)
+	}()
+
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
+// GetOperation 获取异步操作进度
+func (c *SnapshotController) GetOperation(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	op, exists := c.operationTracker.Get(id)
+	if !exists {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "操作不存在或已过期"))
+		return
+	}
+
+	// 操作的步骤信息包含资源名称和错误详情，需要在操作所在的集群和命名空间有查看权限
+	scope := services.Scope{Cluster: op.Cluster, Namespace: op.Namespace}
+	if !middleware.Authorize(ctx, c.rbac, models.PermRead, scope) {
+		ctx.JSON(http.StatusForbidden, models.NewErrorResponse(403, "权限不足"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.NewSuccessResponse(op))
+}
+
 // GetVolumeSnapshotContent 获取 VolumeSnapshotContent 详情
 func (c *SnapshotController) GetVolumeSnapshotContent(ctx *gin.Context) {
 	name := ctx.Param("name")
 
-	vsc, err := c.k8sService.GetVolumeSnapshotContent(context.Background(), name)
+	vsc, err := c.k8sService.GetVolumeSnapshotContent(ctx.Request.Context(), name)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
 		return
 	}
 
+	// VolumeSnapshotContent 是集群级资源，按其绑定快照的命名空间判断可见性
+	scope := services.Scope{Cluster: middleware.GetCurrentCluster(ctx), Namespace: vsc.Spec.VolumeSnapshotRef.Namespace}
+	if !middleware.Authorize(ctx, c.rbac, models.PermRead, scope) {
+		ctx.JSON(http.StatusForbidden, models.NewErrorResponse(403, "权限不足"))
+		return
+	}
+
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(vsc))
 }
 
 // GetPVCs 获取 PVC 列表
 func (c *SnapshotController) GetPVCs(ctx *gin.Context) {
-	namespace := ctx.Query("namespace")
-	if namespace == "" {
-		namespace = "default"
+	listOptions, err := parseListOptions(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+	if listOptions.Namespace == "" {
+		listOptions.Namespace = "default"
+	}
+	if !c.applyNamespaceFilter(ctx, &listOptions) {
+		return
 	}
 
-	pvcs, err := c.k8sService.GetPVCsWithPVInfo(context.Background(), namespace)
+	pvcs, err := c.k8sService.GetPVCsWithPVInfo(ctx.Request.Context(), models.PVCListOptions{ListOptions: listOptions})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
 		return
@@ -241,20 +466,80 @@ func (c *SnapshotController) GetPVCs(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(pvcs))
 }
 
+// ClonePVC 以现有 PVC 为数据源克隆新的 PVC
+// PVC 创建后在后台等待绑定，进度通过 /api/operations/:id 查询
+func (c *SnapshotController) ClonePVC(ctx *gin.Context) {
+	namespace := ctx.Param("namespace")
+	name := ctx.Param("name")
+
+	var req models.ClonePVCRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户信息
+	username, exists := middleware.GetCurrentUsername(ctx)
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户未认证"))
+		return
+	}
+	req.CreatedBy = username
+
+	if _, err := c.k8sService.GetPVC(ctx.Request.Context(), namespace, name); err != nil {
+		ctx.JSON(http.StatusNotFound, models.NewErrorResponse(404, "PVC 不存在"))
+		return
+	}
+
+	op := c.operationTracker.Start("clone", middleware.GetCurrentCluster(ctx), namespace, req.PVCName, username)
+	report := c.operationTracker.Reporter(op.ID)
+
+	report("创建 PVC", models.OperationStatusRunning, "正在从 PVC "+name+" 克隆 "+req.PVCName)
+	pvc, err := c.k8sService.ClonePVC(ctx.Request.Context(), namespace, name, req)
+	if err != nil {
+		report("创建 PVC", models.OperationStatusFailed, err.Error())
+		c.operationTracker.Finish(op.ID, err)
+		ctx.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "克隆 PVC 失败: "+err.Error()))
+		return
+	}
+	report("创建 PVC", models.OperationStatusSucceeded, "PVC "+pvc.Name+" 已创建")
+
+	// 后台等待 PVC 绑定，脱离请求生命周期但保留目标集群
+	bgCtx := services.WithCluster(context.Background(), middleware.GetCurrentCluster(ctx))
+	go func() {
+		err := services.WaitForPVCBound(bgCtx, c.k8sService, namespace, pvc.Name, report)
+		c.operationTracker.Finish(op.ID, err)
+	}()
+
+	op, _ = c.operationTracker.Get(op.ID)
+	ctx.JSON(http.StatusAccepted, models.NewSuccessResponse(op))
+}
+
 // GetNamespaces 获取所有命名空间
 func (c *SnapshotController) GetNamespaces(ctx *gin.Context) {
-	namespaces, err := c.k8sService.GetNamespaces(context.Background())
+	namespaces, err := c.k8sService.GetNamespaces(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
 		return
 	}
 
+	// 只返回用户有权查看的命名空间
+	if allow := middleware.NamespaceFilter(ctx, c.rbac, models.PermRead); allow != nil {
+		visible := []corev1.Namespace{}
+		for _, ns := range namespaces {
+			if allow(ns.Name) {
+				visible = append(visible, ns)
+			}
+		}
+		namespaces = visible
+	}
+
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(namespaces))
 }
 
 // GetStorageClasses 获取所有存储类
 func (c *SnapshotController) GetStorageClasses(ctx *gin.Context) {
-	storageClasses, err := c.k8sService.GetStorageClasses(context.Background())
+	storageClasses, err := c.k8sService.GetStorageClasses(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
 		return
@@ -262,3 +547,58 @@ func (c *SnapshotController) GetStorageClasses(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, models.NewSuccessResponse(storageClasses))
 }
+
+// applyNamespaceFilter 按权限限制列表查询的命名空间
+// 显式查询无权查看的命名空间时返回 403 并返回 false
+func (c *SnapshotController) applyNamespaceFilter(ctx *gin.Context, opts *models.ListOptions) bool {
+	allow := middleware.NamespaceFilter(ctx, c.rbac, models.PermRead)
+	if allow == nil {
+		return true
+	}
+	if opts.Namespace != "" && opts.Namespace != "all" && !allow(opts.Namespace) {
+		ctx.JSON(http.StatusForbidden, models.NewErrorResponse(403, "无权查看命名空间 "+opts.Namespace))
+		return false
+	}
+	opts.AllowNamespace = allow
+	return true
+}
+
+// parseListOptions 解析列表接口的通用查询参数
+// namespace, labelSelector, createdBy, createdAfter/createdBefore (RFC3339), sortBy, order, limit, continue
+func parseListOptions(ctx *gin.Context) (models.ListOptions, error) {
+	opts := models.ListOptions{
+		Namespace:     ctx.Query("namespace"),
+		LabelSelector: ctx.Query("labelSelector"),
+		CreatedBy:     ctx.Query("createdBy"),
+		SortBy:        ctx.Query("sortBy"),
+		Order:         ctx.Query("order"),
+		Continue:      ctx.Query("continue"),
+	}
+
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, fmt.Errorf("无效的 limit 参数: %s", value)
+		}
+		opts.Limit = limit
+	}
+	if value := ctx.Query("createdAfter"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return opts, fmt.Errorf("无效的 createdAfter 参数（需要 RFC3339 格式）: %s", value)
+		}
+		opts.CreatedAfter = &t
+	}
+	if value := ctx.Query("createdBefore"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return opts, fmt.Errorf("无效的 createdBefore 参数（需要 RFC3339 格式）: %s", value)
+		}
+		opts.CreatedBefore = &t
+	}
+
+	if err := services.ValidateListOptions(opts); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
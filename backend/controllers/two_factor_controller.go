@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+type TwoFactorController struct {
+	userService *services.UserService
+	rbac        *services.RBACService
+}
+
+func NewTwoFactorController(userService *services.UserService, rbac *services.RBACService) *TwoFactorController {
+	return &TwoFactorController{
+		userService: userService,
+		rbac:        rbac,
+	}
+}
+
+// GetStatus 获取当前用户的两步验证状态
+func (tc *TwoFactorController) GetStatus(c *gin.Context) {
+	user, _ := middleware.GetCurrentUser(c)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(tc.userService.TwoFactorStatus(user, tc.rbac.HasWritePermission(user))))
+}
+
+// Enroll 开始绑定验证器应用，返回密钥和 otpauth:// URI
+func (tc *TwoFactorController) Enroll(c *gin.Context) {
+	username, _ := middleware.GetCurrentUsername(c)
+
+	response, err := tc.userService.BeginTwoFactorEnrollment(username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response))
+}
+
+// Verify 输入验证码确认绑定，启用两步验证并返回恢复码
+func (tc *TwoFactorController) Verify(c *gin.Context) {
+	username, _ := middleware.GetCurrentUsername(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	codes, err := tc.userService.ConfirmTwoFactorEnrollment(username, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// Disable 使用验证码或恢复码关闭两步验证，按策略必须启用的用户不能关闭
+func (tc *TwoFactorController) Disable(c *gin.Context) {
+	user, _ := middleware.GetCurrentUser(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	if tc.userService.TwoFactorRequired(user, tc.rbac.HasWritePermission(user)) {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(403, "当前账户拥有写权限，按策略不能关闭两步验证"))
+		return
+	}
+
+	if err := tc.userService.DisableTwoFactor(user.Username, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (tc *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	username, _ := middleware.GetCurrentUsername(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	codes, err := tc.userService.RegenerateRecoveryCodes(username, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// ResetUserTwoFactor 管理员为丢失设备的用户关闭两步验证（需要 user:manage 权限）
+func (tc *TwoFactorController) ResetUserTwoFactor(c *gin.Context) {
+	username := c.Param("username")
+
+	if err := tc.userService.ResetTwoFactor(username); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
+		"message":  "已关闭该用户的两步验证",
+		"username": username,
+	}))
+}
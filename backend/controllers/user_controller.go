@@ -1,7 +1,15 @@
 package controllers
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -10,17 +18,40 @@ import (
 	"k8s-volume-snapshots/services"
 )
 
+// oidcStateCookie 保存 OIDC 授权请求 state 的 cookie 名称
+const oidcStateCookie = "oidc_state"
+
 type UserController struct {
-	userService *services.UserService
+	userService    *services.UserService
+	oidcService    *services.OIDCService
+	tokenService   *services.APITokenService
+	sessionService *services.SessionService
+	loginLimiter   *services.LoginLimiter
 }
 
-func NewUserController(userService *services.UserService) *UserController {
+func NewUserController(userService *services.UserService, oidcService *services.OIDCService, tokenService *services.APITokenService, sessionService *services.SessionService, loginLimiter *services.LoginLimiter) *UserController {
 	return &UserController{
-		userService: userService,
+		userService:    userService,
+		oidcService:    oidcService,
+		tokenService:   tokenService,
+		sessionService: sessionService,
+		loginLimiter:   loginLimiter,
 	}
 }
 
-// Register 用户注册
+// GetRegistrationInfo 获取自助注册模式（公开接口）
+func (uc *UserController) GetRegistrationInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.RegistrationInfo{
+		Mode: uc.userService.RegistrationMode(),
+	}))
+}
+
+// GetPasswordPolicy 获取密码策略（公开接口）
+func (uc *UserController) GetPasswordPolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(uc.userService.PasswordPolicy()))
+}
+
+// Register 用户自助注册，受 REGISTRATION_MODE 控制
 func (uc *UserController) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -30,6 +61,10 @@ func (uc *UserController) Register(c *gin.Context) {
 
 	user, err := uc.userService.Register(req)
 	if err != nil {
+		if errors.Is(err, services.ErrRegistrationDisabled) {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(403, err.Error()))
+			return
+		}
 		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
 		return
 	}
@@ -45,27 +80,289 @@ func (uc *UserController) Login(c *gin.Context) {
 		return
 	}
 
+	// 按来源 IP 和用户名限制尝试次数，未记录结果的尝试（如服务不可用）在返回时释放
+	attempt, wait, err := uc.loginLimiter.Reserve(c.ClientIP(), req.Username)
+	if err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.NewErrorResponse(429, err.Error()))
+		return
+	}
+	defer attempt.Release()
+
 	user, err := uc.userService.Login(req)
 	if err != nil {
+		if errors.Is(err, services.ErrUserDisabled) {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(403, err.Error()))
+			return
+		}
+		if errors.Is(err, services.ErrAuthUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, models.NewErrorResponse(503, err.Error()))
+			return
+		}
+		attempt.Fail()
 		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, err.Error()))
 		return
 	}
 
-	// 生成JWT token
-	token, err := middleware.GenerateToken(user)
+	// 启用了两步验证的用户凭挑战和验证码完成登录，失败记录保留到第二步成功
+	if user.TwoFactorEnabled {
+		challenge, err := uc.userService.NewLoginChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, models.NewSuccessResponse(models.TwoFactorChallenge{
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+			ExpiresIn:         int64(services.LoginChallengeTTL.Seconds()),
+		}))
+		return
+	}
+	attempt.Succeed()
+
+	response, err := uc.newLoginResponse(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "生成token失败"))
 		return
 	}
 
-	response := models.LoginResponse{
-		Token: token,
-		User:  *user,
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response))
+}
+
+// LoginTwoFactor 登录第二步：校验 TOTP 验证码或恢复码（公开接口）
+func (uc *UserController) LoginTwoFactor(c *gin.Context) {
+	var req models.LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	username, err := uc.userService.LoginChallengeUsername(req.Challenge)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, err.Error()))
+		return
+	}
+	middleware.SetAuditActor(c, username)
+
+	// 验证码同样计入登录失败次数，防止已知密码后暴力猜测验证码
+	attempt, wait, err := uc.loginLimiter.Reserve(c.ClientIP(), username)
+	if err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.NewErrorResponse(429, err.Error()))
+		return
+	}
+	defer attempt.Release()
+
+	user, err := uc.userService.CompleteLoginChallenge(req.Challenge, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrUserDisabled) {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(403, err.Error()))
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			attempt.Fail()
+		}
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, err.Error()))
+		return
+	}
+	attempt.Succeed()
+
+	response, err := uc.newLoginResponse(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "生成token失败"))
+		return
 	}
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response))
 }
 
+// newLoginResponse 签发访问令牌和新会话的刷新令牌
+func (uc *UserController) newLoginResponse(user *models.User) (*models.LoginResponse, error) {
+	token, err := middleware.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := uc.sessionService.IssueRefreshToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL().Seconds()),
+		User:         *user,
+	}, nil
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换（公开接口）
+func (uc *UserController) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	session, refreshToken, err := uc.sessionService.Rotate(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, err.Error()))
+		return
+	}
+	middleware.SetAuditActor(c, session.Username)
+
+	// 用户被删除、禁用，或修改了密码、角色后，会话不能继续刷新
+	user, err := uc.userService.GetUser(session.Username)
+	if err != nil || user.ID != session.UserID || user.Disabled || user.TokenGeneration != session.Generation {
+		if err := uc.sessionService.RevokeFamily(session.Family); err != nil {
+			fmt.Printf("吊销会话失败: %v\n", err)
+		}
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, services.ErrInvalidRefreshToken.Error()))
+		return
+	}
+
+	token, err := middleware.GenerateToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "生成token失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL().Seconds()),
+		User:         *user,
+	}))
+}
+
+// Logout 退出登录：吊销当前访问令牌的 jti，携带刷新令牌时同时吊销该会话
+func (uc *UserController) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	if claims, ok := middleware.GetCurrentClaims(c); ok {
+		if err := uc.sessionService.RevokeJTI(claims.ID, claims.ExpiresAt.Time); err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+			return
+		}
+	}
+
+	if req.RefreshToken != "" {
+		if err := uc.sessionService.RevokeRefreshToken(req.RefreshToken); err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
+		"message": "已退出登录",
+	}))
+}
+
+// GetOIDCConfig 获取单点登录配置（公开接口）
+func (uc *UserController) GetOIDCConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.OIDCConfigInfo{
+		Enabled: uc.oidcService.Enabled(),
+	}))
+}
+
+// OIDCLogin 跳转到身份提供方发起授权码登录
+func (uc *UserController) OIDCLogin(c *gin.Context) {
+	if !uc.oidcService.Enabled() {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "未启用单点登录"))
+		return
+	}
+
+	authURL, state, err := uc.oidcService.AuthCodeURL(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse(502, err.Error()))
+		return
+	}
+
+	// state 同时写入只在本浏览器中存在的 cookie，回调时比对，
+	// 防止攻击者诱导用户完成以攻击者身份发起的登录
+	uc.setOIDCStateCookie(c, state, int(services.OIDCStateTTL/time.Second))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 身份提供方回调：换取并验证 ID Token，同步本地用户后签发应用自己的 JWT
+// 结果通过 URL fragment 交给前端登录页，避免 token 出现在服务端日志和 Referer 中
+func (uc *UserController) OIDCCallback(c *gin.Context) {
+	if !uc.oidcService.Enabled() {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "未启用单点登录"))
+		return
+	}
+
+	if errorCode := c.Query("error"); errorCode != "" {
+		message := errorCode
+		if description := c.Query("error_description"); description != "" {
+			message += ": " + description
+		}
+		uc.redirectToLoginPage(c, url.Values{"error": {message}})
+		return
+	}
+
+	state := c.Query("state")
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	uc.setOIDCStateCookie(c, "", -1)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		fmt.Printf("OIDC 登录失败: state 与 cookie 不匹配\n")
+		uc.redirectToLoginPage(c, url.Values{"error": {"登录请求无效或已过期，请重新登录"}})
+		return
+	}
+
+	identity, err := uc.oidcService.Exchange(c.Request.Context(), state, c.Query("code"))
+	if err != nil {
+		fmt.Printf("OIDC 登录失败: %v\n", err)
+		uc.redirectToLoginPage(c, url.Values{"error": {err.Error()}})
+		return
+	}
+	middleware.SetAuditActor(c, identity.Username)
+
+	user, err := uc.userService.ProvisionExternalUser(models.AuthSourceOIDC, identity)
+	if err != nil {
+		fmt.Printf("OIDC 用户 %s 登录失败: %v\n", identity.Username, err)
+		uc.redirectToLoginPage(c, url.Values{"error": {err.Error()}})
+		return
+	}
+
+	response, err := uc.newLoginResponse(user)
+	if err != nil {
+		uc.redirectToLoginPage(c, url.Values{"error": {"生成token失败"}})
+		return
+	}
+
+	uc.redirectToLoginPage(c, url.Values{
+		"token":        {response.Token},
+		"refreshToken": {response.RefreshToken},
+	})
+}
+
+// setOIDCStateCookie 设置或清除（maxAge < 0）授权请求的 state cookie
+// SameSite=Lax 允许身份提供方跳转回来的顶层 GET 请求携带该 cookie
+func (uc *UserController) setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (uc *UserController) redirectToLoginPage(c *gin.Context, fragment url.Values) {
+	// 重定向的状态码无法区分成功和失败，由审计日志单独记录
+	if message := fragment.Get("error"); message != "" {
+		middleware.SetAuditError(c, message)
+	}
+	c.Redirect(http.StatusFound, uc.oidcService.LoginPageURL()+"#"+fragment.Encode())
+}
+
 // GetProfile 获取当前用户资料
 func (uc *UserController) GetProfile(c *gin.Context) {
 	user, exists := middleware.GetCurrentUser(c)
@@ -97,8 +394,24 @@ func (uc *UserController) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
-		"message": "密码修改成功",
+	// 修改密码会使所有已签发的登录令牌失效，为当前会话签发新的令牌
+	user, err := uc.userService.GetUser(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+	response, err := uc.newLoginResponse(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, "生成token失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"message":      "密码修改成功",
+		"token":        response.Token,
+		"refreshToken": response.RefreshToken,
+		"expiresIn":    response.ExpiresIn,
+		"user":         response.User,
 	}))
 }
 
@@ -122,8 +435,174 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	if err := uc.tokenService.RevokeUser(username); err != nil {
+		fmt.Printf("吊销用户 %s 的API token失败: %v\n", username, err)
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
 		"message":  "用户删除成功",
 		"username": username,
 	}))
 }
+
+// CreateUser 创建用户（仅管理员可用）
+func (uc *UserController) CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	user, err := uc.userService.CreateUser(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(user))
+}
+
+// UpdateUserRole 修改用户角色（仅管理员可用），不能修改自己的角色
+func (uc *UserController) UpdateUserRole(c *gin.Context) {
+	username := c.Param("username")
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	if current, _ := middleware.GetCurrentUsername(c); current == username {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "不能修改自己的角色"))
+		return
+	}
+
+	user, err := uc.userService.UpdateRole(username, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(user))
+}
+
+// ResetPassword 重置用户密码（仅管理员可用）
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	username := c.Param("username")
+
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	if err := uc.userService.ResetPassword(username, req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
+		"message":  "密码重置成功",
+		"username": username,
+	}))
+}
+
+// DisableUser 禁用用户（仅管理员可用），保留账户数据，不能禁用自己
+func (uc *UserController) DisableUser(c *gin.Context) {
+	username := c.Param("username")
+
+	if current, _ := middleware.GetCurrentUsername(c); current == username {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, "不能禁用自己的账户"))
+		return
+	}
+
+	user, err := uc.userService.SetDisabled(username, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(user))
+}
+
+// EnableUser 启用被禁用的用户（仅管理员可用）
+func (uc *UserController) EnableUser(c *gin.Context) {
+	user, err := uc.userService.SetDisabled(c.Param("username"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(user))
+}
+
+// GetLoginLockouts 获取因连续登录失败被锁定的用户名和来源 IP（仅管理员可用）
+func (uc *UserController) GetLoginLockouts(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(uc.loginLimiter.Lockouts()))
+}
+
+// UnlockUser 解除用户的登录锁定（仅管理员可用）
+func (uc *UserController) UnlockUser(c *gin.Context) {
+	username := c.Param("username")
+	if !uc.loginLimiter.Unlock(username, "") {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "该用户没有登录失败记录"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
+		"message":  "已解除登录锁定",
+		"username": username,
+	}))
+}
+
+// UnlockIP 解除来源 IP 的登录锁定（仅管理员可用）
+func (uc *UserController) UnlockIP(c *gin.Context) {
+	var req models.UnlockLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	if !uc.loginLimiter.Unlock("", req.IP) {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, "该IP没有登录失败记录"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]string{
+		"message": "已解除登录锁定",
+		"ip":      req.IP,
+	}))
+}
+
+// GetInvites 获取注册邀请码列表（仅管理员可用）
+func (uc *UserController) GetInvites(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(uc.userService.GetInvites()))
+}
+
+// CreateInvite 创建注册邀请码（仅管理员可用），令牌只在响应中返回一次
+func (uc *UserController) CreateInvite(c *gin.Context) {
+	var req models.CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+		return
+	}
+
+	username, _ := middleware.GetCurrentUsername(c)
+	invite, err := uc.userService.CreateInvite(req, username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(invite))
+}
+
+// DeleteInvite 撤销注册邀请码（仅管理员可用）
+func (uc *UserController) DeleteInvite(c *gin.Context) {
+	if err := uc.userService.DeleteInvite(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
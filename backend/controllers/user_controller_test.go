@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/services"
+)
+
+// newOIDCTestRouter 只提供 discovery 的身份提供方，足以发起授权请求
+func newOIDCTestRouter(t *testing.T) *gin.Engine {
+	var issuer *httptest.Server
+	issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/auth",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/keys",
+		})
+	}))
+	t.Cleanup(issuer.Close)
+
+	t.Setenv("OIDC_ISSUER_URL", issuer.URL)
+	t.Setenv("OIDC_CLIENT_ID", "kvs")
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost/api/auth/oidc/callback")
+	oidcService, err := services.NewOIDCService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	uc := NewUserController(nil, oidcService, nil, nil, nil)
+	router := gin.New()
+	router.GET("/api/auth/oidc/login", uc.OIDCLogin)
+	router.GET("/api/auth/oidc/callback", uc.OIDCCallback)
+	return router
+}
+
+func TestOIDCLoginSetsStateCookie(t *testing.T) {
+	router := newOIDCTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d: %s", w.Code, w.Body.String())
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected one cookie, got %v", cookies)
+	}
+	cookie := cookies[0]
+	if cookie.Name != oidcStateCookie || cookie.Value != location.Query().Get("state") {
+		t.Fatalf("cookie 应当保存授权地址中的 state: %v", cookie)
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge <= 0 {
+		t.Fatalf("unexpected cookie attributes: %v", cookie)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	router := newOIDCTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	state := w.Result().Cookies()[0].Value
+
+	for name, cookie := range map[string]string{
+		"缺少 cookie": "",
+		"state 不一致": "attacker-state",
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=code&state="+state, nil)
+			if cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookie})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusFound || !strings.Contains(w.Header().Get("Location"), "#error=") {
+				t.Fatalf("expected redirect with error, got %d %s", w.Code, w.Header().Get("Location"))
+			}
+			if !strings.Contains(w.Header().Get("Set-Cookie"), "Max-Age=0") {
+				t.Fatalf("回调后应清除 state cookie: %s", w.Header().Get("Set-Cookie"))
+			}
+		})
+	}
+}
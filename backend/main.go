@@ -11,6 +11,7 @@ import (
 
 	"k8s-volume-snapshots/controllers"
 	"k8s-volume-snapshots/middleware"
+	"k8s-volume-snapshots/models"
 	"k8s-volume-snapshots/services"
 
 	// 导入静态资源
@@ -27,6 +28,49 @@ func main() {
 	// 初始化用户服务
 	userService := services.NewUserService()
 
+	// 初始化 LDAP 认证（未配置 LDAP_URL 时不启用），在本地账户之后尝试
+	ldapProvider, err := services.NewLDAPAuthProvider()
+	if err != nil {
+		log.Fatalf("Failed to initialize LDAP authentication: %v", err)
+	}
+	if ldapProvider != nil {
+		userService.AddAuthProvider(ldapProvider)
+	}
+
+	// 初始化权限服务（自定义角色和角色绑定）
+	rbacService := services.NewRBACService()
+
+	// 初始化 API token 服务（CI 和自动化脚本使用的长期令牌）
+	apiTokenService := services.NewAPITokenService()
+
+	// 初始化会话服务（刷新令牌和访问令牌吊销列表）
+	sessionService := services.NewSessionService()
+
+	// 初始化登录限制（按来源 IP 和用户名退避、锁定）
+	loginLimiter := services.NewLoginLimiter()
+
+	// 初始化事件流票据
+	streamTickets := services.NewStreamTicketService()
+
+	// 初始化 JWT 签名密钥（从文件或 Kubernetes Secret 加载，按计划轮换）
+	jwtKeyManager, err := services.NewJWTKeyManager()
+	if err != nil {
+		log.Fatalf("Failed to initialize JWT signing keys: %v", err)
+	}
+	middleware.SetKeyManager(jwtKeyManager)
+
+	// 初始化审计日志（写操作和登录，哈希链防篡改）
+	auditService, err := services.NewAuditService()
+	if err != nil {
+		log.Fatalf("Failed to initialize audit log: %v", err)
+	}
+
+	// 初始化 OIDC 单点登录（未配置 OIDC_ISSUER_URL 时不启用）
+	oidcService, err := services.NewOIDCService()
+	if err != nil {
+		log.Fatalf("Failed to initialize OIDC service: %v", err)
+	}
+
 	// 初始化 Ceph 服务
 	cephService, err := services.NewCephService()
 	if err != nil {
@@ -35,12 +79,42 @@ func main() {
 		// 继续运行，但 Ceph 功能将显示为不可用
 	}
 
+	// 初始化定时任务执行历史
+	runHistoryService := services.NewRunHistoryService()
+
+	// 初始化异步操作跟踪器（恢复等耗时操作）
+	operationTracker := services.NewOperationTracker()
+
+	// 初始化快照就绪跟踪器
+	snapshotTracker := services.NewSnapshotTracker(multiK8sService)
+
+	// 初始化命名空间备份（备份包存储在本地目录，配置 BACKUP_S3_BUCKET 后可选 S3 兼容对象存储）
+	backupService, err := services.NewNamespaceBackupService(multiK8sService, snapshotTracker)
+	if err != nil {
+		log.Fatalf("Failed to initialize namespace backup storage: %v", err)
+	}
+
+	// 初始化快照数据导出（配置 BACKUP_S3_BUCKET 和 DATA_MOVER_PASSWORD 后启用）
+	dataMover, err := services.NewDataMoverService(multiK8sService)
+	if err != nil {
+		log.Fatalf("Failed to initialize data mover: %v", err)
+	}
+
 	// 初始化控制器
-	snapshotController := controllers.NewSnapshotController(multiK8sService)
-	scheduledController := controllers.NewScheduledController(multiK8sService)
-	userController := controllers.NewUserController(userService)
+	snapshotController := controllers.NewSnapshotController(multiK8sService, operationTracker, snapshotTracker, rbacService)
+	groupSnapshotController := controllers.NewGroupSnapshotController(multiK8sService, operationTracker)
+	backupController := controllers.NewBackupController(backupService, operationTracker, rbacService)
+	exportController := controllers.NewExportController(dataMover, operationTracker, rbacService)
+	scheduledController := controllers.NewScheduledController(multiK8sService, runHistoryService, snapshotTracker, dataMover, multiK8sService.Events(), rbacService)
+	userController := controllers.NewUserController(userService, oidcService, apiTokenService, sessionService, loginLimiter)
 	cephController := controllers.NewCephController(cephService)
-	clusterController := controllers.NewClusterController(multiK8sService)
+	clusterController := controllers.NewClusterController(multiK8sService, userService)
+	rbacController := controllers.NewRBACController(rbacService, userService)
+	apiTokenController := controllers.NewAPITokenController(apiTokenService, userService, rbacService)
+	eventController := controllers.NewEventController(multiK8sService, rbacService, streamTickets)
+	jwtKeyController := controllers.NewJWTKeyController(jwtKeyManager)
+	twoFactorController := controllers.NewTwoFactorController(userService, rbacService)
+	auditController := controllers.NewAuditController(auditService)
 
 	// 设置 Gin 路由
 	r := gin.Default()
@@ -55,7 +129,7 @@ func main() {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:8080", "http://localhost:3000"}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", middleware.ClusterHeader}
 	r.Use(cors.New(config))
 
 	// 设置静态文件服务
@@ -96,38 +170,156 @@ func main() {
 		})
 	})
 
+	// 就绪检查接口（公开），informer 缓存首次同步完成前返回 503
+	r.GET("/ready", func(c *gin.Context) {
+		if !multiK8sService.CachesSynced() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "syncing",
+				"message": "Waiting for cluster caches to sync",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ok",
+		})
+	})
+
 	// API 路由组
 	api := r.Group("/api")
+	// 记录所有写操作和登录，需在认证之前注册以覆盖公开的登录接口
+	api.Use(middleware.AuditMiddleware(auditService))
 	{
 		// 认证相关接口（公开路径）
 		auth := api.Group("/auth")
 		{
 			auth.POST("/login", userController.Login)
+			auth.POST("/login/2fa", userController.LoginTwoFactor)
 			auth.POST("/register", userController.Register)
+			auth.POST("/refresh", userController.RefreshToken)
+			auth.GET("/registration", userController.GetRegistrationInfo)
+			auth.GET("/password-policy", userController.GetPasswordPolicy)
+			auth.GET("/oidc/config", userController.GetOIDCConfig)
+			auth.GET("/oidc/login", userController.OIDCLogin)
+			auth.GET("/oidc/callback", userController.OIDCCallback)
+			auth.GET("/jwks", jwtKeyController.GetJWKS)
 		}
 
 		// 需要认证的接口
 		authenticated := api.Group("")
-		authenticated.Use(middleware.AuthMiddleware(userService))
+		authenticated.Use(middleware.AuthMiddleware(userService, apiTokenService, sessionService, streamTickets))
+		// 按 TWO_FACTOR_POLICY 要求拥有写权限的用户先启用两步验证
+		authenticated.Use(middleware.TwoFactorMiddleware(userService, rbacService))
+		// 解析请求的目标集群（X-Cluster 请求头 / cluster 查询参数 / 用户默认集群）
+		authenticated.Use(middleware.ClusterMiddleware(multiK8sService))
 		{
+			// 权限检查：按请求目标集群和命名空间匹配用户角色及角色绑定
+			requireGlobal := func(verb string) gin.HandlerFunc {
+				return middleware.RequirePermission(rbacService, verb, middleware.GlobalScope)
+			}
+			requireInPath := func(verb string) gin.HandlerFunc {
+				return middleware.RequirePermission(rbacService, verb, middleware.PathNamespaceScope)
+			}
+			requireInBody := func(verb string) gin.HandlerFunc {
+				return middleware.RequirePermission(rbacService, verb, middleware.BodyNamespaceScope)
+			}
+			requireForTask := func(verb string) gin.HandlerFunc {
+				return middleware.RequirePermission(rbacService, verb, scheduledController.TaskScopes)
+			}
+			// 快照钩子会在业务 Pod 中执行命令，请求带有 hooks 时额外需要 pod:exec
+			requireExecForHooks := middleware.RequirePermission(rbacService, models.PermPodExec, middleware.BodyHooksScope)
+			requireExecForTaskHooks := middleware.RequirePermission(rbacService, models.PermPodExec, scheduledController.HookScopes)
+			// 定时任务开启数据导出时还需要 backup:create
+			requireBackupForTaskExport := middleware.RequirePermission(rbacService, models.PermBackupCreate, scheduledController.ExportScopes)
+
+			// 退出登录（需要认证，吊销当前访问令牌）
+			authenticated.POST("/auth/logout", userController.Logout)
+
+			// JWT 签名密钥管理（user:manage）
+			authenticated.GET("/auth/keys", requireGlobal(models.PermUserManage), jwtKeyController.GetKeys)
+			authenticated.POST("/auth/keys/rotate", requireGlobal(models.PermUserManage), jwtKeyController.RotateKey)
+
+			// 审计日志（audit:read）
+			authenticated.GET("/audit", requireGlobal(models.PermAuditRead), auditController.GetAuditRecords)
+			authenticated.GET("/audit/verify", requireGlobal(models.PermAuditRead), auditController.VerifyAuditLog)
+
 			// 用户相关接口（需要认证）
 			user := authenticated.Group("/user")
 			{
 				user.GET("/profile", userController.GetProfile)
+				user.GET("/permissions", rbacController.GetPermissions)
 				user.POST("/change-password", userController.ChangePassword)
-				// 管理员专用接口
-				user.GET("/all", middleware.RequireAdmin(), userController.GetAllUsers)
-				user.DELETE("/:username", middleware.RequireAdmin(), userController.DeleteUser)
+				// 两步验证
+				user.GET("/2fa", twoFactorController.GetStatus)
+				user.POST("/2fa/enroll", twoFactorController.Enroll)
+				user.POST("/2fa/verify", twoFactorController.Verify)
+				user.POST("/2fa/disable", twoFactorController.Disable)
+				user.POST("/2fa/recovery-codes", twoFactorController.RegenerateRecoveryCodes)
+				// API token（拥有 user:manage 权限时可以吊销任意用户的令牌）
+				user.GET("/tokens", apiTokenController.GetTokens)
+				user.POST("/tokens", apiTokenController.CreateToken)
+				user.DELETE("/tokens/:id", apiTokenController.RevokeToken)
+				user.GET("/tokens/all", requireGlobal(models.PermUserManage), apiTokenController.GetAllTokens)
+				user.POST("/:username/tokens", requireGlobal(models.PermUserManage), apiTokenController.CreateUserToken)
+				// 用户管理接口（user:manage）
+				user.GET("/all", requireGlobal(models.PermUserManage), userController.GetAllUsers)
+				user.DELETE("/:username", requireGlobal(models.PermUserManage), userController.DeleteUser)
+				user.POST("", requireGlobal(models.PermUserManage), userController.CreateUser)
+				user.PUT("/:username/role", requireGlobal(models.PermUserManage), userController.UpdateUserRole)
+				user.PUT("/:username/groups", requireGlobal(models.PermUserManage), rbacController.UpdateUserGroups)
+				user.POST("/:username/reset-password", requireGlobal(models.PermUserManage), userController.ResetPassword)
+				user.POST("/:username/disable", requireGlobal(models.PermUserManage), userController.DisableUser)
+				user.POST("/:username/enable", requireGlobal(models.PermUserManage), userController.EnableUser)
+				user.POST("/:username/unlock", requireGlobal(models.PermUserManage), userController.UnlockUser)
+				user.POST("/:username/2fa/reset", requireGlobal(models.PermUserManage), twoFactorController.ResetUserTwoFactor)
+				user.GET("/lockouts", requireGlobal(models.PermUserManage), userController.GetLoginLockouts)
+				user.POST("/lockouts/unlock-ip", requireGlobal(models.PermUserManage), userController.UnlockIP)
+				user.GET("/invites", requireGlobal(models.PermUserManage), userController.GetInvites)
+				user.POST("/invites", requireGlobal(models.PermUserManage), userController.CreateInvite)
+				user.DELETE("/invites/:id", requireGlobal(models.PermUserManage), userController.DeleteInvite)
+			}
+
+			// 角色和角色绑定管理接口（user:manage）
+			rbacGroup := authenticated.Group("/rbac")
+			rbacGroup.Use(requireGlobal(models.PermUserManage))
+			{
+				rbacGroup.GET("/roles", rbacController.GetRoles)
+				rbacGroup.POST("/roles", rbacController.CreateRole)
+				rbacGroup.PUT("/roles/:name", rbacController.UpdateRole)
+				rbacGroup.DELETE("/roles/:name", rbacController.DeleteRole)
+				rbacGroup.GET("/bindings", rbacController.GetBindings)
+				rbacGroup.POST("/bindings", rbacController.CreateBinding)
+				rbacGroup.PUT("/bindings/:id", rbacController.UpdateBinding)
+				rbacGroup.DELETE("/bindings/:id", rbacController.DeleteBinding)
 			}
 
-			// 只读接口（认证用户都可以访问）
+			// 只读接口（认证用户都可以访问，列表按用户可见的命名空间过滤）
 			// VolumeSnapshotClass 相关接口
 			authenticated.GET("/volumesnapshotclasses", snapshotController.GetVolumeSnapshotClasses)
 
 			// VolumeSnapshot 查询接口
 			authenticated.GET("/volumesnapshots", snapshotController.GetVolumeSnapshots)
+			authenticated.GET("/volumesnapshots/:namespace/:name/tracking", requireInPath(models.PermRead), snapshotController.GetVolumeSnapshotTracking)
 			authenticated.GET("/volumesnapshotcontents/:name", snapshotController.GetVolumeSnapshotContent)
 
+			// 快照组查询接口
+			authenticated.GET("/groupsnapshots/:namespace", requireInPath(models.PermRead), groupSnapshotController.GetGroupSnapshots)
+			authenticated.GET("/groupsnapshots/:namespace/:name", requireInPath(models.PermRead), groupSnapshotController.GetGroupSnapshot)
+
+			// 命名空间备份（列表按权限过滤命名空间）
+			authenticated.GET("/backups/namespaces", backupController.GetNamespaceBackups)
+			authenticated.GET("/backups/namespaces/:namespace/:id", requireInPath(models.PermRead), backupController.GetNamespaceBackup)
+
+			// 快照数据导出记录（列表按权限过滤命名空间）
+			authenticated.GET("/backups/exports", exportController.GetExports)
+			authenticated.GET("/backups/exports/:namespace/:id", requireInPath(models.PermRead), exportController.GetExport)
+
+			// 资源变更事件流（SSE）
+			authenticated.POST("/events/ticket", eventController.CreateStreamTicket)
+			authenticated.GET("/events/stream", eventController.Stream)
+
+			// 异步操作进度查询接口
+			authenticated.GET("/operations/:id", snapshotController.GetOperation)
+
 			// PVC 相关接口
 			authenticated.GET("/pvcs", snapshotController.GetPVCs)
 
@@ -139,6 +331,8 @@ func main() {
 
 			// 定时任务查询接口
 			authenticated.GET("/scheduled-snapshots", scheduledController.GetScheduledSnapshots)
+			authenticated.GET("/scheduled-snapshots/:id/runs", requireForTask(models.PermRead), scheduledController.GetScheduledRuns)
+			authenticated.GET("/scheduled-snapshots/:id/retention/preview", requireForTask(models.PermRead), scheduledController.PreviewRetention)
 
 			// Ceph 集群信息接口（只读）
 			ceph := authenticated.Group("/ceph")
@@ -149,31 +343,44 @@ func main() {
 				ceph.GET("/connection/status", cephController.GetConnectionStatus)
 			}
 
-			// K8s 集群管理接口（只读）
+			// K8s 集群管理接口
 			clusters := authenticated.Group("/clusters")
 			{
 				clusters.GET("", clusterController.GetClusters)
 				clusters.GET("/current", clusterController.GetCurrentCluster)
+				// 设置当前用户的默认集群，只影响该用户自己的请求（cluster:switch）
+				clusters.POST("/switch", middleware.RequirePermission(rbacService, models.PermClusterSwitch, clusterController.SwitchScope), clusterController.SwitchCluster)
 			}
 
-			// 需要管理员权限的写操作接口
-			writeOps := authenticated.Group("")
-			writeOps.Use(middleware.RequireWritePermission())
-			{
-				// VolumeSnapshot 写操作
-				writeOps.POST("/volumesnapshots", snapshotController.CreateVolumeSnapshot)
-				writeOps.DELETE("/volumesnapshots/:namespace/:name", snapshotController.DeleteVolumeSnapshot)
-				writeOps.POST("/volumesnapshots/:namespace/:name/force-delete", snapshotController.ForceDeleteVolumeSnapshot)
-
-				// 定时任务写操作
-				writeOps.POST("/scheduled-snapshots", scheduledController.CreateScheduledSnapshot)
-				writeOps.PUT("/scheduled-snapshots/:id", scheduledController.UpdateScheduledSnapshot)
-				writeOps.DELETE("/scheduled-snapshots/:id", scheduledController.DeleteScheduledSnapshot)
-				writeOps.POST("/scheduled-snapshots/:id/toggle", scheduledController.ToggleScheduledSnapshot)
-
-				// 集群切换操作（需要写权限）
-				writeOps.POST("/clusters/switch", clusterController.SwitchCluster)
-			}
+			// VolumeSnapshot 写操作
+			authenticated.POST("/volumesnapshots", requireInBody(models.PermSnapshotCreate), requireExecForHooks, snapshotController.CreateVolumeSnapshot)
+			authenticated.DELETE("/volumesnapshots/:namespace/:name", requireInPath(models.PermSnapshotDelete), snapshotController.DeleteVolumeSnapshot)
+			authenticated.POST("/volumesnapshots/:namespace/:name/force-delete", requireInPath(models.PermSnapshotForceDelete), snapshotController.ForceDeleteVolumeSnapshot)
+			authenticated.POST("/volumesnapshots/:namespace/:name/restore", requireInPath(models.PermSnapshotRestore), snapshotController.RestoreVolumeSnapshot)
+			authenticated.POST("/volumesnapshots/:namespace/:name/rollback", requireInPath(models.PermSnapshotRestore), snapshotController.RollbackVolumeSnapshot)
+
+			// 快照组写操作
+			authenticated.POST("/groupsnapshots", requireInBody(models.PermSnapshotCreate), requireExecForHooks, groupSnapshotController.CreateGroupSnapshot)
+			authenticated.DELETE("/groupsnapshots/:namespace/:name", requireInPath(models.PermSnapshotDelete), groupSnapshotController.DeleteGroupSnapshot)
+			authenticated.POST("/groupsnapshots/:namespace/:name/restore", requireInPath(models.PermSnapshotRestore), groupSnapshotController.RestoreGroupSnapshot)
+
+			// 命名空间备份写操作
+			authenticated.POST("/backups/namespaces", requireInBody(models.PermBackupCreate), backupController.CreateNamespaceBackup)
+			authenticated.DELETE("/backups/namespaces/:namespace/:id", requireInPath(models.PermBackupDelete), backupController.DeleteNamespaceBackup)
+			authenticated.POST("/backups/namespaces/:namespace/:id/restore", requireInPath(models.PermBackupRestore), backupController.RestoreNamespaceBackup)
+
+			// 快照数据导出写操作
+			authenticated.POST("/volumesnapshots/:namespace/:name/export", requireInPath(models.PermBackupCreate), exportController.ExportVolumeSnapshot)
+			authenticated.DELETE("/backups/exports/:namespace/:id", requireInPath(models.PermBackupDelete), exportController.DeleteExport)
+
+			// PVC 写操作
+			authenticated.POST("/pvcs/:namespace/:name/clone", requireInPath(models.PermPVCClone), snapshotController.ClonePVC)
+
+			// 定时任务写操作（task:manage，需在任务的每个目标集群中授权）
+			authenticated.POST("/scheduled-snapshots", requireForTask(models.PermTaskManage), requireExecForTaskHooks, requireBackupForTaskExport, scheduledController.CreateScheduledSnapshot)
+			authenticated.PUT("/scheduled-snapshots/:id", requireForTask(models.PermTaskManage), requireExecForTaskHooks, requireBackupForTaskExport, scheduledController.UpdateScheduledSnapshot)
+			authenticated.DELETE("/scheduled-snapshots/:id", requireForTask(models.PermTaskManage), scheduledController.DeleteScheduledSnapshot)
+			authenticated.POST("/scheduled-snapshots/:id/toggle", requireForTask(models.PermTaskManage), scheduledController.ToggleScheduledSnapshot)
 		}
 	}
 
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+const (
+	// 请求体摘要的最大长度，超出时只记录字段名
+	auditRequestSummaryLimit = 4096
+	// 摘要中字符串字段的最大长度
+	auditStringLimit = 256
+	// 捕获的响应体长度，用于记录错误信息
+	auditResponseCaptureLimit = 4096
+	// 写操作请求体的上限，接口都只接受小型 JSON，超出时直接拒绝，避免缓存大请求耗尽内存
+	MaxRequestBodyBytes = 1 << 20
+)
+
+// auditedReadPaths 需要审计的 GET 接口（登录回调等）
+var auditedReadPaths = map[string]bool{
+	"/api/auth/oidc/callback": true,
+}
+
+// auditSensitiveKeys 请求体中需要脱敏的字段（小写，包含即匹配）
+var auditSensitiveKeys = []string{"password", "token", "secret", "challenge", "credential", "privatekey"}
+
+// auditResponseWriter 记录响应体开头部分，用于提取错误信息
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if remaining := auditResponseCaptureLimit - w.body.Len(); remaining > 0 {
+		if len(data) < remaining {
+			remaining = len(data)
+		}
+		w.body.Write(data[:remaining])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// AuditMiddleware 记录所有写操作和登录的审计日志，需在 AuthMiddleware 之前使用，
+// 处理完成后从上下文读取操作者和目标集群
+func AuditMiddleware(audit *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAuditedRequest(c.Request) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		var body []byte
+		truncated := false
+		if c.Request.Body != nil {
+			body, _ = ioutil.ReadAll(io.LimitReader(c.Request.Body, MaxRequestBodyBytes+1))
+			if len(body) > MaxRequestBodyBytes {
+				truncated = true
+				body = nil
+			}
+			c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		if truncated {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(413, fmt.Sprintf("请求体过大，最大 %d 字节", MaxRequestBodyBytes)))
+		} else {
+			c.Next()
+		}
+
+		record := models.AuditRecord{
+			Time:       start,
+			SourceIP:   c.ClientIP(),
+			Cluster:    GetCurrentCluster(c),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Status:     writer.Status(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if record.Route == "" {
+			record.Route = record.Path
+		}
+
+		summary, fields := summarizeAuditBody(body)
+		if truncated {
+			summary, _ = json.Marshal(map[string]interface{}{"_truncated": true, "_bytesOver": MaxRequestBodyBytes})
+		}
+		record.Request = summary
+		record.Target = auditTarget(c, fields)
+
+		// 操作者：认证用户 > 处理函数设置的登录用户 > 请求体中的用户名
+		if username, ok := GetCurrentUsername(c); ok {
+			record.Actor = username
+			record.AuthMethod = "jwt"
+			if token, viaToken := GetCurrentAPIToken(c); viaToken {
+				record.AuthMethod = "api-token"
+				record.APITokenID = token.ID
+			}
+		} else if actor := c.GetString(auditActorKey); actor != "" {
+			record.Actor = actor
+		} else if username, ok := fields["username"].(string); ok {
+			record.Actor = username
+		}
+
+		record.Outcome = models.AuditOutcomeSuccess
+		if message := c.GetString(auditErrorKey); message != "" {
+			record.Outcome = models.AuditOutcomeFailure
+			record.Error = message
+		} else if record.Status >= http.StatusBadRequest {
+			record.Outcome = models.AuditOutcomeFailure
+			record.Error = auditResponseMessage(writer.body.Bytes())
+		}
+
+		if err := audit.Record(record); err != nil {
+			fmt.Printf("写入审计日志失败: %v\n", err)
+		}
+	}
+}
+
+const (
+	auditActorKey = "auditActor"
+	auditErrorKey = "auditError"
+)
+
+// SetAuditActor 公开接口（如登录第二步、刷新令牌）在处理函数中确定操作者
+func SetAuditActor(c *gin.Context, username string) {
+	c.Set(auditActorKey, username)
+}
+
+// SetAuditError 响应状态无法反映失败时（如重定向回登录页），记录失败原因
+func SetAuditError(c *gin.Context, message string) {
+	c.Set(auditErrorKey, message)
+}
+
+func isAuditedRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return auditedReadPaths[r.URL.Path]
+	}
+}
+
+// summarizeAuditBody 解析 JSON 请求体并脱敏，返回摘要和顶层字段
+func summarizeAuditBody(body []byte) (json.RawMessage, map[string]interface{}) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		summary, _ := json.Marshal(map[string]interface{}{"_unparsed": true, "_bytes": len(body)})
+		return summary, nil
+	}
+
+	summary, err := json.Marshal(redactAuditValue(fields))
+	if err != nil || len(summary) > auditRequestSummaryLimit {
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		summary, _ = json.Marshal(map[string]interface{}{"_truncated": true, "_keys": keys})
+	}
+	return summary, fields
+}
+
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if isSensitiveAuditKey(key) {
+				redacted[key] = "[REDACTED]"
+				continue
+			}
+			redacted[key] = redactAuditValue(item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactAuditValue(item)
+		}
+		return redacted
+	case string:
+		if len(v) > auditStringLimit {
+			return v[:auditStringLimit] + "..."
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// isSensitiveAuditKey 不区分大小写匹配敏感字段，code 按后缀匹配以覆盖 totpCode、recoveryCodes 等验证码字段
+func isSensitiveAuditKey(key string) bool {
+	lower := strings.ToLower(key)
+	if strings.HasSuffix(lower, "code") || strings.HasSuffix(lower, "codes") {
+		return true
+	}
+	for _, sensitive := range auditSensitiveKeys {
+		if strings.Contains(lower, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditTarget 从路由参数和请求体中提取操作对象
+func auditTarget(c *gin.Context, fields map[string]interface{}) map[string]string {
+	target := make(map[string]string)
+	for _, param := range c.Params {
+		target[param.Key] = param.Value
+	}
+	for _, key := range []string{"namespace", "name", "pvcName", "snapshotName"} {
+		if _, exists := target[key]; exists {
+			continue
+		}
+		if value, ok := fields[key].(string); ok && value != "" {
+			target[key] = value
+		}
+	}
+	if len(target) == 0 {
+		return nil
+	}
+	return target
+}
+
+// auditResponseMessage 从统一响应结构中提取错误信息
+func auditResponseMessage(body []byte) string {
+	var response struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	return response.Message
+}
@@ -16,74 +16,146 @@ import (
 	"k8s-volume-snapshots/services"
 )
 
+// 访问令牌默认有效期，过期后使用刷新令牌换取新的访问令牌
+const DefaultAccessTokenTTL = 15 * time.Minute
+
 var (
-	// JWT密钥，从环境变量读取，如果没有则使用随机生成的密钥
-	jwtSecret []byte
+	// JWT签名密钥管理器，由 main 在启动时通过 SetKeyManager 设置
+	keyManager *services.JWTKeyManager
+	// 访问令牌有效期，从 ACCESS_TOKEN_TTL 读取
+	accessTokenTTL = DefaultAccessTokenTTL
 )
 
+// ErrTokenRevoked 令牌已被吊销，或在签发后修改了密码、角色
+var ErrTokenRevoked = errors.New("token已失效，请重新登录")
+
 func init() {
-	// 从环境变量读取JWT密钥
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// 如果没有设置环境变量，生成一个随机密钥（注意：重启后会变化）
-		bytes := make([]byte, 32)
-		rand.Read(bytes)
-		secret = hex.EncodeToString(bytes)
-		println("警告: 使用随机生成的JWT密钥，重启后将失效。建议设置JWT_SECRET环境变量")
+	if ttl := os.Getenv("ACCESS_TOKEN_TTL"); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err == nil && d > 0 {
+			accessTokenTTL = d
+		} else {
+			println("警告: 无效的 ACCESS_TOKEN_TTL，使用默认值 15m")
+		}
 	}
-	jwtSecret = []byte(secret)
+}
+
+// SetKeyManager 设置用于签发和验证访问令牌的密钥管理器
+func SetKeyManager(manager *services.JWTKeyManager) {
+	keyManager = manager
+}
+
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
 }
 
 // Claims JWT声明结构
 type Claims struct {
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// Generation 签发时用户的令牌代数，与用户当前代数不一致时令牌失效
+	Generation int `json:"gen"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
+// GenerateToken 生成短期有效的访问令牌，jti 用于吊销
 func GenerateToken(user *models.User) (string, error) {
-	// 设置token过期时间为24小时
-	expirationTime := time.Now().Add(24 * time.Hour)
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
 
+	now := time.Now()
 	claims := &Claims{
-		Username: user.Username,
-		Role:     user.Role,
+		Username:   user.Username,
+		Role:       user.Role,
+		Generation: user.TokenGeneration,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        hex.EncodeToString(jti),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   user.ID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	return keyManager.Sign(claims)
+}
+
+// ParseToken 解析JWT token，并检查 jti 是否已吊销、用户是否仍然存在以及令牌代数是否一致
+func ParseToken(tokenString string, userService *services.UserService, sessions *services.SessionService) (*Claims, *models.User, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyManager.Keyfunc, jwt.WithValidMethods(keyManager.ValidMethods()))
+
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
-	return tokenString, nil
-}
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, nil, errors.New("invalid token")
+	}
 
-// ParseToken 解析JWT token
-func ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	if sessions.IsRevoked(claims.ID) {
+		return nil, nil, ErrTokenRevoked
+	}
 
-	if err != nil {
-		return nil, err
+	// 用户被删除后重建同名用户，旧令牌不能继续使用
+	user, err := userService.GetUser(claims.Username)
+	if err != nil || user.ID != claims.Subject {
+		return nil, nil, errors.New("用户不存在")
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if user.TokenGeneration != claims.Generation {
+		return nil, nil, ErrTokenRevoked
 	}
 
-	return nil, errors.New("invalid token")
+	return claims, user, nil
 }
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware(userService *services.UserService) gin.HandlerFunc {
+// passwordChangeAllowedPaths 修改初始密码之前允许访问的接口
+var passwordChangeAllowedPaths = map[string]bool{
+	"/api/user/profile":         true,
+	"/api/user/permissions":     true,
+	"/api/user/change-password": true,
+	"/api/auth/logout":          true,
+}
+
+// twoFactorEnrollmentPaths 按策略必须启用两步验证的用户在启用之前允许访问的接口
+var twoFactorEnrollmentPaths = map[string]bool{
+	"/api/user/profile":         true,
+	"/api/user/permissions":     true,
+	"/api/user/change-password": true,
+	"/api/user/2fa":             true,
+	"/api/user/2fa/enroll":      true,
+	"/api/user/2fa/verify":      true,
+	"/api/auth/logout":          true,
+}
+
+// TwoFactorMiddleware 按 TWO_FACTOR_POLICY 要求拥有写权限的用户先启用两步验证
+// API token 由已登录的用户签发，不受此限制
+func TwoFactorMiddleware(userService *services.UserService, rbac *services.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetCurrentUser(c)
+		if !exists || user.TwoFactorEnabled || twoFactorEnrollmentPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		if _, viaToken := GetCurrentAPIToken(c); viaToken {
+			c.Next()
+			return
+		}
+
+		if userService.TwoFactorRequired(user, rbac.HasWritePermission(user)) {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(403, services.ErrTwoFactorRequired.Error()))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AuthMiddleware 认证中间件，接受登录签发的 JWT 和 API token
+func AuthMiddleware(userService *services.UserService, tokenService *services.APITokenService, sessions *services.SessionService, tickets *services.StreamTicketService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 公开路径，不需要认证
 		publicPaths := []string{
@@ -99,8 +171,20 @@ func AuthMiddleware(userService *services.UserService) gin.HandlerFunc {
 			}
 		}
 
-		// 从请求头获取token
+		// 浏览器 EventSource 无法设置请求头，事件流接口接受通过 POST /api/events/ticket 获取的一次性票据
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && path == "/api/events/stream" && c.Query("ticket") != "" {
+			user, ok := redeemStreamTicket(userService, tickets, c.Query("ticket"))
+			if !ok {
+				c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "无效或已过期的事件流票据"))
+				c.Abort()
+				return
+			}
+			authorizeUser(c, user, nil, nil)
+			return
+		}
+
+		// 从请求头获取token
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "缺少Authorization头"))
 			c.Abort()
@@ -117,29 +201,88 @@ func AuthMiddleware(userService *services.UserService) gin.HandlerFunc {
 			return
 		}
 
-		// 解析token
-		claims, err := ParseToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "无效的token"))
-			c.Abort()
-			return
-		}
+		var user *models.User
+		var claims *Claims
+		var apiToken *models.APIToken
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			// API token
+			var err error
+			apiToken, err = tokenService.Validate(tokenString, c.ClientIP())
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, err.Error()))
+				c.Abort()
+				return
+			}
 
-		// 验证用户是否仍然存在
-		user, err := userService.GetUser(claims.Username)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户不存在"))
-			c.Abort()
-			return
+			// 用户被删除后重建同名用户，旧令牌不能继续使用
+			user, err = userService.GetUser(apiToken.Username)
+			if err != nil || user.ID != apiToken.UserID {
+				c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, "用户不存在"))
+				c.Abort()
+				return
+			}
+			user.TokenScopes = apiToken.Scopes
+		} else {
+			// 解析token
+			var err error
+			claims, user, err = ParseToken(tokenString, userService, sessions)
+			if err != nil {
+				message := "无效的token"
+				if errors.Is(err, ErrTokenRevoked) {
+					message = err.Error()
+				}
+				c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, message))
+				c.Abort()
+				return
+			}
 		}
 
-		// 将用户信息存储到上下文中
-		c.Set("user", user)
-		c.Set("username", user.Username)
-		c.Set("role", user.Role)
+		authorizeUser(c, user, apiToken, claims)
+	}
+}
 
-		c.Next()
+// redeemStreamTicket 使用事件流票据并加载对应用户，用户被删除后重建的同名用户不能使用旧票据
+func redeemStreamTicket(userService *services.UserService, tickets *services.StreamTicketService, ticket string) (*models.User, bool) {
+	username, userID, tokenScopes, ok := tickets.Redeem(ticket)
+	if !ok {
+		return nil, false
 	}
+	user, err := userService.GetUser(username)
+	if err != nil || user.ID != userID {
+		return nil, false
+	}
+	user.TokenScopes = tokenScopes
+	return user, true
+}
+
+// authorizeUser 检查已认证用户的状态并将其写入请求上下文
+func authorizeUser(c *gin.Context, user *models.User, apiToken *models.APIToken, claims *Claims) {
+	// 被禁用的用户即使持有未过期的token也不能访问
+	if user.Disabled {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(401, services.ErrUserDisabled.Error()))
+		c.Abort()
+		return
+	}
+
+	// 必须修改初始密码的用户只能访问修改密码所需的接口
+	if user.MustChangePassword && !passwordChangeAllowedPaths[c.Request.URL.Path] {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(403, services.ErrPasswordChangeRequired.Error()))
+		c.Abort()
+		return
+	}
+
+	// 将用户信息存储到上下文中
+	c.Set("user", user)
+	c.Set("username", user.Username)
+	c.Set("role", user.Role)
+	if apiToken != nil {
+		c.Set("apiToken", apiToken)
+	}
+	if claims != nil {
+		c.Set("claims", claims)
+	}
+
+	c.Next()
 }
 
 // RequireRole 角色权限中间件
@@ -194,6 +337,24 @@ func GetCurrentUsername(c *gin.Context) (string, bool) {
 	return username.(string), true
 }
 
+// GetCurrentClaims 请求通过登录令牌认证时返回令牌声明
+func GetCurrentClaims(c *gin.Context) (*Claims, bool) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	return claims.(*Claims), true
+}
+
+// GetCurrentAPIToken 请求通过 API token 认证时返回该令牌
+func GetCurrentAPIToken(c *gin.Context) (*models.APIToken, bool) {
+	token, exists := c.Get("apiToken")
+	if !exists {
+		return nil, false
+	}
+	return token.(*models.APIToken), true
+}
+
 // RequireWritePermission 需要写权限（创建、删除、修改操作）
 func RequireWritePermission() gin.HandlerFunc {
 	return func(c *gin.Context) {
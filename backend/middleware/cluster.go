@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+// ClusterHeader 指定请求目标集群的请求头
+const ClusterHeader = "X-Cluster"
+
+// ClusterMiddleware 解析请求的目标集群，需在 AuthMiddleware 之后使用
+// 优先级：X-Cluster 请求头 > cluster 查询参数 > 用户默认集群 > 系统默认集群
+// 显式指定的集群不可用时返回 400；用户默认集群失效时回退到系统默认集群
+func ClusterMiddleware(multiClusterService *services.MultiClusterK8sService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clusterName := c.GetHeader(ClusterHeader)
+		if clusterName == "" {
+			clusterName = c.Query("cluster")
+		}
+
+		if clusterName != "" {
+			if err := multiClusterService.ValidateCluster(clusterName); err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+				c.Abort()
+				return
+			}
+		} else {
+			clusterName = multiClusterService.GetDefaultCluster()
+			if user, ok := GetCurrentUser(c); ok && user.DefaultCluster != "" {
+				if err := multiClusterService.ValidateCluster(user.DefaultCluster); err == nil {
+					clusterName = user.DefaultCluster
+				}
+			}
+		}
+
+		c.Request = c.Request.WithContext(services.WithCluster(c.Request.Context(), clusterName))
+
+		c.Next()
+	}
+}
+
+// GetCurrentCluster 从上下文获取本次请求的目标集群
+func GetCurrentCluster(c *gin.Context) string {
+	return services.ClusterFromContext(c.Request.Context())
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"k8s-volume-snapshots/models"
+	"k8s-volume-snapshots/services"
+)
+
+// MaxPeekBodyBytes 授权前读取请求体的上限，超出时拒绝请求
+const MaxPeekBodyBytes = 1 << 20
+
+// ScopeResolver 从请求中解析权限检查的范围，返回多个范围时必须全部通过
+type ScopeResolver func(c *gin.Context) ([]services.Scope, error)
+
+// RequirePermission 权限中间件，需在 AuthMiddleware 和 ClusterMiddleware 之后使用
+func RequirePermission(rbac *services.RBACService, verb string, resolve ScopeResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetCurrentUser(c)
+		if !exists {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(403, "权限不足"))
+			c.Abort()
+			return
+		}
+
+		scopes, err := resolve(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(400, err.Error()))
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !rbac.Authorize(user, verb, scope) {
+				c.JSON(http.StatusForbidden, models.NewErrorResponse(403, permissionDeniedMessage(verb, scope)))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func permissionDeniedMessage(verb string, scope services.Scope) string {
+	message := "权限不足: 缺少 " + verb
+	if scope.Cluster != "" {
+		message += "，集群 " + scope.Cluster
+	}
+	if scope.Namespace != "" {
+		message += "，命名空间 " + scope.Namespace
+	}
+	return message
+}
+
+// GlobalScope 全局操作（如用户管理），只有不限制集群和命名空间的授权才能通过
+func GlobalScope(c *gin.Context) ([]services.Scope, error) {
+	return []services.Scope{{}}, nil
+}
+
+// ClusterScope 请求目标集群内的集群级操作
+func ClusterScope(c *gin.Context) ([]services.Scope, error) {
+	return []services.Scope{{Cluster: GetCurrentCluster(c)}}, nil
+}
+
+// PathNamespaceScope 请求目标集群中路径参数 namespace 指定的命名空间
+func PathNamespaceScope(c *gin.Context) ([]services.Scope, error) {
+	return []services.Scope{{Cluster: GetCurrentCluster(c), Namespace: c.Param("namespace")}}, nil
+}
+
+// BodyNamespaceScope 请求目标集群中 JSON 请求体 namespace 字段指定的命名空间
+// 读取后会恢复请求体，处理函数仍可正常绑定
+func BodyNamespaceScope(c *gin.Context) ([]services.Scope, error) {
+	var body struct {
+		Namespace string `json:"namespace"`
+	}
+	if err := PeekJSONBody(c, &body); err != nil {
+		return nil, err
+	}
+	return []services.Scope{{Cluster: GetCurrentCluster(c), Namespace: body.Namespace}}, nil
+}
+
+// BodyHooksScope 请求体带有 hooks 时返回 namespace 字段指定的命名空间，否则不需要额外授权
+func BodyHooksScope(c *gin.Context) ([]services.Scope, error) {
+	var body struct {
+		Namespace string                `json:"namespace"`
+		Hooks     *models.SnapshotHooks `json:"hooks"`
+	}
+	if err := PeekJSONBody(c, &body); err != nil {
+		return nil, err
+	}
+	if body.Hooks == nil {
+		return nil, nil
+	}
+	return []services.Scope{{Cluster: GetCurrentCluster(c), Namespace: body.Namespace}}, nil
+}
+
+// PeekJSONBody 解析 JSON 请求体但不消耗它；请求体格式错误时交给处理函数报告
+// 请求体超过 MaxPeekBodyBytes 时返回错误，避免未授权的请求占用大量内存
+func PeekJSONBody(c *gin.Context, obj interface{}) error {
+	if c.Request.Body == nil {
+		return nil
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, MaxPeekBodyBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxPeekBodyBytes {
+		return fmt.Errorf("请求体过大，最大 %d 字节", MaxPeekBodyBytes)
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, obj)
+	}
+	return nil
+}
+
+// NamespaceFilter 返回当前用户在请求目标集群中拥有某个权限的命名空间判断函数，为 nil 表示不限制
+func NamespaceFilter(c *gin.Context, rbac *services.RBACService, verb string) func(namespace string) bool {
+	user, _ := GetCurrentUser(c)
+	return rbac.NamespaceFilter(user, verb, GetCurrentCluster(c))
+}
+
+// Authorize 判断当前用户是否在指定范围内拥有某个权限，供处理函数做数据相关的检查
+func Authorize(c *gin.Context, rbac *services.RBACService, verb string, scope services.Scope) bool {
+	user, _ := GetCurrentUser(c)
+	return rbac.Authorize(user, verb, scope)
+}
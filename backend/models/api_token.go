@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// APITokenPrefix API token 的固定前缀，用于和 JWT 区分
+const APITokenPrefix = "kvs_"
+
+// APIToken 长期有效的 API token，供 CI 和自动化脚本使用，只保存令牌的哈希
+type APIToken struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Username  string `json:"username"`
+	UserID    string `json:"userId"`
+	TokenHash string `json:"tokenHash,omitempty"`
+	// Hint 令牌末尾几位，便于在列表中辨认
+	Hint string `json:"hint"`
+	// Scopes 权限动词（支持通配符），实际权限为用户权限与 Scopes 的交集
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `json:"lastUsedIp,omitempty"`
+}
+
+// CreateAPITokenRequest 创建 API token 请求
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=64"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"required,min=1,max=365"`
+}
+
+// CreateAPITokenResponse 创建 API token 响应，令牌明文只返回这一次
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
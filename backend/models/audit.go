@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 审计记录结果
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditRecord 一次写操作或登录的审计记录
+// 每条记录的 Hash 由上一条记录的 Hash 和本条内容计算，修改或删除任意一条都会使之后的校验失败
+type AuditRecord struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Actor 操作者用户名；登录失败时为请求中的用户名
+	Actor string `json:"actor"`
+	// AuthMethod 认证方式：jwt、api-token，公开接口为空
+	AuthMethod string            `json:"authMethod,omitempty"`
+	APITokenID string            `json:"apiTokenId,omitempty"`
+	SourceIP   string            `json:"sourceIp"`
+	Cluster    string            `json:"cluster,omitempty"`
+	Method     string            `json:"method"`
+	Route      string            `json:"route"`
+	Path       string            `json:"path"`
+	Target     map[string]string `json:"target,omitempty"`
+	// Request 请求体摘要（JSON），密码、令牌等敏感字段已脱敏；保持原始字节以便重新计算哈希
+	Request    json.RawMessage `json:"request,omitempty"`
+	Status     int             `json:"status"`
+	Outcome    string          `json:"outcome"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"durationMs"`
+	PrevHash   string          `json:"prevHash"`
+	Hash       string          `json:"hash"`
+}
+
+// AuditQuery 审计记录查询条件
+type AuditQuery struct {
+	Actor     string    `form:"actor"`
+	Method    string    `form:"method"`
+	Route     string    `form:"route"` // 路由包含该字符串
+	Cluster   string    `form:"cluster"`
+	Namespace string    `form:"namespace"`
+	Outcome   string    `form:"outcome" binding:"omitempty,oneof=success failure"`
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit" binding:"omitempty,min=1,max=10000"`
+	Format    string    `form:"format" binding:"omitempty,oneof=json csv"`
+}
+
+// AuditList 审计记录查询结果（按时间倒序）
+type AuditList struct {
+	Items []AuditRecord `json:"items"`
+	Total int           `json:"total"` // 匹配条件的总数
+}
+
+// AuditVerifyResult 审计日志哈希链校验结果
+type AuditVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Records  int    `json:"records"`
+	FirstSeq uint64 `json:"firstSeq"`
+	LastSeq  uint64 `json:"lastSeq"`
+	// Files 参与校验的日志文件，最早的轮换文件被删除后从剩余的第一条记录开始校验
+	Files []string `json:"files"`
+	Error string   `json:"error,omitempty"`
+}
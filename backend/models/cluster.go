@@ -39,6 +39,7 @@ type ClusterInfo struct {
 	Enabled     bool   `json:"enabled"`
 	Status      string `json:"status"`      // online, offline, error
 	LastCheck   time.Time `json:"last_check"`
+	CacheSynced bool   `json:"cache_synced"` // informer 缓存是否已同步
 }
 
 // ClusterSwitchRequest 切换集群请求
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 资源事件类型
+const (
+	EventTypeAdded    = "ADDED"
+	EventTypeModified = "MODIFIED"
+	EventTypeDeleted  = "DELETED"
+)
+
+// 资源事件对象类型
+const (
+	EventKindVolumeSnapshot        = "VolumeSnapshot"
+	EventKindVolumeSnapshotContent = "VolumeSnapshotContent"
+	EventKindPVC                   = "PersistentVolumeClaim"
+	EventKindScheduledRun          = "ScheduledRun"
+)
+
+// ResourceEvent 推送给前端的资源变更事件
+type ResourceEvent struct {
+	Type      string      `json:"type"` // ADDED, MODIFIED, DELETED
+	Kind      string      `json:"kind"`
+	Cluster   string      `json:"cluster,omitempty"`   // 为空表示与集群无关（如跨集群的定时任务执行记录）
+	Namespace string      `json:"namespace,omitempty"` // VolumeSnapshotContent 为其绑定快照的命名空间
+	Name      string      `json:"name"`
+	Object    interface{} `json:"object,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// 导出方式，由源 PVC 的 volumeMode 决定
+const (
+	ExportModeFilesystem = "filesystem" // 备份挂载目录中的文件
+	ExportModeBlock      = "block"      // 以数据流备份整个块设备
+)
+
+// ExportSnapshotRequest 将快照数据导出到对象存储的请求
+type ExportSnapshotRequest struct {
+	StorageClassName string `json:"storageClassName,omitempty"` // 临时 PVC 的存储类，为空时沿用源 PVC 的存储类
+	CreatedBy        string `json:"createdBy,omitempty"`        // 创建者用户名
+}
+
+// ExportArtifact 备份目录中的一条记录：一次快照数据导出在 restic 仓库中生成的快照
+type ExportArtifact struct {
+	ID               string    `json:"id"`
+	Cluster          string    `json:"cluster"`
+	Namespace        string    `json:"namespace"`
+	SnapshotName     string    `json:"snapshotName"`
+	PVCName          string    `json:"pvcName,omitempty"` // 快照的源 PVC
+	Mode             string    `json:"mode"`              // filesystem, block
+	Repository       string    `json:"repository"`        // restic 仓库地址，不含凭据
+	ResticSnapshotID string    `json:"resticSnapshotId"`
+	TotalBytes       int64     `json:"totalBytes"`           // 读取的数据量
+	AddedBytes       int64     `json:"addedBytes"`           // 去重、压缩后新写入仓库的数据量
+	TotalFiles       int64     `json:"totalFiles,omitempty"` // filesystem 模式下的文件数
+	DurationSeconds  float64   `json:"durationSeconds"`
+	CreatedBy        string    `json:"createdBy,omitempty"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
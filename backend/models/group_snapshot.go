@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// 快照组的创建方式
+const (
+	GroupSnapshotModeAuto     = "auto"     // 集群支持 VolumeGroupSnapshot 时使用 native，否则使用 fallback
+	GroupSnapshotModeNative   = "native"   // VolumeGroupSnapshot API，由存储在同一时间点切出所有卷
+	GroupSnapshotModeFallback = "fallback" // 执行钩子静默应用后并行创建 VolumeSnapshot
+)
+
+// 快照组的汇总状态
+const (
+	GroupSnapshotPhasePending = "pending" // 仍有成员未就绪
+	GroupSnapshotPhaseReady   = "ready"   // 所有成员已就绪
+	GroupSnapshotPhaseFailed  = "failed"  // 组或任一成员报错
+)
+
+// CreateGroupSnapshotRequest 创建快照组请求，pvcNames 和 pvcSelector 二选一
+type CreateGroupSnapshotRequest struct {
+	Name                         string         `json:"name" binding:"required"`
+	Namespace                    string         `json:"namespace" binding:"required"`
+	PVCNames                     []string       `json:"pvcNames,omitempty"`
+	PVCSelector                  string         `json:"pvcSelector,omitempty"`                  // 命名空间内的 PVC 标签选择器
+	Mode                         string         `json:"mode,omitempty"`                         // auto（默认）、native、fallback
+	VolumeGroupSnapshotClassName string         `json:"volumeGroupSnapshotClassName,omitempty"` // native 模式使用，为空时按 PVC 的 CSI 驱动选择
+	VolumeSnapshotClassName      string         `json:"volumeSnapshotClassName,omitempty"`      // fallback 模式使用，为空时使用默认快照类
+	Hooks                        *SnapshotHooks `json:"hooks,omitempty"`                        // 在挂载任一成员 PVC 的 Pod 中执行，需要 pod:exec 权限
+	CreatedBy                    string         `json:"createdBy,omitempty"`                    // 创建者用户名
+}
+
+// GroupSnapshotMember 快照组中单个 PVC 的快照
+type GroupSnapshotMember struct {
+	PVCName          string `json:"pvcName,omitempty"` // 源 PVC，native 模式下无法确定时为空
+	SnapshotName     string `json:"snapshotName"`
+	ReadyToUse       bool   `json:"readyToUse"`
+	RestoreSize      string `json:"restoreSize,omitempty"`
+	BoundContentName string `json:"boundContentName,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// GroupSnapshotInfo 快照组：一组在同一时间点创建的快照，作为整体查询、恢复和删除
+type GroupSnapshotInfo struct {
+	Name       string                `json:"name"`
+	Namespace  string                `json:"namespace"`
+	Mode       string                `json:"mode"`  // native, fallback
+	Phase      string                `json:"phase"` // pending, ready, failed
+	ReadyToUse bool                  `json:"readyToUse"`
+	Error      string                `json:"error,omitempty"`
+	ClassName  string                `json:"className,omitempty"` // native 模式为 VolumeGroupSnapshotClass，fallback 模式为 VolumeSnapshotClass
+	CreatedBy  string                `json:"createdBy,omitempty"`
+	CreatedAt  time.Time             `json:"createdAt"`
+	Members    []GroupSnapshotMember `json:"members"`
+	Hooks      []HookResult          `json:"hooks,omitempty"` // 仅在创建响应中返回
+}
+
+// RestoreGroupSnapshotRequest 从快照组恢复出一组新的 PVC
+// 新 PVC 名称优先取 pvcNames 中以源 PVC 名（或成员快照名）为键的值，否则为 <源 PVC 名>-<suffix>
+type RestoreGroupSnapshotRequest struct {
+	Suffix           string            `json:"suffix,omitempty"`
+	PVCNames         map[string]string `json:"pvcNames,omitempty"`
+	StorageClassName string            `json:"storageClassName,omitempty"` // 为空时沿用源 PVC 的存储类
+	CreatedBy        string            `json:"createdBy,omitempty"`        // 创建者用户名
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// 钩子执行阶段
+const (
+	HookPhasePre  = "pre"
+	HookPhasePost = "post"
+)
+
+// pre 钩子失败时的处理方式
+const (
+	HookOnErrorAbort    = "abort"    // 不创建快照（仍执行 post 钩子）
+	HookOnErrorContinue = "continue" // 继续创建快照
+)
+
+// SnapshotHooks 快照前后在挂载源 PVC 的 Pod 中执行的命令，用于获得应用一致的快照
+// 命令通过 Kubernetes exec API 直接执行（不经过 shell），需要 shell 语法时使用 ["/bin/sh", "-c", "..."]
+type SnapshotHooks struct {
+	Container   string   `json:"container,omitempty"`   // 执行命令的容器，为空时使用 Pod 的第一个容器
+	PodSelector string   `json:"podSelector,omitempty"` // 可选的 Pod 标签选择器，进一步筛选挂载 PVC 的 Pod
+	Pre         []string `json:"pre,omitempty"`         // 创建快照前执行，如 fsfreeze -f
+	Post        []string `json:"post,omitempty"`        // 快照切点完成后执行，pre 失败或快照创建失败时同样执行
+	Timeout     string   `json:"timeout,omitempty"`     // 每条命令的超时时间，默认 30s
+	OnError     string   `json:"onError,omitempty"`     // pre 失败时：abort（默认）或 continue
+}
+
+// HookResult 钩子在单个 Pod 中的执行结果，输出超过 4KB 时截断
+type HookResult struct {
+	Phase      string    `json:"phase"` // pre, post
+	Pod        string    `json:"pod,omitempty"`
+	Container  string    `json:"container,omitempty"`
+	Command    []string  `json:"command,omitempty"`
+	Skipped    bool      `json:"skipped,omitempty"` // 没有运行中的 Pod 挂载该 PVC，未执行
+	ExitCode   int       `json:"exitCode"`
+	Stdout     string    `json:"stdout,omitempty"`
+	Stderr     string    `json:"stderr,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// JWTKey JWT 签名密钥，HS256 使用 Secret，RS256/EdDSA 使用 PEM 格式的 PKCS8 私钥
+type JWTKey struct {
+	ID         string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	Secret     string `json:"secret,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	// Status active 用于签名，verify 只用于验证，retired 不再使用
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RotatedAt *time.Time `json:"rotatedAt,omitempty"`
+}
+
+// JWK JSON Web Key，只包含公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
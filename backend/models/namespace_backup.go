@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 命名空间备份包的格式版本
+const (
+	NamespaceBackupAPIVersion = "k8s-volume-snapshots/v1"
+	NamespaceBackupKind       = "NamespaceBackup"
+)
+
+// 备份包的存储位置
+const (
+	BackupStorageLocal = "local" // BACKUP_DIR 目录
+	BackupStorageS3    = "s3"    // BACKUP_S3_* 配置的 S3 兼容对象存储
+)
+
+// NamespaceBackupRequest 命名空间备份请求
+type NamespaceBackupRequest struct {
+	Namespace               string   `json:"namespace" binding:"required"`
+	Kinds                   []string `json:"kinds,omitempty"`                   // 要导出的资源类型，为空时使用默认列表；Namespace 和 PersistentVolumeClaim 总是导出
+	VolumeSnapshotClassName string   `json:"volumeSnapshotClassName,omitempty"` // 为空时使用默认快照类
+	Storage                 string   `json:"storage,omitempty"`                 // local（默认）或 s3
+	CreatedBy               string   `json:"createdBy,omitempty"`               // 创建者用户名
+}
+
+// BundleSnapshot 备份包中 PVC 对应的快照
+// 快照内容在备份时被设为 Retain，命名空间被删除后仍可通过 snapshotHandle 恢复
+type BundleSnapshot struct {
+	PVCName                 string `json:"pvcName"`
+	SnapshotName            string `json:"snapshotName"`
+	ContentName             string `json:"contentName"`
+	SnapshotHandle          string `json:"snapshotHandle"`
+	Driver                  string `json:"driver"`
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+	RestoreSize             string `json:"restoreSize,omitempty"`
+}
+
+// BundleResource 备份包中的单个资源清单，已去除 uid、status 等集群相关字段
+type BundleResource struct {
+	Kind     string          `json:"kind"`
+	Name     string          `json:"name"`
+	Manifest json.RawMessage `json:"manifest"`
+}
+
+// NamespaceBackupBundle 命名空间备份包：资源清单及其 PVC 快照的引用
+type NamespaceBackupBundle struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	ID         string           `json:"id"`
+	Cluster    string           `json:"cluster"`
+	Namespace  string           `json:"namespace"`
+	Kinds      []string         `json:"kinds"`
+	Snapshots  []BundleSnapshot `json:"snapshots"`
+	Resources  []BundleResource `json:"resources"`
+	CreatedBy  string           `json:"createdBy,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+}
+
+// BundleResourceRef 备份包中资源的类型和名称
+type BundleResourceRef struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// NamespaceBackupSummary 备份包摘要；列表只包含存储中可直接获得的字段，详情包含快照和资源列表（不含清单内容）
+type NamespaceBackupSummary struct {
+	ID        string              `json:"id"`
+	Cluster   string              `json:"cluster"`
+	Namespace string              `json:"namespace"`
+	Storage   string              `json:"storage"`
+	Size      int64               `json:"size"`
+	CreatedBy string              `json:"createdBy,omitempty"`
+	CreatedAt time.Time           `json:"createdAt"`
+	Kinds     []string            `json:"kinds,omitempty"`
+	Snapshots []BundleSnapshot    `json:"snapshots,omitempty"`
+	Resources []BundleResourceRef `json:"resources,omitempty"`
+}
+
+// NamespaceRestoreRequest 从备份包恢复命名空间
+type NamespaceRestoreRequest struct {
+	TargetNamespace string `json:"targetNamespace,omitempty"` // 为空时恢复到原命名空间
+	CreatedBy       string `json:"createdBy,omitempty"`
+}
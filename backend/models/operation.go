@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// 异步操作状态
+const (
+	OperationStatusRunning   = "running"
+	OperationStatusSucceeded = "succeeded"
+	OperationStatusFailed    = "failed"
+	OperationStatusSkipped   = "skipped"
+)
+
+// OperationStep 异步操作中的单个步骤
+type OperationStep struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"` // running, succeeded, failed, skipped
+	Message    string     `json:"message,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Operation 异步操作（恢复、回滚、克隆等耗时操作）的进度信息
+type Operation struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"` // restore, rollback, clone, group-restore, namespace-backup, namespace-restore, export, export-purge
+	Cluster    string          `json:"cluster,omitempty"`
+	Namespace  string          `json:"namespace"`
+	Target     string          `json:"target"` // 操作对象名称
+	Status     string          `json:"status"` // running, succeeded, failed
+	Steps      []OperationStep `json:"steps"`
+	Error      string          `json:"error,omitempty"`
+	CreatedBy  string          `json:"createdBy,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// 权限动词
+const (
+	PermRead                = "read" // 查看资源，仅用于列表过滤和只读接口
+	PermSnapshotCreate      = "snapshot:create"
+	PermSnapshotDelete      = "snapshot:delete"
+	PermSnapshotForceDelete = "snapshot:force-delete"
+	PermSnapshotRestore     = "snapshot:restore" // 恢复、回滚快照
+	PermPVCClone            = "pvc:clone"
+	PermBackupCreate        = "backup:create"  // 备份命名空间（快照和资源清单）
+	PermBackupDelete        = "backup:delete"  // 删除命名空间备份
+	PermBackupRestore       = "backup:restore" // 从备份恢复命名空间，需要目标命名空间的权限
+	PermPodExec             = "pod:exec"       // 配置在 Pod 中执行命令的快照钩子
+	PermTaskManage          = "task:manage"
+	PermClusterSwitch       = "cluster:switch"
+	PermUserManage          = "user:manage" // 管理用户、角色和绑定
+	PermAuditRead           = "audit:read"  // 查看和导出审计日志
+)
+
+// AllPermissions 所有可授予的权限动词
+var AllPermissions = []string{
+	PermRead,
+	PermSnapshotCreate,
+	PermSnapshotDelete,
+	PermSnapshotForceDelete,
+	PermSnapshotRestore,
+	PermPVCClone,
+	PermBackupCreate,
+	PermBackupDelete,
+	PermBackupRestore,
+	PermPodExec,
+	PermTaskManage,
+	PermClusterSwitch,
+	PermUserManage,
+	PermAuditRead,
+}
+
+// Role 角色，由一组权限动词组成，动词支持通配符（如 snapshot:*）
+type Role struct {
+	Name        string    `json:"name" binding:"required,min=2,max=64"`
+	Description string    `json:"description"`
+	Verbs       []string  `json:"verbs" binding:"required,min=1"`
+	BuiltIn     bool      `json:"builtIn"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// RoleBinding 将角色授予用户或用户组，并限定生效的集群和命名空间
+// Clusters、Namespaces 为通配符列表（如 prod-*、team-a-*），为空表示所有
+type RoleBinding struct {
+	ID         string    `json:"id"`
+	Role       string    `json:"role" binding:"required"`
+	Users      []string  `json:"users"`
+	Groups     []string  `json:"groups"`
+	Clusters   []string  `json:"clusters"`
+	Namespaces []string  `json:"namespaces"`
+	CreatedBy  string    `json:"createdBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// UpdateUserGroupsRequest 设置用户所属组请求
+type UpdateUserGroupsRequest struct {
+	Groups []string `json:"groups"`
+}
+
+// EffectivePermission 用户在某个范围内拥有的权限
+type EffectivePermission struct {
+	Source     string   `json:"source"` // 来源：role（用户角色）或 binding:<id>
+	Role       string   `json:"role"`
+	Verbs      []string `json:"verbs"`
+	Clusters   []string `json:"clusters"`
+	Namespaces []string `json:"namespaces"`
+}
@@ -23,28 +23,185 @@ type VolumeSnapshotInfo struct {
 
 // CreateVolumeSnapshotRequest 创建 VolumeSnapshot 请求
 type CreateVolumeSnapshotRequest struct {
-	Name                    string `json:"name" binding:"required"`
-	Namespace               string `json:"namespace" binding:"required"`
-	PVCName                 string `json:"pvcName" binding:"required"`
-	VolumeSnapshotClassName string `json:"volumeSnapshotClassName" binding:"required"`
-	CreatedBy               string `json:"createdBy,omitempty"` // 创建者用户名
+	Name                    string         `json:"name" binding:"required"`
+	Namespace               string         `json:"namespace" binding:"required"`
+	PVCName                 string         `json:"pvcName" binding:"required"`
+	VolumeSnapshotClassName string         `json:"volumeSnapshotClassName" binding:"required"`
+	Hooks                   *SnapshotHooks `json:"hooks,omitempty"`     // 应用一致性钩子，需要 pod:exec 权限
+	CreatedBy               string         `json:"createdBy,omitempty"` // 创建者用户名
+}
+
+// RestoreVolumeSnapshotRequest 从 VolumeSnapshot 恢复 PVC 请求
+type RestoreVolumeSnapshotRequest struct {
+	PVCName          string   `json:"pvcName" binding:"required"` // 新 PVC 名称
+	StorageClassName string   `json:"storageClassName,omitempty"` // 为空时沿用源 PVC 的存储类
+	AccessModes      []string `json:"accessModes,omitempty"`      // 为空时沿用源 PVC 的访问模式
+	Size             string   `json:"size,omitempty"`             // 为空时使用快照的 restoreSize
+	CreatedBy        string   `json:"createdBy,omitempty"`        // 创建者用户名
+}
+
+// ClonePVCRequest 以现有 PVC 为数据源克隆新 PVC 的请求
+type ClonePVCRequest struct {
+	PVCName          string   `json:"pvcName" binding:"required"` // 新 PVC 名称
+	StorageClassName string   `json:"storageClassName,omitempty"` // 必须与源 PVC 相同，为空时沿用
+	AccessModes      []string `json:"accessModes,omitempty"`      // 为空时沿用源 PVC 的访问模式
+	Size             string   `json:"size,omitempty"`             // 为空时与源 PVC 相同
+	CreatedBy        string   `json:"createdBy,omitempty"`        // 创建者用户名
+}
+
+// RollbackVolumeSnapshotRequest 将 PVC 原地回滚到快照的请求
+type RollbackVolumeSnapshotRequest struct {
+	Confirm string `json:"confirm" binding:"required"` // 需填写源 PVC 名称以确认操作
+}
+
+// WorkloadRef 挂载 PVC 的工作负载及其原副本数
+type WorkloadRef struct {
+	Kind     string `json:"kind"` // Deployment, StatefulSet
+	Name     string `json:"name"`
+	Replicas int32  `json:"replicas"`
 }
 
 // ScheduledSnapshot 定时快照任务
+// 目标 PVC 三选一：namespace + pvcName 指定单个 PVC；namespace + pvcSelector 选择命名空间中匹配标签的 PVC；
+// namespaceSelector（可加 pvcSelector）选择所有匹配标签的命名空间中的 PVC。选择器在每次执行时重新解析
 type ScheduledSnapshot struct {
-	ID                      string     `json:"id"`
-	Name                    string     `json:"name" binding:"required"`
-	Namespace               string     `json:"namespace" binding:"required"`
-	PVCName                 string     `json:"pvcName" binding:"required"`
-	VolumeSnapshotClassName string     `json:"volumeSnapshotClassName" binding:"required"`
-	CronExpression          string     `json:"cronExpression" binding:"required"`
-	Enabled                 bool       `json:"enabled"`
-	CreatedBy               string     `json:"createdBy,omitempty"` // 创建者用户名
-	CreatedAt               time.Time  `json:"createdAt"`
-	UpdatedAt               time.Time  `json:"updatedAt"`
-	LastExecuted            *time.Time `json:"lastExecuted,omitempty"`
-	NextExecution           *time.Time `json:"nextExecution,omitempty"`
-	TargetClusters          []string   `json:"targetClusters,omitempty"` // 目标集群列表，为空时仅在当前集群执行
+	ID                      string           `json:"id"`
+	Name                    string           `json:"name" binding:"required"`
+	Namespace               string           `json:"namespace"`
+	PVCName                 string           `json:"pvcName"`
+	PVCSelector             string           `json:"pvcSelector,omitempty"`       // PVC 标签选择器，如 app=mysql
+	NamespaceSelector       string           `json:"namespaceSelector,omitempty"` // 命名空间标签选择器，如 backup=enabled
+	VolumeSnapshotClassName string           `json:"volumeSnapshotClassName" binding:"required"`
+	CronExpression          string           `json:"cronExpression" binding:"required"`
+	Enabled                 bool             `json:"enabled"`
+	CreatedBy               string           `json:"createdBy,omitempty"` // 创建者用户名
+	CreatedAt               time.Time        `json:"createdAt"`
+	UpdatedAt               time.Time        `json:"updatedAt"`
+	LastExecuted            *time.Time       `json:"lastExecuted,omitempty"`
+	NextExecution           *time.Time       `json:"nextExecution,omitempty"`
+	TargetClusters          []string         `json:"targetClusters,omitempty"` // 目标集群列表，为空时仅在当前集群执行
+	Retention               *RetentionPolicy `json:"retention,omitempty"`      // 快照保留策略，为空时不自动清理
+	Hooks                   *SnapshotHooks   `json:"hooks,omitempty"`          // 应用一致性钩子，对每个目标 PVC 分别执行
+	ExportData              bool             `json:"exportData,omitempty"`     // 快照就绪后将数据导出到对象存储
+	LastStatus              string           `json:"lastStatus,omitempty"`     // 最近一次执行结果：success, partial, failed
+	ConsecutiveFailures     int              `json:"consecutiveFailures"`      // 连续失败次数，成功后清零
+}
+
+// 定时任务执行结果
+const (
+	RunStatusRunning = "running"
+	RunStatusSuccess = "success"
+	RunStatusPartial = "partial" // 部分集群或 PVC 失败
+	RunStatusFailed  = "failed"
+)
+
+// 快照跟踪阶段
+const (
+	TrackingPhasePending = "pending"
+	TrackingPhaseReady   = "ready"
+	TrackingPhaseFailed  = "failed"
+	TrackingPhaseTimeout = "timeout"
+)
+
+// SnapshotTrackingResult 新建快照的就绪跟踪结果
+type SnapshotTrackingResult struct {
+	Cluster                        string     `json:"cluster,omitempty"`
+	Namespace                      string     `json:"namespace"`
+	Name                           string     `json:"name"`
+	Phase                          string     `json:"phase"` // pending, ready, failed, timeout
+	Error                          string     `json:"error,omitempty"`
+	BoundVolumeSnapshotContentName string     `json:"boundVolumeSnapshotContentName,omitempty"`
+	RestoreSize                    string     `json:"restoreSize,omitempty"`
+	StartedAt                      time.Time  `json:"startedAt"`
+	FinishedAt                     *time.Time `json:"finishedAt,omitempty"`
+	DurationSeconds                float64    `json:"durationSeconds,omitempty"`
+}
+
+// CreateVolumeSnapshotResponse 创建快照的响应
+type CreateVolumeSnapshotResponse struct {
+	VolumeSnapshot *snapshotv1.VolumeSnapshot `json:"volumeSnapshot"`
+	Tracking       SnapshotTrackingResult     `json:"tracking"`
+	Hooks          []HookResult               `json:"hooks,omitempty"`
+}
+
+// ClusterRunResult 定时任务在单个集群中的执行结果
+// 使用选择器的任务在 PVCs 中记录每个 PVC 的结果，部分 PVC 失败时状态为 partial
+type ClusterRunResult struct {
+	Cluster              string         `json:"cluster"`
+	Status               string         `json:"status"` // success, partial, failed
+	Error                string         `json:"error,omitempty"`
+	ReadyToUse           bool           `json:"readyToUse"`
+	ReadyDurationSeconds float64        `json:"readyDurationSeconds,omitempty"` // 从创建到 ReadyToUse 的耗时
+	Pruned               int            `json:"pruned,omitempty"`               // 按保留策略清理的快照数
+	BoundContentName     string         `json:"boundContentName,omitempty"`
+	RestoreSize          string         `json:"restoreSize,omitempty"`
+	Hooks                []HookResult   `json:"hooks,omitempty"`
+	ExportArtifactID     string         `json:"exportArtifactId,omitempty"` // 导出到对象存储的记录 ID
+	PVCs                 []PVCRunResult `json:"pvcs,omitempty"`
+}
+
+// PVCRunResult 选择器任务中单个 PVC 的快照结果
+type PVCRunResult struct {
+	Namespace            string       `json:"namespace"`
+	PVCName              string       `json:"pvcName"`
+	SnapshotName         string       `json:"snapshotName"`
+	Status               string       `json:"status"` // success, failed
+	Error                string       `json:"error,omitempty"`
+	ReadyToUse           bool         `json:"readyToUse"`
+	ReadyDurationSeconds float64      `json:"readyDurationSeconds,omitempty"`
+	BoundContentName     string       `json:"boundContentName,omitempty"`
+	RestoreSize          string       `json:"restoreSize,omitempty"`
+	Hooks                []HookResult `json:"hooks,omitempty"`
+	ExportArtifactID     string       `json:"exportArtifactId,omitempty"`
+}
+
+// ScheduledRun 定时任务的一次执行记录
+type ScheduledRun struct {
+	ID           string             `json:"id"`
+	TaskID       string             `json:"taskId"`
+	TaskName     string             `json:"taskName"`
+	SnapshotName string             `json:"snapshotName"` // 选择器任务中为快照名称前缀，实际名称见各 PVC 的结果
+	Status       string             `json:"status"`       // running, success, partial, failed
+	StartedAt    time.Time          `json:"startedAt"`
+	FinishedAt   *time.Time         `json:"finishedAt,omitempty"`
+	Clusters     []ClusterRunResult `json:"clusters"`
+	ErrorMessage string             `json:"errorMessage,omitempty"`
+}
+
+// ScheduledRunPage 执行记录分页结果
+type ScheduledRunPage struct {
+	Items    []ScheduledRun `json:"items"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+}
+
+// RetentionPolicy 定时快照保留策略
+// 命中 keepLast 或任一 GFS（小时/天/周/月）规则的快照会被保留，超过 maxAge 的快照一律清理
+type RetentionPolicy struct {
+	KeepLast int    `json:"keepLast,omitempty"` // 保留最近 N 个快照
+	MaxAge   string `json:"maxAge,omitempty"`   // 最长保留时间，如 "72h"、"30d"
+	Hourly   int    `json:"hourly,omitempty"`   // 保留最近 N 个小时中每小时最新的快照
+	Daily    int    `json:"daily,omitempty"`    // 保留最近 N 天中每天最新的快照
+	Weekly   int    `json:"weekly,omitempty"`   // 保留最近 N 周中每周最新的快照
+	Monthly  int    `json:"monthly,omitempty"`  // 保留最近 N 个月中每月最新的快照
+}
+
+// RetentionDecision 单个快照的保留/清理决定
+type RetentionDecision struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	CreatedAt time.Time `json:"createdAt"`
+	Reasons   []string  `json:"reasons,omitempty"` // 命中的保留规则或清理原因
+}
+
+// RetentionResult 在一个集群中应用保留策略的结果
+type RetentionResult struct {
+	Cluster string              `json:"cluster"`
+	DryRun  bool                `json:"dryRun"`
+	Keep    []RetentionDecision `json:"keep"`
+	Prune   []RetentionDecision `json:"prune"`
+	Errors  []string            `json:"errors,omitempty"`
 }
 
 // ScheduledSnapshotStatus 定时快照任务状态
@@ -92,37 +249,51 @@ type PVCWithPVInfo struct {
 	VolumeAttributes *PVVolumeAttributes          `json:"volumeAttributes,omitempty"`
 }
 
-// User 用户模型
-type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username" binding:"required"`
-	Password  string    `json:"password,omitempty"` // 返回时不包含密码
-	Role      string    `json:"role" binding:"required,oneof=readonly admin"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+// 列表排序字段
+const (
+	SortByAge  = "age"
+	SortByName = "name"
+	SortBySize = "size"
+)
+
+// ListOptions 列表的通用过滤、排序和分页参数
+type ListOptions struct {
+	Namespace     string
+	LabelSelector string
+	CreatedBy     string // created-by 标签
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	SortBy        string // age, name, size
+	Order         string // asc, desc，为空时按排序字段使用默认顺序
+	Limit         int    // 0 表示不分页
+	Continue      string // 上一页返回的 continue 令牌
+	// AllowNamespace 按权限过滤命名空间，为 nil 表示不限制
+	AllowNamespace func(namespace string) bool
 }
 
-// LoginRequest 登录请求
-type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+// VolumeSnapshotListOptions 快照列表查询参数
+type VolumeSnapshotListOptions struct {
+	ListOptions
+	PVCName         string
+	Ready           *bool
+	ScheduledTaskID string // scheduled-task-id 标签
 }
 
-// RegisterRequest 注册请求
-type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=20"`
-	Password string `json:"password" binding:"required,min=6"`
-	Role     string `json:"role" binding:"required,oneof=readonly admin"`
+// PVCListOptions PVC 列表查询参数
+type PVCListOptions struct {
+	ListOptions
 }
 
-// LoginResponse 登录响应
-type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+// VolumeSnapshotList 快照列表分页结果
+type VolumeSnapshotList struct {
+	Items    []VolumeSnapshotInfo `json:"items"`
+	Total    int                  `json:"total"`              // 过滤后的总数
+	Continue string               `json:"continue,omitempty"` // 下一页令牌，为空表示没有更多数据
 }
 
-// ChangePasswordRequest 修改密码请求
-type ChangePasswordRequest struct {
-	OldPassword string `json:"oldPassword" binding:"required"`
-	NewPassword string `json:"newPassword" binding:"required,min=6"`
+// PVCList PVC 列表分页结果
+type PVCList struct {
+	Items    []PVCWithPVInfo `json:"items"`
+	Total    int             `json:"total"`
+	Continue string          `json:"continue,omitempty"`
 }
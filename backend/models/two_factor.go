@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// 两步验证策略
+const (
+	TwoFactorPolicyOptional = "optional" // 用户自行选择是否启用
+	TwoFactorPolicyWriters  = "writers"  // 拥有写权限的用户必须启用
+)
+
+// TwoFactor 用户的 TOTP 配置，单独保存，不随用户信息返回
+type TwoFactor struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	// Secret Base32 编码的 TOTP 密钥
+	Secret  string `json:"secret"`
+	Enabled bool   `json:"enabled"`
+	// RecoveryCodes 恢复码的 SHA-256 哈希，每个只能使用一次
+	RecoveryCodes []string `json:"recoveryCodes"`
+	// LastUsedStep 最近一次使用的时间步，防止同一个验证码被重放
+	LastUsedStep int64      `json:"lastUsedStep"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	EnabledAt    *time.Time `json:"enabledAt,omitempty"`
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Required 按策略当前用户必须启用两步验证
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+// TwoFactorEnrollResponse 开始绑定时返回的密钥，ProvisioningURI 可生成二维码供验证器应用扫描
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// TwoFactorCodeRequest 提交验证码（TOTP 验证码或恢复码）
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse 恢复码明文只在生成时返回一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TwoFactorChallenge 密码验证通过但需要两步验证时的登录响应
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	Challenge         string `json:"challenge"`
+	ExpiresIn         int64  `json:"expiresIn"`
+}
+
+// LoginTwoFactorRequest 登录第二步请求
+type LoginTwoFactorRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
@@ -0,0 +1,157 @@
+package models
+
+import "time"
+
+// User 用户模型
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password,omitempty"` // 返回时不包含密码
+	Role     string `json:"role" binding:"required,oneof=readonly admin none"`
+	// Groups 用户所属组，用于匹配 RoleBinding
+	Groups []string `json:"groups,omitempty"`
+	// DefaultCluster 用户的默认集群，请求未指定集群时使用
+	DefaultCluster string `json:"defaultCluster,omitempty"`
+	// AuthSource 账户来源，为空表示本地账户；外部账户的角色和组在每次登录时同步
+	AuthSource string `json:"authSource,omitempty"`
+	// Subject 外部身份提供方中的用户标识（如 OIDC sub）
+	Subject string `json:"subject,omitempty"`
+	Email   string `json:"email,omitempty"`
+	// Disabled 被禁用的用户不能登录，已签发的 token 也会被拒绝
+	Disabled   bool       `json:"disabled"`
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+	// TokenGeneration 令牌代数，修改密码或角色时递增，使之前签发的登录令牌失效
+	TokenGeneration int `json:"tokenGeneration"`
+	// MustChangePassword 下次登录后必须先修改密码（引导创建的默认管理员）
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
+	// TwoFactorEnabled 已启用 TOTP 两步验证，登录时需要输入验证码
+	TwoFactorEnabled bool `json:"twoFactorEnabled"`
+	// TokenScopes 请求通过 API token 认证时的权限范围，只存在于请求上下文中，nil 表示不限制
+	TokenScopes []string  `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// 账户来源
+const (
+	AuthSourceLocal = ""
+	AuthSourceOIDC  = "oidc"
+	AuthSourceLDAP  = "ldap"
+)
+
+// OIDCConfigInfo 前端登录页使用的 OIDC 配置
+type OIDCConfigInfo struct {
+	Enabled bool `json:"enabled"`
+}
+
+// LoginRequest 登录请求
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// 自助注册模式
+const (
+	RegistrationModeDisabled = "disabled" // 关闭自助注册，只能由管理员创建用户
+	RegistrationModeReadonly = "readonly" // 允许自助注册只读用户
+	RegistrationModeInvite   = "invite"   // 凭管理员签发的邀请码注册，角色由邀请码决定
+)
+
+// RegisterRequest 自助注册请求，角色由注册模式或邀请码决定
+type RegisterRequest struct {
+	Username    string `json:"username" binding:"required,min=3,max=20"`
+	Password    string `json:"password" binding:"required,min=6"`
+	Role        string `json:"role,omitempty" binding:"omitempty,oneof=readonly"` // 只能为空或 readonly，邀请模式下忽略
+	InviteToken string `json:"inviteToken,omitempty"`
+}
+
+// PasswordPolicy 密码策略（公开接口返回，便于前端提示）
+type PasswordPolicy struct {
+	MinLength        int  `json:"minLength"`
+	MinCharClasses   int  `json:"minCharClasses"`
+	DisallowUsername bool `json:"disallowUsername"`
+}
+
+// LoginLockout 因连续登录失败被临时锁定的用户名或来源 IP
+type LoginLockout struct {
+	Type        string    `json:"type"` // username 或 ip
+	Key         string    `json:"key"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// UnlockLoginRequest 解除来源 IP 的登录锁定
+type UnlockLoginRequest struct {
+	IP string `json:"ip" binding:"required"`
+}
+
+// RegistrationInfo 注册配置（公开接口返回）
+type RegistrationInfo struct {
+	Mode string `json:"mode"`
+}
+
+// CreateUserRequest 管理员创建用户请求
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=20"`
+	Password string `json:"password" binding:"required,min=6"`
+	Role     string `json:"role" binding:"required,oneof=readonly admin none"`
+}
+
+// UpdateUserRoleRequest 修改用户角色请求
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=readonly admin none"`
+}
+
+// ResetPasswordRequest 管理员重置密码请求
+type ResetPasswordRequest struct {
+	NewPassword string `json:"newPassword" binding:"required,min=6"`
+}
+
+// Invite 注册邀请码，只保存令牌的哈希
+type Invite struct {
+	ID        string     `json:"id"`
+	TokenHash string     `json:"tokenHash,omitempty"`
+	Role      string     `json:"role"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedBy    string     `json:"usedBy,omitempty"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+// CreateInviteRequest 创建邀请码请求
+type CreateInviteRequest struct {
+	Role           string `json:"role" binding:"required,oneof=readonly admin none"`
+	ExpiresInHours int    `json:"expiresInHours" binding:"omitempty,min=1,max=720"` // 默认 72 小时
+}
+
+// CreateInviteResponse 创建邀请码响应，令牌明文只在创建时返回一次
+type CreateInviteResponse struct {
+	Invite
+	Token string `json:"token"`
+}
+
+// LoginResponse 登录响应
+type LoginResponse struct {
+	Token string `json:"token"`
+	// RefreshToken 用于换取新的访问令牌，每次使用后轮换
+	RefreshToken string `json:"refreshToken"`
+	// ExpiresIn 访问令牌有效期（秒）
+	ExpiresIn int64 `json:"expiresIn"`
+	User      User  `json:"user"`
+}
+
+// RefreshTokenRequest 刷新访问令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// LogoutRequest 退出登录请求，携带刷新令牌时同时吊销该会话
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=6"`
+}
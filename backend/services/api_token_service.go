@@ -0,0 +1,231 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// API token 数据存储文件路径
+	APITokenDataFile = "/data/api_tokens.json"
+	// 最近使用时间的落盘间隔，避免每个请求都写文件
+	apiTokenUsageFlushInterval = time.Minute
+)
+
+// ErrInvalidAPIToken 令牌不存在、已过期或已吊销
+var ErrInvalidAPIToken = errors.New("无效或已过期的API token")
+
+// APITokenService 管理 API token 的签发、校验和吊销
+type APITokenService struct {
+	tokens    map[string]*models.APIToken // key 为令牌哈希
+	mutex     sync.RWMutex
+	dataFile  string
+	lastFlush time.Time
+}
+
+func NewAPITokenService() *APITokenService {
+	service := &APITokenService{
+		tokens:   make(map[string]*models.APIToken),
+		dataFile: APITokenDataFile,
+	}
+	service.load()
+	return service
+}
+
+// load 从文件加载 API token
+func (s *APITokenService) load() {
+	data, err := ioutil.ReadFile(s.dataFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("读取API token数据文件失败: %v\n", err)
+		}
+		return
+	}
+
+	var tokens []models.APIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		fmt.Printf("解析API token数据失败: %v\n", err)
+		return
+	}
+
+	for _, token := range tokens {
+		tokenCopy := token
+		s.tokens[token.TokenHash] = &tokenCopy
+	}
+}
+
+// saveLocked 保存 API token 到文件，调用方需持有锁
+func (s *APITokenService) saveLocked() error {
+	dir := filepath.Dir(s.dataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	tokens := make([]models.APIToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, *token)
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化API token数据失败: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.dataFile, data, 0600); err != nil {
+		return fmt.Errorf("写入API token数据文件失败: %v", err)
+	}
+
+	s.lastFlush = time.Now()
+	return nil
+}
+
+// Create 为用户签发 API token，令牌明文只在返回值中出现一次
+func (s *APITokenService) Create(user *models.User, req models.CreateAPITokenRequest, createdBy string) (*models.CreateAPITokenResponse, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, fmt.Errorf("生成API token失败: %v", err)
+	}
+	token := models.APITokenPrefix + hex.EncodeToString(tokenBytes)
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+
+	now := time.Now()
+	apiToken := &models.APIToken{
+		ID:        "tok-" + hex.EncodeToString(idBytes),
+		Name:      req.Name,
+		Username:  user.Username,
+		UserID:    user.ID,
+		TokenHash: hashAPIToken(token),
+		Hint:      token[len(token)-4:],
+		Scopes:    req.Scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens[apiToken.TokenHash] = apiToken
+	if err := s.saveLocked(); err != nil {
+		delete(s.tokens, apiToken.TokenHash)
+		return nil, err
+	}
+
+	response := &models.CreateAPITokenResponse{APIToken: *apiToken, Token: token}
+	response.TokenHash = ""
+	return response, nil
+}
+
+// Validate 校验令牌并记录最近使用时间和来源 IP
+func (s *APITokenService) Validate(token, clientIP string) (*models.APIToken, error) {
+	if !strings.HasPrefix(token, models.APITokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+	hash := hashAPIToken(token)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	apiToken, exists := s.tokens[hash]
+	if !exists {
+		return nil, ErrInvalidAPIToken
+	}
+
+	now := time.Now()
+	if now.After(apiToken.ExpiresAt) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	apiToken.LastUsedAt = &now
+	apiToken.LastUsedIP = clientIP
+	if now.Sub(s.lastFlush) > apiTokenUsageFlushInterval {
+		if err := s.saveLocked(); err != nil {
+			fmt.Printf("保存API token使用记录失败: %v\n", err)
+		}
+	}
+
+	result := *apiToken
+	result.TokenHash = ""
+	return &result, nil
+}
+
+// List 列出令牌（不包含哈希），username 为空时列出所有用户的令牌
+func (s *APITokenService) List(username string) []models.APIToken {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tokens := []models.APIToken{}
+	for _, token := range s.tokens {
+		if username != "" && token.Username != username {
+			continue
+		}
+		tokenCopy := *token
+		tokenCopy.TokenHash = ""
+		tokens = append(tokens, tokenCopy)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens
+}
+
+// Revoke 吊销令牌，username 不为空时只能吊销该用户自己的令牌
+func (s *APITokenService) Revoke(id, username string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for hash, token := range s.tokens {
+		if token.ID != id {
+			continue
+		}
+		if username != "" && token.Username != username {
+			break
+		}
+		delete(s.tokens, hash)
+		if err := s.saveLocked(); err != nil {
+			s.tokens[hash] = token
+			return err
+		}
+		return nil
+	}
+	return errors.New("API token不存在")
+}
+
+// RevokeUser 吊销用户的所有令牌，在删除用户时调用
+func (s *APITokenService) RevokeUser(username string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	removed := false
+	for hash, token := range s.tokens {
+		if token.Username == username {
+			delete(s.tokens, hash)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 审计日志默认目录
+	AuditLogDir = "/data/audit"
+	// 当前写入的日志文件名，轮换后重命名为 audit-<时间>.log
+	auditActiveFile = "audit.log"
+	// 默认的链尾锚点文件名，记录最后写入的序号和哈希
+	auditAnchorFile = "audit.anchor"
+	// 单个日志文件默认大小上限
+	DefaultAuditMaxSizeMB = 10
+	// 默认保留的轮换文件数量
+	DefaultAuditMaxFiles = 10
+	// 单条记录的最大长度（读取时）
+	auditMaxLineSize = 1 << 20
+	// 查询默认返回条数
+	defaultAuditQueryLimit = 100
+)
+
+// AuditService 追加写入的审计日志，记录之间用 SHA-256 串成哈希链，文件超过大小上限时轮换
+type AuditService struct {
+	dir        string
+	anchorPath string
+	maxSize    int64
+	maxFiles   int
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	lastSeq  uint64
+	lastHash string
+}
+
+// NewAuditService 根据环境变量创建审计服务，并从已有日志恢复序号和哈希链
+func NewAuditService() (*AuditService, error) {
+	service := &AuditService{
+		dir:      envOrDefault("AUDIT_LOG_DIR", AuditLogDir),
+		maxSize:  int64(envInt("AUDIT_MAX_SIZE_MB", DefaultAuditMaxSizeMB)) << 20,
+		maxFiles: envInt("AUDIT_MAX_FILES", DefaultAuditMaxFiles),
+	}
+	service.anchorPath = envOrDefault("AUDIT_ANCHOR_FILE", filepath.Join(service.dir, auditAnchorFile))
+	if service.maxSize <= 0 {
+		service.maxSize = DefaultAuditMaxSizeMB << 20
+	}
+
+	if err := os.MkdirAll(service.dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建审计日志目录失败: %v", err)
+	}
+
+	if err := service.restoreChain(); err != nil {
+		return nil, err
+	}
+	if err := service.openActive(); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("审计日志: %s，最后序号 %d\n", service.dir, service.lastSeq)
+	return service, nil
+}
+
+// auditAnchor 日志之外保存的链尾，用于发现重启前末尾记录被截断
+type auditAnchor struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// restoreChain 读取最后一条记录，新记录接在其后。
+// 锚点比日志更新时说明末尾记录已被删除，沿用锚点中的链尾，之后的校验会报告缺失的记录
+func (s *AuditService) restoreChain() error {
+	files, err := s.logFiles()
+	if err != nil {
+		return err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		var last *models.AuditRecord
+		err := scanAuditFile(files[i], func(record *models.AuditRecord) bool {
+			last = record
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("读取审计日志失败: %v", err)
+		}
+		if last != nil {
+			s.lastSeq = last.Seq
+			s.lastHash = last.Hash
+			break
+		}
+	}
+
+	anchor, err := s.loadAnchor()
+	if err != nil {
+		return err
+	}
+	// 写入日志后、更新锚点前退出时锚点会落后于日志，以日志为准
+	if anchor != nil && (anchor.Seq > s.lastSeq || (anchor.Seq == s.lastSeq && anchor.Hash != s.lastHash)) {
+		fmt.Printf("警告: 审计日志最后一条记录为 %d，锚点记录为 %d，日志末尾可能被删除或修改\n", s.lastSeq, anchor.Seq)
+		s.lastSeq = anchor.Seq
+		s.lastHash = anchor.Hash
+	}
+	return nil
+}
+
+// loadAnchor 读取链尾锚点，文件不存在时返回 nil
+func (s *AuditService) loadAnchor() (*auditAnchor, error) {
+	data, err := ioutil.ReadFile(s.anchorPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取审计日志锚点失败: %v", err)
+	}
+	var anchor auditAnchor
+	if err := json.Unmarshal(data, &anchor); err != nil {
+		return nil, fmt.Errorf("解析审计日志锚点失败: %v", err)
+	}
+	return &anchor, nil
+}
+
+// saveAnchorLocked 写入临时文件后重命名，避免崩溃时留下不完整的锚点
+func (s *AuditService) saveAnchorLocked() error {
+	data, err := json.Marshal(auditAnchor{Seq: s.lastSeq, Hash: s.lastHash})
+	if err != nil {
+		return err
+	}
+	tmp := s.anchorPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.anchorPath)
+}
+
+func (s *AuditService) openActive() error {
+	path := filepath.Join(s.dir, auditActiveFile)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("打开审计日志失败: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("打开审计日志失败: %v", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// Record 追加一条审计记录，填写序号和哈希链
+func (s *AuditService) Record(record models.AuditRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record.Seq = s.lastSeq + 1
+	record.Time = record.Time.UTC()
+	record.PrevHash = s.lastHash
+	record.Hash = ""
+
+	hash, err := hashAuditRecord(&record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化审计记录失败: %v", err)
+	}
+	line = append(line, '\n')
+
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotateLocked(); err != nil {
+			fmt.Printf("轮换审计日志失败: %v\n", err)
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入审计日志失败: %v", err)
+	}
+
+	s.lastSeq = record.Seq
+	s.lastHash = record.Hash
+	if err := s.saveAnchorLocked(); err != nil {
+		fmt.Printf("保存审计日志锚点失败: %v\n", err)
+	}
+	return nil
+}
+
+// rotateLocked 将当前文件重命名为带时间的轮换文件，并删除超出数量的旧文件
+func (s *AuditService) rotateLocked() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	active := filepath.Join(s.dir, auditActiveFile)
+	rotated := filepath.Join(s.dir, fmt.Sprintf("audit-%s.log", time.Now().UTC().Format("20060102T150405.000000000")))
+	if err := os.Rename(active, rotated); err != nil {
+		// 重命名失败时继续写入原文件
+		if openErr := s.openActive(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+
+	if err := s.openActive(); err != nil {
+		return err
+	}
+
+	files, err := s.logFiles()
+	if err != nil {
+		return err
+	}
+	rotatedFiles := files[:len(files)-1]
+	if s.maxFiles > 0 && len(rotatedFiles) > s.maxFiles {
+		for _, old := range rotatedFiles[:len(rotatedFiles)-s.maxFiles] {
+			if err := os.Remove(old); err != nil {
+				fmt.Printf("删除旧审计日志 %s 失败: %v\n", old, err)
+			}
+		}
+	}
+	return nil
+}
+
+// logFiles 按时间顺序返回所有日志文件，当前文件在最后
+func (s *AuditService) logFiles() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取审计日志目录失败: %v", err)
+	}
+
+	var rotated []string
+	hasActive := false
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case name == auditActiveFile:
+			hasActive = true
+		case strings.HasPrefix(name, "audit-") && strings.HasSuffix(name, ".log"):
+			rotated = append(rotated, filepath.Join(s.dir, name))
+		}
+	}
+	sort.Strings(rotated)
+
+	if hasActive {
+		rotated = append(rotated, filepath.Join(s.dir, auditActiveFile))
+	}
+	return rotated, nil
+}
+
+// Query 按条件查询审计记录，按时间倒序返回最多 limit 条
+func (s *AuditService) Query(query models.AuditQuery) (*models.AuditList, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditQueryLimit
+	}
+
+	files, err := s.logFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按文件顺序读取，只保留最新的 limit 条
+	result := &models.AuditList{Items: []models.AuditRecord{}}
+	var matched []models.AuditRecord
+	for _, file := range files {
+		err := scanAuditFile(file, func(record *models.AuditRecord) bool {
+			if !auditRecordMatches(record, query) {
+				return true
+			}
+			result.Total++
+			matched = append(matched, *record)
+			if len(matched) > limit {
+				matched = matched[1:]
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("读取审计日志失败: %v", err)
+		}
+	}
+
+	for i := len(matched) - 1; i >= 0; i-- {
+		result.Items = append(result.Items, matched[i])
+	}
+	return result, nil
+}
+
+func auditRecordMatches(record *models.AuditRecord, query models.AuditQuery) bool {
+	if query.Actor != "" && record.Actor != query.Actor {
+		return false
+	}
+	if query.Method != "" && !strings.EqualFold(record.Method, query.Method) {
+		return false
+	}
+	if query.Route != "" && !strings.Contains(record.Route, query.Route) && !strings.Contains(record.Path, query.Route) {
+		return false
+	}
+	if query.Cluster != "" && record.Cluster != query.Cluster {
+		return false
+	}
+	if query.Namespace != "" && record.Target["namespace"] != query.Namespace {
+		return false
+	}
+	if query.Outcome != "" && record.Outcome != query.Outcome {
+		return false
+	}
+	if !query.Since.IsZero() && record.Time.Before(query.Since) {
+		return false
+	}
+	if !query.Until.IsZero() && record.Time.After(query.Until) {
+		return false
+	}
+	return true
+}
+
+// Verify 重新计算所有记录的哈希并检查序号和链接是否连续
+func (s *AuditService) Verify() (*models.AuditVerifyResult, error) {
+	// 持有锁，避免校验时读到写了一半的记录
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files, err := s.logFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.AuditVerifyResult{Valid: true, Files: []string{}}
+	var prev *models.AuditRecord
+	for _, file := range files {
+		result.Files = append(result.Files, filepath.Base(file))
+		err := scanAuditFile(file, func(record *models.AuditRecord) bool {
+			expected, err := hashAuditRecord(record)
+			switch {
+			case err != nil:
+				result.Error = fmt.Sprintf("记录 %d 无法计算哈希: %v", record.Seq, err)
+			case expected != record.Hash:
+				result.Error = fmt.Sprintf("记录 %d 的哈希不匹配，内容可能被修改", record.Seq)
+			case prev != nil && record.PrevHash != prev.Hash:
+				result.Error = fmt.Sprintf("记录 %d 与上一条记录的哈希链断开", record.Seq)
+			case prev != nil && record.Seq != prev.Seq+1:
+				result.Error = fmt.Sprintf("记录 %d 之前缺少记录（上一条为 %d）", record.Seq, prev.Seq)
+			}
+			if result.Error != "" {
+				result.Valid = false
+				return false
+			}
+
+			if prev == nil {
+				result.FirstSeq = record.Seq
+			}
+			result.LastSeq = record.Seq
+			result.Records++
+			prev = record
+			return true
+		})
+		if err != nil {
+			result.Valid = false
+			result.Error = fmt.Sprintf("读取 %s 失败: %v", filepath.Base(file), err)
+		}
+		if !result.Valid {
+			break
+		}
+	}
+
+	// 最后一条记录被截断删除时，内存中的链尾与文件不一致
+	if result.Valid && result.LastSeq != s.lastSeq {
+		result.Valid = false
+		result.Error = fmt.Sprintf("日志最后一条记录为 %d，应为 %d，记录可能被删除", result.LastSeq, s.lastSeq)
+	}
+	return result, nil
+}
+
+// scanAuditFile 逐行解析日志文件，fn 返回 false 时停止
+func scanAuditFile(path string, fn func(record *models.AuditRecord) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), auditMaxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record models.AuditRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("解析记录失败: %v", err)
+		}
+		if !fn(&record) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// hashAuditRecord 计算记录（不含 Hash 字段）的 SHA-256，记录中包含上一条的哈希
+func hashAuditRecord(record *models.AuditRecord) (string, error) {
+	copied := *record
+	copied.Hash = ""
+	data, err := json.Marshal(copied)
+	if err != nil {
+		return "", fmt.Errorf("序列化审计记录失败: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-volume-snapshots/models"
+)
+
+func TestAuditVerifyDetectsTruncationAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AUDIT_LOG_DIR", dir)
+
+	service, err := NewAuditService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, actor := range []string{"alice", "bob", "carol"} {
+		if err := service.Record(models.AuditRecord{Actor: actor}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service.file.Close()
+
+	// 删除最后一条记录后重启
+	path := filepath.Join(dir, auditActiveFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines[:2], "")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err := NewAuditService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.file.Close()
+
+	result, err := restarted.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid {
+		t.Fatal("截断后的日志不应通过校验")
+	}
+
+	// 重启后写入的新记录接在锚点之后，缺失的记录仍能被发现
+	if err := restarted.Record(models.AuditRecord{Actor: "dave"}); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = restarted.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid {
+		t.Fatal("截断后写入新记录的日志不应通过校验")
+	}
+}
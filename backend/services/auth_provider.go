@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"k8s-volume-snapshots/models"
+)
+
+// ErrUnknownUser 认证后端中不存在该用户，由下一个后端继续尝试
+var ErrUnknownUser = errors.New("用户不存在")
+
+// errInvalidCredentials 统一的登录失败信息，不区分用户不存在和密码错误
+var errInvalidCredentials = errors.New("用户名或密码错误")
+
+// AuthProvider 用户名密码认证后端，UserService.Login 按顺序依次尝试
+type AuthProvider interface {
+	// Name 认证来源，与 models.User.AuthSource 对应
+	Name() string
+	// Authenticate 校验用户名和密码，用户不属于该后端时返回 ErrUnknownUser
+	Authenticate(username, password string) (*ExternalIdentity, error)
+}
+
+// ExternalIdentity 认证后端返回的用户身份
+type ExternalIdentity struct {
+	Subject  string // 后端中的唯一标识，如 OIDC sub、LDAP DN
+	Username string
+	Email    string
+	Groups   []string
+	Role     string
+}
+
+// RoleMapping 外部身份的组和邮箱到本地角色的映射
+type RoleMapping struct {
+	AdminGroups    []string
+	AdminEmails    []string
+	ReadonlyGroups []string
+	DefaultRole    string
+}
+
+// roleMappingFromEnv 读取 <prefix>_ADMIN_GROUPS、<prefix>_ADMIN_EMAILS、<prefix>_READONLY_GROUPS、<prefix>_DEFAULT_ROLE
+func roleMappingFromEnv(prefix string) (RoleMapping, error) {
+	mapping := RoleMapping{
+		AdminGroups:    splitList(os.Getenv(prefix + "_ADMIN_GROUPS")),
+		AdminEmails:    splitList(os.Getenv(prefix + "_ADMIN_EMAILS")),
+		ReadonlyGroups: splitList(os.Getenv(prefix + "_READONLY_GROUPS")),
+		DefaultRole:    envOrDefault(prefix+"_DEFAULT_ROLE", "none"),
+	}
+
+	switch mapping.DefaultRole {
+	case "admin", "readonly", "none":
+	default:
+		return mapping, fmt.Errorf("无效的 %s_DEFAULT_ROLE: %s", prefix, mapping.DefaultRole)
+	}
+	return mapping, nil
+}
+
+// Map 管理员组/邮箱 > 只读组 > 默认角色
+func (m RoleMapping) Map(email string, groups []string) string {
+	if (email != "" && containsFold(m.AdminEmails, email)) || intersects(m.AdminGroups, groups) {
+		return "admin"
+	}
+	if intersects(m.ReadonlyGroups, groups) {
+		return "readonly"
+	}
+	return m.DefaultRole
+}
+
+// localAuthProvider 本地 JSON 文件中 bcrypt 加密的账户
+type localAuthProvider struct {
+	service *UserService
+}
+
+func (p *localAuthProvider) Name() string {
+	return models.AuthSourceLocal
+}
+
+func (p *localAuthProvider) Authenticate(username, password string) (*ExternalIdentity, error) {
+	p.service.mutex.RLock()
+	defer p.service.mutex.RUnlock()
+
+	// 外部账户的本地副本没有密码，交给对应的后端认证
+	user, exists := p.service.users[username]
+	if !exists || user.AuthSource != models.AuthSourceLocal {
+		return nil, ErrUnknownUser
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, errInvalidCredentials
+	}
+
+	return &ExternalIdentity{
+		Subject:  user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Groups:   user.Groups,
+	}, nil
+}
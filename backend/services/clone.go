@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clonePVC 以现有 PVC 为 dataSource 创建新的 PVC（单集群与多集群服务共用）
+// CSI 克隆要求源和目标使用同一存储类和同一 CSI 驱动
+func clonePVC(ctx context.Context, clientSet kubernetes.Interface, namespace, name string, req models.ClonePVCRequest) (*corev1.PersistentVolumeClaim, error) {
+	sourcePVC, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if sourcePVC.DeletionTimestamp != nil {
+		return nil, fmt.Errorf("PVC %s 正在删除中", name)
+	}
+	if sourcePVC.Status.Phase != corev1.ClaimBound || sourcePVC.Spec.VolumeName == "" {
+		return nil, fmt.Errorf("PVC %s 尚未绑定，无法克隆", name)
+	}
+	if sourcePVC.Spec.StorageClassName == nil || *sourcePVC.Spec.StorageClassName == "" {
+		return nil, fmt.Errorf("PVC %s 未使用存储类，无法克隆", name)
+	}
+
+	// 存储类必须与源 PVC 一致
+	storageClassName := *sourcePVC.Spec.StorageClassName
+	if req.StorageClassName != "" && req.StorageClassName != storageClassName {
+		return nil, fmt.Errorf("克隆必须使用与源 PVC 相同的存储类 %s", storageClassName)
+	}
+
+	// CSI 驱动必须与存储类的 provisioner 一致
+	pv, err := clientSet.CoreV1().PersistentVolumes().Get(ctx, sourcePVC.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("获取 PV %s 失败: %v", sourcePVC.Spec.VolumeName, err)
+	}
+	if pv.Spec.CSI == nil {
+		return nil, fmt.Errorf("PV %s 不是 CSI 卷，无法克隆", pv.Name)
+	}
+	sc, err := clientSet.StorageV1().StorageClasses().Get(ctx, storageClassName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("获取存储类 %s 失败: %v", storageClassName, err)
+	}
+	if sc.Provisioner != pv.Spec.CSI.Driver {
+		return nil, fmt.Errorf("存储类 %s 的驱动 %s 与源卷的 CSI 驱动 %s 不一致", sc.Name, sc.Provisioner, pv.Spec.CSI.Driver)
+	}
+
+	// 容量：默认与源 PVC 相同，不能小于源 PVC
+	size := sourcePVC.Spec.Resources.Requests[corev1.ResourceStorage]
+	if capacity, ok := sourcePVC.Status.Capacity[corev1.ResourceStorage]; ok && capacity.Cmp(size) > 0 {
+		size = capacity
+	}
+	if req.Size != "" {
+		requested, err := resource.ParseQuantity(req.Size)
+		if err != nil {
+			return nil, fmt.Errorf("无效的容量 %q: %v", req.Size, err)
+		}
+		if requested.Cmp(size) < 0 {
+			return nil, fmt.Errorf("容量 %s 小于源 PVC 容量 %s", requested.String(), size.String())
+		}
+		size = requested
+	}
+
+	accessModes, err := parseAccessModes(req.AccessModes)
+	if err != nil {
+		return nil, err
+	}
+	if len(accessModes) == 0 {
+		accessModes = sourcePVC.Spec.AccessModes
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.PVCName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app": "k8s-volume-snapshots",
+			},
+			Annotations: map[string]string{
+				"k8s-volume-snapshots/cloned-from": namespace + "/" + name,
+				"k8s-volume-snapshots/created-at":  time.Now().Format(time.RFC3339),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: &storageClassName,
+			VolumeMode:       sourcePVC.Spec.VolumeMode,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: name,
+			},
+		},
+	}
+	if req.CreatedBy != "" {
+		pvc.Labels["created-by"] = CreatedByLabelValue(req.CreatedBy)
+		pvc.Annotations["k8s-volume-snapshots/created-by"] = req.CreatedBy
+	}
+
+	return clientSet.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{})
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
+	snapshotinformers "github.com/kubernetes-csi/external-snapshotter/client/v6/informers/externalversions"
+	snapshotlisters "github.com/kubernetes-csi/external-snapshotter/client/v6/listers/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// 等待 informer 首次同步的最长时间，超时后读取回退到直接访问 API
+	cacheSyncTimeout = 2 * time.Minute
+)
+
+// ClusterCache 单个集群的 informer 缓存
+// 读操作优先从 lister 读取，避免大集群中反复 List 带来的延迟和 apiserver 压力
+// 核心资源和快照资源分别判断同步状态，未安装快照 CRD 的集群仍可使用核心资源缓存
+type ClusterCache struct {
+	kubeFactory     informers.SharedInformerFactory
+	snapshotFactory snapshotinformers.SharedInformerFactory
+
+	pvcLister     corelisters.PersistentVolumeClaimLister
+	pvLister      corelisters.PersistentVolumeLister
+	nsLister      corelisters.NamespaceLister
+	scLister      storagelisters.StorageClassLister
+	vsLister      snapshotlisters.VolumeSnapshotLister
+	vscLister     snapshotlisters.VolumeSnapshotContentLister
+	vsClassLister snapshotlisters.VolumeSnapshotClassLister
+
+	// 需要推送变更事件的 informer
+	pvcInformer cache.SharedIndexInformer
+	vsInformer  cache.SharedIndexInformer
+	vscInformer cache.SharedIndexInformer
+
+	coreSyncedFuncs     []cache.InformerSynced
+	snapshotSyncedFuncs []cache.InformerSynced
+
+	coreSynced     atomic.Bool
+	snapshotSynced atomic.Bool
+	// 首次同步已完成或已超时
+	syncDone atomic.Bool
+
+	stopCh chan struct{}
+}
+
+// NewClusterCache 创建集群缓存，需调用 Start 启动
+func NewClusterCache(clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface) *ClusterCache {
+	kubeFactory := informers.NewSharedInformerFactory(clientSet, 0)
+	snapshotFactory := snapshotinformers.NewSharedInformerFactory(snapshotClientSet, 0)
+
+	c := &ClusterCache{
+		kubeFactory:     kubeFactory,
+		snapshotFactory: snapshotFactory,
+		stopCh:          make(chan struct{}),
+	}
+
+	pvcInformer := kubeFactory.Core().V1().PersistentVolumeClaims()
+	pvInformer := kubeFactory.Core().V1().PersistentVolumes()
+	nsInformer := kubeFactory.Core().V1().Namespaces()
+	scInformer := kubeFactory.Storage().V1().StorageClasses()
+	vsInformer := snapshotFactory.Snapshot().V1().VolumeSnapshots()
+	vscInformer := snapshotFactory.Snapshot().V1().VolumeSnapshotContents()
+	vsClassInformer := snapshotFactory.Snapshot().V1().VolumeSnapshotClasses()
+
+	c.pvcLister = pvcInformer.Lister()
+	c.pvLister = pvInformer.Lister()
+	c.nsLister = nsInformer.Lister()
+	c.scLister = scInformer.Lister()
+	c.vsLister = vsInformer.Lister()
+	c.vscLister = vscInformer.Lister()
+	c.vsClassLister = vsClassInformer.Lister()
+
+	c.pvcInformer = pvcInformer.Informer()
+	c.vsInformer = vsInformer.Informer()
+	c.vscInformer = vscInformer.Informer()
+
+	for _, informer := range []cache.SharedIndexInformer{pvcInformer.Informer(), pvInformer.Informer(), nsInformer.Informer(), scInformer.Informer()} {
+		_ = informer.SetTransform(stripManagedFields)
+		c.coreSyncedFuncs = append(c.coreSyncedFuncs, informer.HasSynced)
+	}
+	for _, informer := range []cache.SharedIndexInformer{vsInformer.Informer(), vscInformer.Informer(), vsClassInformer.Informer()} {
+		_ = informer.SetTransform(stripManagedFields)
+		c.snapshotSyncedFuncs = append(c.snapshotSyncedFuncs, informer.HasSynced)
+	}
+
+	return c
+}
+
+// AddEventHandlers 将 VolumeSnapshot、VolumeSnapshotContent 和 PVC 的变更转换为资源事件发布
+// 首次同步产生的 ADDED 事件会被忽略，只推送之后发生的变更
+func (c *ClusterCache) AddEventHandlers(clusterName string, publish func(models.ResourceEvent)) error {
+	handlers := map[string]cache.SharedIndexInformer{
+		models.EventKindVolumeSnapshot:        c.vsInformer,
+		models.EventKindVolumeSnapshotContent: c.vscInformer,
+		models.EventKindPVC:                   c.pvcInformer,
+	}
+
+	for kind, informer := range handlers {
+		kind := kind
+		emit := func(eventType string, obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			event, ok := newResourceEvent(clusterName, kind, eventType, obj)
+			if ok {
+				publish(event)
+			}
+		}
+
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if !isInInitialList {
+					emit(models.EventTypeAdded, obj)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldMeta, err1 := meta.Accessor(oldObj)
+				newMeta, err2 := meta.Accessor(newObj)
+				if err1 == nil && err2 == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+					return
+				}
+				emit(models.EventTypeModified, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				emit(models.EventTypeDeleted, obj)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newResourceEvent 根据 informer 对象生成资源事件
+func newResourceEvent(clusterName, kind, eventType string, obj interface{}) (models.ResourceEvent, bool) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return models.ResourceEvent{}, false
+	}
+
+	namespace := accessor.GetNamespace()
+	// VolumeSnapshotContent 是集群级资源，使用其绑定快照的命名空间以便按命名空间过滤
+	if vsc, ok := obj.(*snapshotv1.VolumeSnapshotContent); ok {
+		namespace = vsc.Spec.VolumeSnapshotRef.Namespace
+	}
+
+	return models.ResourceEvent{
+		Type:      eventType,
+		Kind:      kind,
+		Cluster:   clusterName,
+		Namespace: namespace,
+		Name:      accessor.GetName(),
+		Object:    obj,
+		Timestamp: time.Now(),
+	}, true
+}
+
+// Start 启动 informer 并在后台等待同步
+func (c *ClusterCache) Start(clusterName string) {
+	c.kubeFactory.Start(c.stopCh)
+	c.snapshotFactory.Start(c.stopCh)
+
+	go func() {
+		if cache.WaitForCacheSync(c.stopCh, c.coreSyncedFuncs...) {
+			c.coreSynced.Store(true)
+			log.Printf("Cluster %s: core resource cache synced", clusterName)
+		}
+	}()
+	go func() {
+		if cache.WaitForCacheSync(c.stopCh, c.snapshotSyncedFuncs...) {
+			c.snapshotSynced.Store(true)
+			log.Printf("Cluster %s: snapshot resource cache synced", clusterName)
+		}
+	}()
+	go func() {
+		deadline := time.After(cacheSyncTimeout)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			if c.coreSynced.Load() && c.snapshotSynced.Load() {
+				c.syncDone.Store(true)
+				return
+			}
+			select {
+			case <-deadline:
+				// 同步超时（如未安装快照 CRD）时不阻塞就绪，未同步的资源回退到直接访问 API
+				log.Printf("Cluster %s: cache sync timed out (core: %v, snapshot: %v), falling back to API for unsynced resources",
+					clusterName, c.coreSynced.Load(), c.snapshotSynced.Load())
+				c.syncDone.Store(true)
+				return
+			case <-c.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止所有 informer
+func (c *ClusterCache) Stop() {
+	if c == nil {
+		return
+	}
+	close(c.stopCh)
+}
+
+// CoreSynced 核心资源（PVC、PV、StorageClass、Namespace）缓存是否可用
+func (c *ClusterCache) CoreSynced() bool {
+	return c != nil && c.coreSynced.Load()
+}
+
+// SnapshotsSynced 快照资源（VolumeSnapshot、VolumeSnapshotContent、VolumeSnapshotClass）缓存是否可用
+func (c *ClusterCache) SnapshotsSynced() bool {
+	return c != nil && c.snapshotSynced.Load()
+}
+
+// SyncDone 首次同步是否已结束（成功或超时）
+func (c *ClusterCache) SyncDone() bool {
+	return c == nil || c.syncDone.Load()
+}
+
+// ListVolumeSnapshots 从缓存列出快照，namespace 为空时列出所有命名空间
+func (c *ClusterCache) ListVolumeSnapshots(namespace string, selector labels.Selector) ([]snapshotv1.VolumeSnapshot, error) {
+	items, err := c.vsLister.VolumeSnapshots(namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]snapshotv1.VolumeSnapshot, 0, len(items))
+	for _, item := range items {
+		result = append(result, *item.DeepCopy())
+	}
+	return result, nil
+}
+
+// GetVolumeSnapshot 从缓存获取快照
+func (c *ClusterCache) GetVolumeSnapshot(namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
+	vs, err := c.vsLister.VolumeSnapshots(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return vs.DeepCopy(), nil
+}
+
+// GetVolumeSnapshotContent 从缓存获取快照内容
+func (c *ClusterCache) GetVolumeSnapshotContent(name string) (*snapshotv1.VolumeSnapshotContent, error) {
+	vsc, err := c.vscLister.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return vsc.DeepCopy(), nil
+}
+
+// ListVolumeSnapshotClasses 从缓存列出快照类
+func (c *ClusterCache) ListVolumeSnapshotClasses() ([]snapshotv1.VolumeSnapshotClass, error) {
+	items, err := c.vsClassLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]snapshotv1.VolumeSnapshotClass, 0, len(items))
+	for _, item := range items {
+		result = append(result, *item.DeepCopy())
+	}
+	return result, nil
+}
+
+// ListPVCs 从缓存列出 PVC，namespace 为空时列出所有命名空间
+func (c *ClusterCache) ListPVCs(namespace string, selector labels.Selector) ([]corev1.PersistentVolumeClaim, error) {
+	items, err := c.pvcLister.PersistentVolumeClaims(namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]corev1.PersistentVolumeClaim, 0, len(items))
+	for _, item := range items {
+		result = append(result, *item.DeepCopy())
+	}
+	return result, nil
+}
+
+// GetPVC 从缓存获取 PVC
+func (c *ClusterCache) GetPVC(namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	pvc, err := c.pvcLister.PersistentVolumeClaims(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return pvc.DeepCopy(), nil
+}
+
+// GetPV 从缓存获取 PV（只读，不做拷贝）
+func (c *ClusterCache) GetPV(name string) (*corev1.PersistentVolume, error) {
+	return c.pvLister.Get(name)
+}
+
+// ListStorageClasses 从缓存列出存储类
+func (c *ClusterCache) ListStorageClasses() ([]storagev1.StorageClass, error) {
+	items, err := c.scLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]storagev1.StorageClass, 0, len(items))
+	for _, item := range items {
+		result = append(result, *item.DeepCopy())
+	}
+	return result, nil
+}
+
+// ListNamespaces 从缓存列出命名空间
+func (c *ClusterCache) ListNamespaces() ([]corev1.Namespace, error) {
+	items, err := c.nsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]corev1.Namespace, 0, len(items))
+	for _, item := range items {
+		result = append(result, *item.DeepCopy())
+	}
+	return result, nil
+}
+
+// stripManagedFields 去掉 managedFields 以减少缓存占用的内存
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
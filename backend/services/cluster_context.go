@@ -0,0 +1,20 @@
+package services
+
+import "context"
+
+type clusterContextKey struct{}
+
+// WithCluster 返回携带目标集群名称的 context
+// 多集群服务的所有操作都从 context 中读取目标集群，未设置时使用默认集群
+func WithCluster(ctx context.Context, clusterName string) context.Context {
+	return context.WithValue(ctx, clusterContextKey{}, clusterName)
+}
+
+// ClusterFromContext 从 context 中获取目标集群名称，未设置时返回空字符串
+func ClusterFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	clusterName, _ := ctx.Value(clusterContextKey{}).(string)
+	return clusterName
+}
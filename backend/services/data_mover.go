@@ -0,0 +1,356 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DataMoverLabel 标记数据搬运创建的临时 PVC、Secret 和 Job，值为导出 ID
+	DataMoverLabel = "k8s-volume-snapshots/export"
+	// 搬运容器中数据卷的挂载点和块设备路径
+	dataMoverMountPath  = "/data"
+	dataMoverDevicePath = "/dev/export"
+	// 读取 Job 日志的上限
+	dataMoverLogTailLines = 200
+)
+
+// 文件系统模式：备份挂载目录；仓库不存在时初始化，并发初始化失败时确认仓库已存在
+// 只输出 restic 的最终汇总行，供解析快照 ID 和数据量
+const dataMoverFilesystemScript = `set -eo pipefail
+restic cat config >/dev/null 2>&1 || restic init >/dev/null || restic cat config >/dev/null
+restic backup ` + dataMoverMountPath + ` --exclude ` + dataMoverMountPath + `/lost+found --host "$EXPORT_HOST" \
+  --tag "export=$EXPORT_ID" --tag "namespace=$EXPORT_NAMESPACE" --tag "pvc=$EXPORT_PVC" --tag "snapshot=$EXPORT_SNAPSHOT" \
+  --json | grep '"message_type":"summary"'
+`
+
+// 块设备模式：以数据流备份整个设备，restic 按内容切块去重
+const dataMoverBlockScript = `set -eo pipefail
+restic cat config >/dev/null 2>&1 || restic init >/dev/null || restic cat config >/dev/null
+restic backup --stdin --stdin-filename "$EXPORT_PVC.img" --host "$EXPORT_HOST" \
+  --tag "export=$EXPORT_ID" --tag "namespace=$EXPORT_NAMESPACE" --tag "pvc=$EXPORT_PVC" --tag "snapshot=$EXPORT_SNAPSHOT" \
+  --json < ` + dataMoverDevicePath + ` | grep '"message_type":"summary"'
+`
+
+// 删除仓库中的快照并回收不再被引用的数据
+const dataMoverForgetScript = `set -e
+restic forget "$EXPORT_RESTIC_SNAPSHOT" --prune
+`
+
+// DataMoverSpec 数据搬运 Job 的参数，由 DataMoverService 按配置生成
+type DataMoverSpec struct {
+	ID          string
+	Cluster     string
+	Namespace   string
+	Image       string
+	Repository  string            // restic 仓库地址，不含凭据
+	Credentials map[string]string // 写入临时 Secret 的环境变量（S3 凭据、仓库密码）
+	Env         map[string]string // 其他环境变量（如 RESTIC_COMPRESSION）
+	Timeout     time.Duration
+	CreatedBy   string
+}
+
+// resticSummary restic backup --json 的汇总行
+type resticSummary struct {
+	MessageType         string `json:"message_type"`
+	SnapshotID          string `json:"snapshot_id"`
+	TotalFilesProcessed int64  `json:"total_files_processed"`
+	TotalBytesProcessed int64  `json:"total_bytes_processed"`
+	DataAdded           int64  `json:"data_added"`
+	DataAddedPacked     int64  `json:"data_added_packed"` // restic 0.16 起提供压缩后的大小
+}
+
+// dataMoverName 导出使用的临时资源名称
+func dataMoverName(id string) string {
+	return "kvs-export-" + id
+}
+
+// exportSnapshotData 将快照数据导出到 restic 仓库：从快照恢复临时 PVC，运行挂载该 PVC 的搬运 Job，
+// 结束后（无论成功与否）删除 Job、Secret 和临时 PVC
+func exportSnapshotData(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, spec DataMoverSpec, snapshotName string, req models.ExportSnapshotRequest, report ProgressFunc) (*models.ExportArtifact, error) {
+	startedAt := time.Now()
+	name := dataMoverName(spec.ID)
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	vs, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(spec.Namespace).Get(ctx, snapshotName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	sourcePVC := ""
+	if vs.Spec.Source.PersistentVolumeClaimName != nil {
+		sourcePVC = *vs.Spec.Source.PersistentVolumeClaimName
+	}
+
+	step := "创建临时 PVC"
+	report(step, models.OperationStatusRunning, "从快照 "+snapshotName+" 恢复 PVC "+name)
+	pvc, err := restoreVolumeSnapshot(ctx, clientSet, snapshotClientSet, spec.Namespace, snapshotName, models.RestoreVolumeSnapshotRequest{
+		PVCName:          name,
+		StorageClassName: req.StorageClassName,
+		CreatedBy:        req.CreatedBy,
+	})
+	if err != nil {
+		report(step, models.OperationStatusFailed, err.Error())
+		return nil, fmt.Errorf("创建临时 PVC 失败: %v", err)
+	}
+	defer func() {
+		step := "删除临时 PVC"
+		err := clientSet.CoreV1().PersistentVolumeClaims(spec.Namespace).Delete(cleanupCtx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			report(step, models.OperationStatusFailed, err.Error())
+			fmt.Printf("Warning: failed to delete data mover PVC %s/%s: %v\n", spec.Namespace, name, err)
+			return
+		}
+		report(step, models.OperationStatusSucceeded, "")
+	}()
+	report(step, models.OperationStatusSucceeded, "")
+
+	// 临时 PVC 在搬运 Pod 调度后才会绑定（WaitForFirstConsumer），无需单独等待
+	mode := models.ExportModeFilesystem
+	script := dataMoverFilesystemScript
+	if pvc.Spec.VolumeMode != nil && *pvc.Spec.VolumeMode == corev1.PersistentVolumeBlock {
+		mode = models.ExportModeBlock
+		script = dataMoverBlockScript
+	}
+
+	host := spec.Cluster
+	if host == "" {
+		host = "default"
+	}
+	env := map[string]string{
+		"EXPORT_ID":        spec.ID,
+		"EXPORT_HOST":      host,
+		"EXPORT_NAMESPACE": spec.Namespace,
+		"EXPORT_PVC":       sourcePVC,
+		"EXPORT_SNAPSHOT":  snapshotName,
+	}
+	if sourcePVC == "" {
+		env["EXPORT_PVC"] = snapshotName
+	}
+
+	logs, err := runDataMoverJob(ctx, clientSet, spec, name, script, env, pvc.Name, mode == models.ExportModeBlock, report)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := parseResticSummary(logs)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := &models.ExportArtifact{
+		ID:               spec.ID,
+		Cluster:          spec.Cluster,
+		Namespace:        spec.Namespace,
+		SnapshotName:     snapshotName,
+		PVCName:          sourcePVC,
+		Mode:             mode,
+		Repository:       spec.Repository,
+		ResticSnapshotID: summary.SnapshotID,
+		TotalBytes:       summary.TotalBytesProcessed,
+		AddedBytes:       summary.DataAdded,
+		DurationSeconds:  time.Since(startedAt).Seconds(),
+		CreatedBy:        req.CreatedBy,
+		CreatedAt:        startedAt,
+	}
+	if summary.DataAddedPacked > 0 {
+		artifact.AddedBytes = summary.DataAddedPacked
+	}
+	if mode == models.ExportModeFilesystem {
+		artifact.TotalFiles = summary.TotalFilesProcessed
+	}
+	return artifact, nil
+}
+
+// forgetExportedData 在 restic 仓库中删除导出的快照并回收空间
+func forgetExportedData(ctx context.Context, clientSet kubernetes.Interface, spec DataMoverSpec, resticSnapshotID string, report ProgressFunc) error {
+	env := map[string]string{"EXPORT_RESTIC_SNAPSHOT": resticSnapshotID}
+	_, err := runDataMoverJob(ctx, clientSet, spec, dataMoverName(spec.ID)+"-forget", dataMoverForgetScript, env, "", false, report)
+	return err
+}
+
+// runDataMoverJob 创建凭据 Secret 和搬运 Job，等待 Job 结束后返回其日志并删除 Job 和 Secret
+// claimName 不为空时将 PVC 挂载到 /data（block 为 true 时映射为块设备 /dev/export）
+func runDataMoverJob(ctx context.Context, clientSet kubernetes.Interface, spec DataMoverSpec, name, script string, env map[string]string, claimName string, block bool, report ProgressFunc) (string, error) {
+	cleanupCtx := context.WithoutCancel(ctx)
+	labels := map[string]string{
+		DataMoverLabel: spec.ID,
+		"app":          "k8s-volume-snapshots",
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: spec.Namespace,
+			Labels:    labels,
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: map[string]string{"RESTIC_REPOSITORY": spec.Repository},
+	}
+	for key, value := range spec.Credentials {
+		secret.StringData[key] = value
+	}
+	if _, err := clientSet.CoreV1().Secrets(spec.Namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("创建搬运凭据失败: %v", err)
+	}
+	defer func() {
+		err := clientSet.CoreV1().Secrets(spec.Namespace).Delete(cleanupCtx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			fmt.Printf("Warning: failed to delete data mover secret %s/%s: %v\n", spec.Namespace, name, err)
+		}
+	}()
+
+	job := buildDataMoverJob(spec, name, script, env, claimName, block, labels)
+	step := "运行搬运 Job " + name
+	report(step, models.OperationStatusRunning, "镜像 "+spec.Image)
+	if _, err := clientSet.BatchV1().Jobs(spec.Namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		report(step, models.OperationStatusFailed, err.Error())
+		return "", fmt.Errorf("创建搬运 Job 失败: %v", err)
+	}
+	defer func() {
+		// 同时删除 Job 创建的 Pod
+		propagation := metav1.DeletePropagationBackground
+		err := clientSet.BatchV1().Jobs(spec.Namespace).Delete(cleanupCtx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !apierrors.IsNotFound(err) {
+			fmt.Printf("Warning: failed to delete data mover job %s/%s: %v\n", spec.Namespace, name, err)
+		}
+	}()
+
+	// Job 自身的 activeDeadlineSeconds 等于超时时间，这里多等一分钟以读取失败状态
+	var jobErr error
+	err := pollUntil(ctx, spec.Timeout+time.Minute, func() (bool, error) {
+		current, err := clientSet.BatchV1().Jobs(spec.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if current.Status.Succeeded > 0 {
+			return true, nil
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				jobErr = fmt.Errorf("搬运 Job 失败: %s %s", condition.Reason, condition.Message)
+				return true, nil
+			}
+		}
+		return false, nil
+	}, fmt.Sprintf("等待搬运 Job %s 完成超时", name))
+
+	logs := dataMoverJobLogs(cleanupCtx, clientSet, spec.Namespace, name)
+	if err == nil {
+		err = jobErr
+	}
+	if err != nil {
+		if logs != "" {
+			err = fmt.Errorf("%v\n%s", err, truncateHookOutput(logs))
+		}
+		report(step, models.OperationStatusFailed, err.Error())
+		return "", err
+	}
+	report(step, models.OperationStatusSucceeded, "")
+	return logs, nil
+}
+
+// buildDataMoverJob 生成不重试的搬运 Job，凭据通过 Secret 注入
+func buildDataMoverJob(spec DataMoverSpec, name, script string, env map[string]string, claimName string, block bool, labels map[string]string) *batchv1.Job {
+	backoffLimit := int32(0)
+	deadline := int64(spec.Timeout.Seconds())
+
+	container := corev1.Container{
+		Name:    "mover",
+		Image:   spec.Image,
+		Command: []string{"/bin/sh", "-c", script},
+		EnvFrom: []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+		}},
+	}
+	for _, values := range []map[string]string{spec.Env, env} {
+		for key, value := range values {
+			container.Env = append(container.Env, corev1.EnvVar{Name: key, Value: value})
+		}
+	}
+	// 按名称排序，使生成的 Job 定义稳定
+	sort.Slice(container.Env, func(i, j int) bool { return container.Env[i].Name < container.Env[j].Name })
+
+	podSpec := corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers:    []corev1.Container{container},
+	}
+	if claimName != "" {
+		// 崩溃一致的快照可能需要重放文件系统日志，因此不以只读方式挂载；临时 PVC 用完即删
+		podSpec.Volumes = []corev1.Volume{{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			},
+		}}
+		if block {
+			podSpec.Containers[0].VolumeDevices = []corev1.VolumeDevice{{Name: "data", DevicePath: dataMoverDevicePath}}
+		} else {
+			podSpec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: dataMoverMountPath}}
+		}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: spec.Namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				"k8s-volume-snapshots/created-by": spec.CreatedBy,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       podSpec,
+			},
+		},
+	}
+}
+
+// dataMoverJobLogs 读取 Job 的 Pod 日志，读取失败时返回空字符串
+func dataMoverJobLogs(ctx context.Context, clientSet kubernetes.Interface, namespace, jobName string) string {
+	pods, err := clientSet.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + jobName})
+	if err != nil || len(pods.Items) == 0 {
+		return ""
+	}
+
+	tailLines := int64(dataMoverLogTailLines)
+	raw, err := clientSet.CoreV1().Pods(namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{TailLines: &tailLines}).Do(ctx).Raw()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// parseResticSummary 从 Job 日志中找到 restic 的汇总行
+func parseResticSummary(logs string) (*resticSummary, error) {
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var summary resticSummary
+		if err := json.Unmarshal([]byte(line), &summary); err == nil && summary.MessageType == "summary" && summary.SnapshotID != "" {
+			return &summary, nil
+		}
+	}
+	return nil, fmt.Errorf("搬运 Job 已完成，但日志中没有 restic 汇总信息: %s", truncateHookOutput(logs))
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// 默认的搬运镜像，restic 仓库格式 v2 支持压缩，按内容定义的分块去重
+	DefaultDataMoverImage = "restic/restic:0.16.4"
+	// 单次导出的默认超时时间
+	DefaultDataMoverTimeout = 6 * time.Hour
+	// 备份目录在对象存储中的 key 前缀，完整 key 为 exports/<集群>/<命名空间>/<导出 ID>.json
+	exportCatalogPrefix = "exports/"
+	exportCatalogSuffix = ".json"
+	// restic 仓库在对象存储中的 key 前缀，每个命名空间一个仓库：restic/<集群>/<命名空间>
+	resticRepositoryPrefix = "restic/"
+)
+
+// 搬运 Job 获取对象存储凭据的方式（DATA_MOVER_CREDENTIALS）
+const (
+	// DataMoverCredentialsSTS 通过 STS AssumeRole 签发只能访问本命名空间仓库的临时凭据
+	DataMoverCredentialsSTS = "sts"
+	// DataMoverCredentialsShared 直接注入 BACKUP_S3_* 长期密钥，命名空间管理员可以读取，
+	// 因此导出只允许拥有全局权限的管理员发起
+	DataMoverCredentialsShared = "shared"
+)
+
+var (
+	// ErrDataMoverDisabled 未配置对象存储或仓库密码
+	ErrDataMoverDisabled = errors.New("快照数据导出未启用，需要设置 BACKUP_S3_BUCKET 和 DATA_MOVER_PASSWORD")
+	// ErrExportNotFound 备份目录中没有该导出记录
+	ErrExportNotFound = errors.New("导出记录不存在")
+)
+
+// DataMoverService 快照数据导出：通过搬运 Job 把快照数据写入 S3 兼容对象存储中的 restic 仓库，
+// 并在同一存储桶中维护备份目录，集群丢失后仍可据此找到数据
+// 搬运 Job 运行在租户命名空间中，能读取该命名空间 Secret 的用户都能看到注入的凭据，
+// 因此每个命名空间使用独立的仓库和派生的仓库密码，对象存储凭据只覆盖该仓库
+type DataMoverService struct {
+	k8sService  MultiClusterK8sServiceInterface
+	catalog     *s3ObjectStore // 为 nil 时未启用
+	sts         *stsClient     // 为 nil 时使用共享的长期密钥
+	image       string
+	password    string // 主密码，只用于派生各命名空间仓库的密码，不会写入集群
+	compression string
+	timeout     time.Duration
+}
+
+func NewDataMoverService(k8sService MultiClusterK8sServiceInterface) (*DataMoverService, error) {
+	service := &DataMoverService{
+		k8sService:  k8sService,
+		image:       envOrDefault("DATA_MOVER_IMAGE", DefaultDataMoverImage),
+		password:    os.Getenv("DATA_MOVER_PASSWORD"),
+		compression: envOrDefault("DATA_MOVER_COMPRESSION", "auto"),
+		timeout:     envDuration("DATA_MOVER_TIMEOUT", DefaultDataMoverTimeout),
+	}
+	switch service.compression {
+	case "auto", "max", "off":
+	default:
+		return nil, fmt.Errorf("无效的 DATA_MOVER_COMPRESSION %q，可选 auto、max、off", service.compression)
+	}
+
+	config := BackupS3ConfigFromEnv()
+	if config == nil || service.password == "" {
+		return service, nil
+	}
+	store, err := newS3ObjectStore(*config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode := envOrDefault("DATA_MOVER_CREDENTIALS", DataMoverCredentialsSTS); mode {
+	case DataMoverCredentialsSTS:
+		service.sts, err = newSTSClient(store.config, os.Getenv("DATA_MOVER_STS_ENDPOINT"), os.Getenv("DATA_MOVER_ROLE_ARN"))
+		if err != nil {
+			return nil, err
+		}
+	case DataMoverCredentialsShared:
+		fmt.Printf("警告: DATA_MOVER_CREDENTIALS=shared，搬运 Job 使用共享的对象存储密钥，只有全局管理员可以导出快照数据\n")
+	default:
+		return nil, fmt.Errorf("无效的 DATA_MOVER_CREDENTIALS %q，可选 sts、shared", mode)
+	}
+	service.catalog = store
+	return service, nil
+}
+
+// Enabled 是否已配置对象存储和仓库密码
+func (s *DataMoverService) Enabled() bool {
+	return s.catalog != nil
+}
+
+// SharedCredentials 搬运 Job 是否使用共享的长期密钥；为 true 时导出和删除数据需要全局权限
+func (s *DataMoverService) SharedCredentials() bool {
+	return s.Enabled() && s.sts == nil
+}
+
+// spec 生成当前集群中某次导出的搬运参数，凭据只能访问该命名空间的仓库
+func (s *DataMoverService) spec(ctx context.Context, namespace, id, createdBy string) (DataMoverSpec, error) {
+	cluster := s.k8sService.GetCurrentCluster(ctx)
+	credentials := map[string]string{
+		"AWS_ACCESS_KEY_ID":     s.catalog.config.AccessKey,
+		"AWS_SECRET_ACCESS_KEY": s.catalog.config.SecretKey,
+		"AWS_DEFAULT_REGION":    s.catalog.config.Region,
+		"RESTIC_PASSWORD":       s.repositoryPassword(cluster, namespace),
+	}
+	if s.sts != nil {
+		policy := s3PrefixPolicy(s.catalog.config.Bucket, s.catalog.config.Prefix+s.repositoryKey(cluster, namespace))
+		// 多留出等待调度和清理的时间，凭据不会在 Job 超时前失效
+		temporary, err := s.sts.AssumeRole(ctx, dataMoverName(id), policy, s.timeout+10*time.Minute)
+		if err != nil {
+			return DataMoverSpec{}, fmt.Errorf("获取搬运临时凭据失败: %v", err)
+		}
+		credentials["AWS_ACCESS_KEY_ID"] = temporary.AccessKeyID
+		credentials["AWS_SECRET_ACCESS_KEY"] = temporary.SecretAccessKey
+		credentials["AWS_SESSION_TOKEN"] = temporary.SessionToken
+	}
+
+	return DataMoverSpec{
+		ID:          id,
+		Cluster:     cluster,
+		Namespace:   namespace,
+		Image:       s.image,
+		Repository:  s.repositoryFor(cluster, namespace),
+		Credentials: credentials,
+		Env:         map[string]string{"RESTIC_COMPRESSION": s.compression},
+		Timeout:     s.timeout,
+		CreatedBy:   createdBy,
+	}, nil
+}
+
+// repositoryKey 仓库在存储桶前缀下的 key，每个命名空间一个 restic 仓库，同一命名空间的快照之间共享去重
+func (s *DataMoverService) repositoryKey(cluster, namespace string) string {
+	if cluster == "" {
+		cluster = "default"
+	}
+	return resticRepositoryPrefix + cluster + "/" + namespace
+}
+
+func (s *DataMoverService) repositoryFor(cluster, namespace string) string {
+	base := s.catalog.base
+	return fmt.Sprintf("s3:%s://%s%s/%s/%s%s", base.Scheme, base.Host, strings.TrimRight(base.Path, "/"),
+		s.catalog.config.Bucket, s.catalog.config.Prefix, s.repositoryKey(cluster, namespace))
+}
+
+// repositoryPassword 由主密码派生的仓库密码，知道一个命名空间的密码无法推出其他命名空间的密码
+func (s *DataMoverService) repositoryPassword(cluster, namespace string) string {
+	mac := hmac.New(sha256.New, []byte(s.password))
+	mac.Write([]byte(s.repositoryKey(cluster, namespace)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Export 将快照数据导出到对象存储并记录到备份目录，ctx 需携带目标集群
+func (s *DataMoverService) Export(ctx context.Context, namespace, snapshotName string, req models.ExportSnapshotRequest, report ProgressFunc) (*models.ExportArtifact, error) {
+	if !s.Enabled() {
+		return nil, ErrDataMoverDisabled
+	}
+
+	bytes := make([]byte, 4)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(bytes)
+
+	spec, err := s.spec(ctx, namespace, id, req.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	artifact, err := s.k8sService.ExportSnapshotData(ctx, spec, snapshotName, req, report)
+	if err != nil {
+		return nil, err
+	}
+
+	step := "记录到备份目录"
+	report(step, models.OperationStatusRunning, "")
+	data, err := json.MarshalIndent(artifact, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.catalog.Put(ctx, s.catalogKey(ctx, namespace, id), data); err != nil {
+		// 数据已写入仓库，返回记录以便手动补登
+		report(step, models.OperationStatusFailed, err.Error())
+		return artifact, fmt.Errorf("记录备份目录失败（restic 快照 %s 已写入仓库）: %v", artifact.ResticSnapshotID, err)
+	}
+	report(step, models.OperationStatusSucceeded, fmt.Sprintf("导出 %s，restic 快照 %s", id, artifact.ResticSnapshotID))
+	return artifact, nil
+}
+
+// List 列出当前集群备份目录中的导出记录，filter 为 nil 时不按命名空间过滤
+func (s *DataMoverService) List(ctx context.Context, filter func(namespace string) bool) ([]models.ExportArtifact, error) {
+	if !s.Enabled() {
+		return nil, ErrDataMoverDisabled
+	}
+
+	prefix := exportCatalogPrefix + s.k8sService.GetCurrentCluster(ctx) + "/"
+	objects, err := s.catalog.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := []models.ExportArtifact{}
+	for _, object := range objects {
+		parts := strings.Split(strings.TrimPrefix(object.Key, prefix), "/")
+		if len(parts) != 2 || !strings.HasSuffix(parts[1], exportCatalogSuffix) {
+			continue
+		}
+		if filter != nil && !filter(parts[0]) {
+			continue
+		}
+		artifact, err := s.load(ctx, object.Key)
+		if err != nil {
+			fmt.Printf("Warning: skipping export catalog entry %s: %v\n", object.Key, err)
+			continue
+		}
+		artifacts = append(artifacts, *artifact)
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].CreatedAt.After(artifacts[j].CreatedAt)
+	})
+	return artifacts, nil
+}
+
+// Get 获取当前集群中的导出记录
+func (s *DataMoverService) Get(ctx context.Context, namespace, id string) (*models.ExportArtifact, error) {
+	if !s.Enabled() {
+		return nil, ErrDataMoverDisabled
+	}
+	if len(validation.IsDNS1123Label(namespace)) > 0 || len(validation.IsDNS1123Label(id)) > 0 {
+		return nil, ErrExportNotFound
+	}
+	return s.load(ctx, s.catalogKey(ctx, namespace, id))
+}
+
+// Delete 删除导出记录；purge 为 true 时先通过搬运 Job 从 restic 仓库中删除数据
+func (s *DataMoverService) Delete(ctx context.Context, namespace, id string, purge bool, report ProgressFunc) error {
+	artifact, err := s.Get(ctx, namespace, id)
+	if err != nil {
+		return err
+	}
+
+	if purge {
+		spec, err := s.spec(ctx, namespace, id, "")
+		if err != nil {
+			return err
+		}
+		if err := s.k8sService.ForgetExportedData(ctx, spec, artifact.ResticSnapshotID, report); err != nil {
+			return err
+		}
+	}
+
+	return s.catalog.Delete(ctx, s.catalogKey(ctx, namespace, id))
+}
+
+func (s *DataMoverService) catalogKey(ctx context.Context, namespace, id string) string {
+	return exportCatalogPrefix + s.k8sService.GetCurrentCluster(ctx) + "/" + namespace + "/" + id + exportCatalogSuffix
+}
+
+func (s *DataMoverService) load(ctx context.Context, key string) (*models.ExportArtifact, error) {
+	data, err := s.catalog.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+
+	var artifact models.ExportArtifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("解析导出记录失败: %v", err)
+	}
+	return &artifact, nil
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+// fakeDataMoverCluster 记录搬运参数而不运行 Job，其他方法不会被数据导出调用
+type fakeDataMoverCluster struct {
+	MultiClusterK8sServiceInterface
+	cluster string
+	specs   []DataMoverSpec
+	forgets []string
+}
+
+func (f *fakeDataMoverCluster) GetCurrentCluster(ctx context.Context) string {
+	return f.cluster
+}
+
+func (f *fakeDataMoverCluster) ExportSnapshotData(ctx context.Context, spec DataMoverSpec, snapshotName string, req models.ExportSnapshotRequest, report ProgressFunc) (*models.ExportArtifact, error) {
+	f.specs = append(f.specs, spec)
+	return &models.ExportArtifact{
+		ID:               spec.ID,
+		Cluster:          spec.Cluster,
+		Namespace:        spec.Namespace,
+		SnapshotName:     snapshotName,
+		Mode:             models.ExportModeFilesystem,
+		Repository:       spec.Repository,
+		ResticSnapshotID: "restic-" + spec.ID,
+		CreatedBy:        req.CreatedBy,
+		CreatedAt:        time.Now(),
+	}, nil
+}
+
+func (f *fakeDataMoverCluster) ForgetExportedData(ctx context.Context, spec DataMoverSpec, resticSnapshotID string, report ProgressFunc) error {
+	f.specs = append(f.specs, spec)
+	f.forgets = append(f.forgets, resticSnapshotID)
+	return nil
+}
+
+// fakeS3Server 内存中的 path-style S3（PUT、GET、DELETE、ListObjectsV2）和 STS AssumeRole
+type fakeS3Server struct {
+	server      *httptest.Server
+	mutex       sync.Mutex
+	objects     map[string][]byte
+	assumeRoles []url.Values
+}
+
+func newFakeS3Server(t *testing.T) *fakeS3Server {
+	fake := &fakeS3Server{objects: make(map[string][]byte)}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (f *fakeS3Server) handle(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=static-key/") {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodPost && r.URL.Path == "/" {
+		r.ParseForm()
+		if r.PostForm.Get("Action") != "AssumeRole" {
+			http.Error(w, "unsupported action", http.StatusBadRequest)
+			return
+		}
+		f.assumeRoles = append(f.assumeRoles, r.PostForm)
+		fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>temp-key-%d</AccessKeyId><SecretAccessKey>temp-secret</SecretAccessKey>
+<SessionToken>session-token</SessionToken><Expiration>%s</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`, len(f.assumeRoles), time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		prefix := strings.TrimSuffix(key, "/") + "/" + r.URL.Query().Get("prefix")
+		var keys []string
+		for existing := range f.objects {
+			if strings.HasPrefix(existing, prefix) {
+				keys = append(keys, existing)
+			}
+		}
+		sort.Strings(keys)
+		fmt.Fprint(w, "<ListBucketResult>")
+		for _, existing := range keys {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size></Contents>",
+				strings.SplitN(existing, "/", 2)[1], len(f.objects[existing]))
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet:
+		data, exists := f.objects[key]
+		if !exists {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported", http.StatusBadRequest)
+	}
+}
+
+func newTestDataMover(t *testing.T, endpoint, credentials string) (*DataMoverService, *fakeDataMoverCluster) {
+	t.Setenv("BACKUP_S3_BUCKET", "snapshots")
+	t.Setenv("BACKUP_S3_ENDPOINT", endpoint)
+	t.Setenv("BACKUP_S3_ACCESS_KEY", "static-key")
+	t.Setenv("BACKUP_S3_SECRET_KEY", "static-secret")
+	t.Setenv("BACKUP_S3_PREFIX", "kvs")
+	t.Setenv("DATA_MOVER_PASSWORD", "master-password")
+	t.Setenv("DATA_MOVER_CREDENTIALS", credentials)
+
+	cluster := &fakeDataMoverCluster{cluster: "prod"}
+	service, err := NewDataMoverService(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, cluster
+}
+
+func noopReport(step, status, message string) {}
+
+func TestDataMoverScopesCredentialsToNamespace(t *testing.T) {
+	fake := newFakeS3Server(t)
+	service, cluster := newTestDataMover(t, fake.server.URL, DataMoverCredentialsSTS)
+	if service.SharedCredentials() {
+		t.Fatal("sts 模式不应使用共享凭据")
+	}
+
+	ctx := context.Background()
+	shop, err := service.Export(ctx, "shop", "mysql-data", models.ExportSnapshotRequest{CreatedBy: "alice"}, noopReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Export(ctx, "billing", "pg-data", models.ExportSnapshotRequest{CreatedBy: "alice"}, noopReport); err != nil {
+		t.Fatal(err)
+	}
+
+	shopSpec, billingSpec := cluster.specs[0], cluster.specs[1]
+	if !strings.HasSuffix(shopSpec.Repository, "/snapshots/kvs/restic/prod/shop") || shop.Repository != shopSpec.Repository {
+		t.Fatalf("unexpected repository: %s", shopSpec.Repository)
+	}
+	if shopSpec.Repository == billingSpec.Repository {
+		t.Fatal("不同命名空间应使用不同的仓库")
+	}
+
+	// 注入 Job 的只有临时凭据和派生密码
+	for key, value := range shopSpec.Credentials {
+		if value == "static-key" || value == "static-secret" || value == "master-password" {
+			t.Fatalf("credential %s leaks the long-term secret", key)
+		}
+	}
+	if shopSpec.Credentials["AWS_ACCESS_KEY_ID"] != "temp-key-1" || shopSpec.Credentials["AWS_SESSION_TOKEN"] != "session-token" {
+		t.Fatalf("unexpected credentials: %v", shopSpec.Credentials)
+	}
+	if shopSpec.Credentials["RESTIC_PASSWORD"] == billingSpec.Credentials["RESTIC_PASSWORD"] {
+		t.Fatal("不同命名空间应使用不同的仓库密码")
+	}
+
+	policy := fake.assumeRoles[0].Get("Policy")
+	if !strings.Contains(policy, `"arn:aws:s3:::snapshots/kvs/restic/prod/shop/*"`) || strings.Contains(policy, "billing") {
+		t.Fatalf("会话策略应只覆盖本命名空间的仓库: %s", policy)
+	}
+	if fake.assumeRoles[0].Get("DurationSeconds") != fmt.Sprint(int((DefaultDataMoverTimeout + 10*time.Minute).Seconds())) {
+		t.Fatalf("临时凭据应覆盖整个 Job 超时时间: %s", fake.assumeRoles[0].Get("DurationSeconds"))
+	}
+
+	// 备份目录使用服务自己的长期密钥读写
+	exports, err := service.List(ctx, func(namespace string) bool { return namespace == "shop" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exports) != 1 || exports[0].ID != shop.ID {
+		t.Fatalf("unexpected exports: %+v", exports)
+	}
+
+	if err := service.Delete(ctx, "shop", shop.ID, true, noopReport); err != nil {
+		t.Fatal(err)
+	}
+	purgeSpec := cluster.specs[2]
+	if len(cluster.forgets) != 1 || cluster.forgets[0] != shop.ResticSnapshotID || purgeSpec.Repository != shopSpec.Repository ||
+		purgeSpec.Credentials["RESTIC_PASSWORD"] != shopSpec.Credentials["RESTIC_PASSWORD"] {
+		t.Fatalf("purge 应使用同一仓库和密码: %+v", purgeSpec)
+	}
+	if _, err := service.Get(ctx, "shop", shop.ID); !errors.Is(err, ErrExportNotFound) {
+		t.Fatalf("expected export to be deleted, got %v", err)
+	}
+}
+
+func TestDataMoverSharedCredentials(t *testing.T) {
+	fake := newFakeS3Server(t)
+	service, cluster := newTestDataMover(t, fake.server.URL, DataMoverCredentialsShared)
+	if !service.SharedCredentials() {
+		t.Fatal("shared 模式应报告共享凭据")
+	}
+
+	if _, err := service.Export(context.Background(), "shop", "mysql-data", models.ExportSnapshotRequest{}, noopReport); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.assumeRoles) != 0 || cluster.specs[0].Credentials["AWS_ACCESS_KEY_ID"] != "static-key" {
+		t.Fatalf("shared 模式应直接使用长期密钥: %v", cluster.specs[0].Credentials)
+	}
+	if cluster.specs[0].Credentials["RESTIC_PASSWORD"] == "master-password" {
+		t.Fatal("仓库密码应由主密码派生")
+	}
+}
+
+func TestDataMoverRejectsUnknownCredentialsMode(t *testing.T) {
+	t.Setenv("BACKUP_S3_BUCKET", "snapshots")
+	t.Setenv("BACKUP_S3_ACCESS_KEY", "static-key")
+	t.Setenv("BACKUP_S3_SECRET_KEY", "static-secret")
+	t.Setenv("DATA_MOVER_PASSWORD", "master-password")
+	t.Setenv("DATA_MOVER_CREDENTIALS", "static")
+	if _, err := NewDataMoverService(&fakeDataMoverCluster{}); err == nil {
+		t.Fatal("expected error for unknown DATA_MOVER_CREDENTIALS")
+	}
+}
+
+// TestDataMoverMinIO 使用真实的 MinIO 验证临时凭据只能访问本命名空间的仓库
+// 需要设置 MINIO_ENDPOINT、MINIO_ACCESS_KEY、MINIO_SECRET_KEY 和已存在的 MINIO_BUCKET，否则跳过
+func TestDataMoverMinIO(t *testing.T) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_ENDPOINT 未设置")
+	}
+	t.Setenv("BACKUP_S3_BUCKET", os.Getenv("MINIO_BUCKET"))
+	t.Setenv("BACKUP_S3_ENDPOINT", endpoint)
+	t.Setenv("BACKUP_S3_ACCESS_KEY", os.Getenv("MINIO_ACCESS_KEY"))
+	t.Setenv("BACKUP_S3_SECRET_KEY", os.Getenv("MINIO_SECRET_KEY"))
+	t.Setenv("BACKUP_S3_PREFIX", fmt.Sprintf("kvs-test-%d", time.Now().UnixNano()))
+	t.Setenv("DATA_MOVER_PASSWORD", "master-password")
+	t.Setenv("DATA_MOVER_CREDENTIALS", DataMoverCredentialsSTS)
+
+	cluster := &fakeDataMoverCluster{cluster: "prod"}
+	service, err := NewDataMoverService(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	artifact, err := service.Export(ctx, "shop", "mysql-data", models.ExportSnapshotRequest{}, noopReport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Delete(ctx, "shop", artifact.ID, false, nil)
+
+	// 以搬运 Job 拿到的临时凭据访问对象存储
+	spec := cluster.specs[0]
+	config := service.catalog.config
+	config.AccessKey = spec.Credentials["AWS_ACCESS_KEY_ID"]
+	config.SecretKey = spec.Credentials["AWS_SECRET_ACCESS_KEY"]
+	config.SessionToken = spec.Credentials["AWS_SESSION_TOKEN"]
+	tenant, err := newS3ObjectStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	own := service.repositoryKey("prod", "shop") + "/config"
+	if err := tenant.Put(ctx, own, []byte("repository")); err != nil {
+		t.Fatalf("临时凭据应能写入本命名空间的仓库: %v", err)
+	}
+	defer service.catalog.Delete(ctx, own)
+	if _, err := tenant.List(ctx, service.repositoryKey("prod", "shop")+"/"); err != nil {
+		t.Fatalf("临时凭据应能列出本命名空间的仓库: %v", err)
+	}
+
+	if err := tenant.Put(ctx, service.repositoryKey("prod", "billing")+"/config", []byte("x")); err == nil {
+		t.Fatal("临时凭据不应能写入其他命名空间的仓库")
+	}
+	if _, err := tenant.Get(ctx, service.catalogKey(ctx, "shop", artifact.ID)); err == nil {
+		t.Fatal("临时凭据不应能读取备份目录")
+	}
+	if _, err := tenant.List(ctx, exportCatalogPrefix); err == nil {
+		t.Fatal("临时凭据不应能列出备份目录")
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"strings"
+	"sync"
+
+	"k8s-volume-snapshots/models"
+)
+
+// 每个订阅者的事件缓冲区大小
+const eventBufferSize = 256
+
+// EventFilter 订阅过滤条件，字段为空表示不过滤
+type EventFilter struct {
+	Cluster   string
+	Namespace string
+	Kinds     map[string]bool
+	// AllowNamespace 按权限过滤命名空间，为 nil 表示不限制
+	AllowNamespace func(namespace string) bool
+}
+
+// Matches 判断事件是否符合过滤条件
+// 与集群无关的事件（Cluster 为空）推送给所有集群的订阅者
+func (f EventFilter) Matches(event models.ResourceEvent) bool {
+	if f.Cluster != "" && event.Cluster != "" && event.Cluster != f.Cluster {
+		return false
+	}
+	if f.Namespace != "" && f.Namespace != "all" && event.Namespace != f.Namespace {
+		return false
+	}
+	if f.AllowNamespace != nil && event.Namespace != "" && !f.AllowNamespace(event.Namespace) {
+		return false
+	}
+	if len(f.Kinds) > 0 && !f.Kinds[event.Kind] {
+		return false
+	}
+	return true
+}
+
+// ParseEventKinds 解析逗号分隔的事件对象类型
+func ParseEventKinds(value string) map[string]bool {
+	kinds := make(map[string]bool)
+	for _, kind := range strings.Split(value, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds[kind] = true
+		}
+	}
+	return kinds
+}
+
+// EventSubscription 事件订阅，C 在订阅取消或消费过慢时关闭
+type EventSubscription struct {
+	C      <-chan models.ResourceEvent
+	ch     chan models.ResourceEvent
+	filter EventFilter
+}
+
+// EventHub 将资源变更事件分发给 SSE 订阅者
+type EventHub struct {
+	subscribers map[*EventSubscription]struct{}
+	mutex       sync.RWMutex
+}
+
+func NewEventHub() *EventHub {
+	return &EventHub{
+		subscribers: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// Subscribe 订阅符合过滤条件的事件
+func (h *EventHub) Subscribe(filter EventFilter) *EventSubscription {
+	ch := make(chan models.ResourceEvent, eventBufferSize)
+	sub := &EventSubscription{C: ch, ch: ch, filter: filter}
+
+	h.mutex.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mutex.Unlock()
+
+	return sub
+}
+
+// Unsubscribe 取消订阅，可重复调用
+func (h *EventHub) Unsubscribe(sub *EventSubscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, exists := h.subscribers[sub]; exists {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish 发布事件，不会阻塞
+// 缓冲区已满的订阅者会被断开，由客户端重连后重新加载列表，避免静默丢失事件
+func (h *EventHub) Publish(event models.ResourceEvent) {
+	if h == nil {
+		return
+	}
+
+	var slow []*EventSubscription
+
+	h.mutex.RLock()
+	for sub := range h.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mutex.RUnlock()
+
+	for _, sub := range slow {
+		h.Unsubscribe(sub)
+	}
+}
@@ -0,0 +1,690 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	groupsnapshotv1alpha1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumegroupsnapshot/v1alpha1"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// GroupSnapshotLabel 快照组名标签，打在 fallback 模式的成员快照和 native 模式的 VolumeGroupSnapshot 上
+	GroupSnapshotLabel = "k8s-volume-snapshots/group"
+	// native 模式按名称指定 PVC 时给 PVC 打的标签，作为 VolumeGroupSnapshot 的选择器
+	groupTargetLabel = "k8s-volume-snapshots/group-target"
+	// fallback 模式成员快照记录的组大小，用于发现缺失的成员
+	groupSizeAnnotation = "k8s-volume-snapshots/group-size"
+	// 默认 VolumeGroupSnapshotClass 的注解
+	defaultGroupSnapshotClassAnnotation = "groupsnapshot.storage.kubernetes.io/is-default-class"
+	// 一个快照组最多包含的 PVC 数（VolumeGroupSnapshot 的上限）
+	maxGroupSnapshotMembers = 100
+)
+
+var (
+	// ErrGroupSnapshotNotFound 快照组不存在
+	ErrGroupSnapshotNotFound = errors.New("快照组不存在")
+	// ErrGroupSnapshotExists 同名快照组已存在
+	ErrGroupSnapshotExists = errors.New("同名快照组已存在")
+)
+
+// ValidateGroupSnapshotRequest 校验创建快照组请求
+func ValidateGroupSnapshotRequest(req *models.CreateGroupSnapshotRequest) error {
+	// 组名同时用作标签值，必须是 DNS label
+	if errs := validation.IsDNS1123Label(req.Name); len(errs) > 0 {
+		return fmt.Errorf("无效的快照组名称 %q: %s", req.Name, strings.Join(errs, "; "))
+	}
+	if (len(req.PVCNames) == 0) == (req.PVCSelector == "") {
+		return fmt.Errorf("pvcNames 和 pvcSelector 必须指定其中一个")
+	}
+	if len(req.PVCNames) > maxGroupSnapshotMembers {
+		return fmt.Errorf("一个快照组最多包含 %d 个 PVC", maxGroupSnapshotMembers)
+	}
+	seen := make(map[string]bool)
+	for _, name := range req.PVCNames {
+		if seen[name] {
+			return fmt.Errorf("PVC %s 重复", name)
+		}
+		seen[name] = true
+	}
+	if req.PVCSelector != "" {
+		if _, err := labels.Parse(req.PVCSelector); err != nil {
+			return fmt.Errorf("无效的 pvcSelector: %v", err)
+		}
+	}
+	switch req.Mode {
+	case "", models.GroupSnapshotModeAuto, models.GroupSnapshotModeNative, models.GroupSnapshotModeFallback:
+	default:
+		return fmt.Errorf("无效的 mode %q，可选 auto、native、fallback", req.Mode)
+	}
+	return ValidateSnapshotHooks(req.Hooks)
+}
+
+// createGroupSnapshot 创建快照组：集群支持时使用 VolumeGroupSnapshot，否则执行钩子后并行创建 VolumeSnapshot
+// 返回的钩子结果在创建失败时同样有效
+func createGroupSnapshot(ctx context.Context, config *rest.Config, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, req models.CreateGroupSnapshotRequest) (*models.GroupSnapshotInfo, []models.HookResult, error) {
+	if _, err := getGroupSnapshot(ctx, clientSet, snapshotClientSet, req.Namespace, req.Name); err == nil {
+		return nil, nil, ErrGroupSnapshotExists
+	} else if !errors.Is(err, ErrGroupSnapshotNotFound) {
+		return nil, nil, err
+	}
+
+	pvcs, err := resolveGroupPVCs(ctx, clientSet, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	pvcNames := make([]string, 0, len(pvcs))
+	for _, pvc := range pvcs {
+		pvcNames = append(pvcNames, pvc.Name)
+	}
+
+	mode := req.Mode
+	var className string
+	if mode != models.GroupSnapshotModeFallback {
+		className, err = groupSnapshotClassFor(ctx, clientSet, snapshotClientSet, req.VolumeGroupSnapshotClassName, pvcs)
+		switch {
+		case err == nil:
+			mode = models.GroupSnapshotModeNative
+		case mode == models.GroupSnapshotModeNative:
+			return nil, nil, err
+		default:
+			fmt.Printf("快照组 %s/%s 无法使用 VolumeGroupSnapshot，改为钩子加并行创建快照: %v\n", req.Namespace, req.Name, err)
+			mode = models.GroupSnapshotModeFallback
+		}
+	}
+
+	hookResults, err := runWithHooks(ctx, config, clientSet, req.Namespace, pvcNames, req.Hooks, func() (func() error, error) {
+		if mode == models.GroupSnapshotModeNative {
+			return createNativeGroupSnapshot(ctx, clientSet, snapshotClientSet, req, pvcs, className)
+		}
+		return createFallbackGroupSnapshot(ctx, snapshotClientSet, req, pvcs)
+	})
+	if err != nil {
+		return nil, hookResults, err
+	}
+
+	info, err := getGroupSnapshot(ctx, clientSet, snapshotClientSet, req.Namespace, req.Name)
+	if err != nil {
+		// 已创建成功，查询失败时返回基本信息，稍后可再次查询
+		fmt.Printf("查询快照组 %s/%s 失败: %v\n", req.Namespace, req.Name, err)
+		info = &models.GroupSnapshotInfo{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+			Mode:      mode,
+			Phase:     models.GroupSnapshotPhasePending,
+			CreatedBy: req.CreatedBy,
+			CreatedAt: time.Now(),
+			Members:   []models.GroupSnapshotMember{},
+		}
+	}
+	info.Hooks = hookResults
+	return info, hookResults, nil
+}
+
+// resolveGroupPVCs 解析快照组的成员 PVC，成员必须已绑定且未在删除中
+func resolveGroupPVCs(ctx context.Context, clientSet kubernetes.Interface, req models.CreateGroupSnapshotRequest) ([]corev1.PersistentVolumeClaim, error) {
+	var pvcs []corev1.PersistentVolumeClaim
+	if req.PVCSelector != "" {
+		pvcList, err := clientSet.CoreV1().PersistentVolumeClaims(req.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("获取 PVC 列表失败: %v", err)
+		}
+		pvcs, err = SelectPVCs(&models.ScheduledSnapshot{Namespace: req.Namespace, PVCSelector: req.PVCSelector}, pvcList.Items, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(pvcs) == 0 {
+			return nil, fmt.Errorf("命名空间 %s 中没有匹配 %s 的已绑定 PVC", req.Namespace, req.PVCSelector)
+		}
+		if len(pvcs) > maxGroupSnapshotMembers {
+			return nil, fmt.Errorf("匹配的 PVC 有 %d 个，超过快照组上限 %d", len(pvcs), maxGroupSnapshotMembers)
+		}
+		return pvcs, nil
+	}
+
+	for _, name := range req.PVCNames {
+		pvc, err := clientSet.CoreV1().PersistentVolumeClaims(req.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("获取 PVC %s 失败: %v", name, err)
+		}
+		if pvc.DeletionTimestamp != nil || pvc.Status.Phase != corev1.ClaimBound {
+			return nil, fmt.Errorf("PVC %s 未绑定或正在删除", name)
+		}
+		pvcs = append(pvcs, *pvc)
+	}
+	return pvcs, nil
+}
+
+// groupSnapshotClassFor 确认集群支持 VolumeGroupSnapshot 并选择成员 PVC 的 CSI 驱动对应的 VolumeGroupSnapshotClass
+// 未指定类时优先使用默认类
+func groupSnapshotClassFor(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, className string, pvcs []corev1.PersistentVolumeClaim) (string, error) {
+	if !groupSnapshotSupported(snapshotClientSet) {
+		return "", fmt.Errorf("集群未安装 VolumeGroupSnapshot API (%s)", groupsnapshotv1alpha1.SchemeGroupVersion)
+	}
+
+	drivers := make(map[string]bool)
+	for _, pvc := range pvcs {
+		pv, err := clientSet.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("获取 PVC %s 的 PV 失败: %v", pvc.Name, err)
+		}
+		if pv.Spec.CSI == nil {
+			return "", fmt.Errorf("PVC %s 不是 CSI 卷", pvc.Name)
+		}
+		drivers[pv.Spec.CSI.Driver] = true
+	}
+	if len(drivers) != 1 {
+		return "", fmt.Errorf("成员 PVC 使用了 %d 个不同的 CSI 驱动，无法创建 VolumeGroupSnapshot", len(drivers))
+	}
+	var driver string
+	for name := range drivers {
+		driver = name
+	}
+
+	classes, err := snapshotClientSet.GroupsnapshotV1alpha1().VolumeGroupSnapshotClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("获取 VolumeGroupSnapshotClass 列表失败: %v", err)
+	}
+	var candidate string
+	for _, class := range classes.Items {
+		if className != "" {
+			if class.Name != className {
+				continue
+			}
+			if class.Driver != driver {
+				return "", fmt.Errorf("VolumeGroupSnapshotClass %s 的驱动 %s 与 PVC 的驱动 %s 不一致", class.Name, class.Driver, driver)
+			}
+			return class.Name, nil
+		}
+		if class.Driver != driver {
+			continue
+		}
+		if class.Annotations[defaultGroupSnapshotClassAnnotation] == "true" {
+			return class.Name, nil
+		}
+		if candidate == "" {
+			candidate = class.Name
+		}
+	}
+	if className != "" {
+		return "", fmt.Errorf("VolumeGroupSnapshotClass %s 不存在", className)
+	}
+	if candidate == "" {
+		return "", fmt.Errorf("没有驱动 %s 的 VolumeGroupSnapshotClass", driver)
+	}
+	return candidate, nil
+}
+
+// groupSnapshotSupported 通过 discovery 判断集群是否提供 VolumeGroupSnapshot API
+func groupSnapshotSupported(snapshotClientSet snapshotclientset.Interface) bool {
+	resources, err := snapshotClientSet.Discovery().ServerResourcesForGroupVersion(groupsnapshotv1alpha1.SchemeGroupVersion.String())
+	if err != nil {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == "volumegroupsnapshots" {
+			return true
+		}
+	}
+	return false
+}
+
+// createNativeGroupSnapshot 创建 VolumeGroupSnapshot，返回等待切点的函数
+// 按名称指定 PVC 时先给成员 PVC 打上组标签作为选择器，并移除之前同名组遗留在其它 PVC 上的标签
+func createNativeGroupSnapshot(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, req models.CreateGroupSnapshotRequest, pvcs []corev1.PersistentVolumeClaim, className string) (func() error, error) {
+	var selector *metav1.LabelSelector
+	if req.PVCSelector != "" {
+		var err error
+		if selector, err = metav1.ParseToLabelSelector(req.PVCSelector); err != nil {
+			return nil, fmt.Errorf("无效的 pvcSelector: %v", err)
+		}
+	} else {
+		if err := clearGroupTargetLabels(ctx, clientSet, req.Namespace, req.Name, req.PVCNames); err != nil {
+			return nil, err
+		}
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, groupTargetLabel, req.Name))
+		for _, pvc := range pvcs {
+			if _, err := clientSet.CoreV1().PersistentVolumeClaims(req.Namespace).Patch(ctx, pvc.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				return nil, fmt.Errorf("给 PVC %s 添加组标签失败: %v", pvc.Name, err)
+			}
+		}
+		selector = &metav1.LabelSelector{MatchLabels: map[string]string{groupTargetLabel: req.Name}}
+	}
+
+	vgs := &groupsnapshotv1alpha1.VolumeGroupSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        req.Name,
+			Namespace:   req.Namespace,
+			Labels:      groupSnapshotLabels(req),
+			Annotations: groupSnapshotAnnotations(req, len(pvcs)),
+		},
+		Spec: groupsnapshotv1alpha1.VolumeGroupSnapshotSpec{
+			Source: groupsnapshotv1alpha1.VolumeGroupSnapshotSource{
+				Selector: *selector,
+			},
+			VolumeGroupSnapshotClassName: &className,
+		},
+	}
+	if _, err := snapshotClientSet.GroupsnapshotV1alpha1().VolumeGroupSnapshots(req.Namespace).Create(ctx, vgs, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("创建 VolumeGroupSnapshot 失败: %v", err)
+	}
+
+	return func() error {
+		return pollUntil(ctx, hookSnapshotCutTimeout, func() (bool, error) {
+			current, err := snapshotClientSet.GroupsnapshotV1alpha1().VolumeGroupSnapshots(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			status := current.Status
+			if status == nil {
+				return false, nil
+			}
+			return status.CreationTime != nil || status.Error != nil ||
+				(status.ReadyToUse != nil && *status.ReadyToUse), nil
+		}, fmt.Sprintf("等待快照组 %s 切点超时", req.Name))
+	}, nil
+}
+
+// createFallbackGroupSnapshot 并行为每个 PVC 创建 VolumeSnapshot，返回等待所有切点的函数
+// 任一成员创建失败时删除已创建的成员，避免留下不完整的快照组
+func createFallbackGroupSnapshot(ctx context.Context, snapshotClientSet snapshotclientset.Interface, req models.CreateGroupSnapshotRequest, pvcs []corev1.PersistentVolumeClaim) (func() error, error) {
+	created := make([]string, len(pvcs))
+	errs := make([]error, len(pvcs))
+	var wg sync.WaitGroup
+	for i := range pvcs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vs := buildGroupMemberSnapshot(req, pvcs[i].Name, len(pvcs))
+			result, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(req.Namespace).Create(ctx, vs, metav1.CreateOptions{})
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %v", pvcs[i].Name, err)
+				return
+			}
+			created[i] = result.Name
+		}(i)
+	}
+	wg.Wait()
+
+	var failures []string
+	for _, err := range errs {
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		for _, name := range created {
+			if name == "" {
+				continue
+			}
+			if err := snapshotClientSet.SnapshotV1().VolumeSnapshots(req.Namespace).Delete(context.WithoutCancel(ctx), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				fmt.Printf("清理快照组 %s/%s 的成员快照 %s 失败: %v\n", req.Namespace, req.Name, name, err)
+			}
+		}
+		return nil, fmt.Errorf("创建成员快照失败，已清理组内其它快照: %s", strings.Join(failures, "; "))
+	}
+
+	return func() error {
+		for _, name := range created {
+			if err := waitForSnapshotCut(ctx, snapshotClientSet, req.Namespace, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// memberSnapshotName 返回 <前缀>-<PVC 名>，超过 253 个字符时截断并附加哈希，
+// 避免前缀相同的长 PVC 名截断后得到同一个快照名
+func memberSnapshotName(prefix, pvcName string) string {
+	name := prefix + "-" + pvcName
+	if len(name) <= 253 {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:10]
+	return strings.TrimRight(name[:253-len(suffix)-1], "-.") + "-" + suffix
+}
+
+// buildGroupMemberSnapshot 生成 fallback 模式的成员快照，命名为 <组名>-<PVC 名>
+func buildGroupMemberSnapshot(req models.CreateGroupSnapshotRequest, pvcName string, size int) *snapshotv1.VolumeSnapshot {
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        memberSnapshotName(req.Name, pvcName),
+			Namespace:   req.Namespace,
+			Labels:      groupSnapshotLabels(req),
+			Annotations: groupSnapshotAnnotations(req, size),
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &pvcName,
+			},
+		},
+	}
+	if req.VolumeSnapshotClassName != "" {
+		className := req.VolumeSnapshotClassName
+		vs.Spec.VolumeSnapshotClassName = &className
+	}
+	return vs
+}
+
+func groupSnapshotLabels(req models.CreateGroupSnapshotRequest) map[string]string {
+	return map[string]string{
+		GroupSnapshotLabel: req.Name,
+		"created-by":       CreatedByLabelValue(req.CreatedBy),
+		"app":              "k8s-volume-snapshots",
+	}
+}
+
+func groupSnapshotAnnotations(req models.CreateGroupSnapshotRequest, size int) map[string]string {
+	return map[string]string{
+		"k8s-volume-snapshots/created-by": req.CreatedBy,
+		"k8s-volume-snapshots/created-at": time.Now().Format(time.RFC3339),
+		groupSizeAnnotation:               strconv.Itoa(size),
+	}
+}
+
+// clearGroupTargetLabels 移除不在 keep 中的 PVC 上指向该组的标签
+func clearGroupTargetLabels(ctx context.Context, clientSet kubernetes.Interface, namespace, group string, keep []string) error {
+	pvcList, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: groupTargetLabel + "=" + group,
+	})
+	if err != nil {
+		return fmt.Errorf("获取 PVC 列表失败: %v", err)
+	}
+
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+	patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{%q:null}}}`, groupTargetLabel))
+	for _, pvc := range pvcList.Items {
+		if kept[pvc.Name] {
+			continue
+		}
+		if _, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, pvc.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("移除 PVC %s 的组标签失败: %v", pvc.Name, err)
+		}
+	}
+	return nil
+}
+
+// getGroupSnapshot 获取快照组：先查找同名 VolumeGroupSnapshot，再查找带组标签的 VolumeSnapshot
+func getGroupSnapshot(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, namespace, name string) (*models.GroupSnapshotInfo, error) {
+	if groupSnapshotSupported(snapshotClientSet) {
+		vgs, err := snapshotClientSet.GroupsnapshotV1alpha1().VolumeGroupSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			return nativeGroupSnapshotInfo(ctx, clientSet, snapshotClientSet, vgs), nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	vsList, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: GroupSnapshotLabel + "=" + name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(vsList.Items) == 0 {
+		return nil, ErrGroupSnapshotNotFound
+	}
+	return fallbackGroupSnapshotInfo(namespace, name, vsList.Items), nil
+}
+
+// listGroupSnapshots 获取命名空间中的所有快照组，按创建时间倒序
+func listGroupSnapshots(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, namespace string) ([]models.GroupSnapshotInfo, error) {
+	groups := []models.GroupSnapshotInfo{}
+
+	if groupSnapshotSupported(snapshotClientSet) {
+		vgsList, err := snapshotClientSet.GroupsnapshotV1alpha1().VolumeGroupSnapshots(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range vgsList.Items {
+			groups = append(groups, *nativeGroupSnapshotInfo(ctx, clientSet, snapshotClientSet, &vgsList.Items[i]))
+		}
+	}
+
+	vsList, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: GroupSnapshotLabel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string][]snapshotv1.VolumeSnapshot)
+	for _, vs := range vsList.Items {
+		name := vs.Labels[GroupSnapshotLabel]
+		members[name] = append(members[name], vs)
+	}
+	for name, items := range members {
+		groups = append(groups, *fallbackGroupSnapshotInfo(namespace, name, items))
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].CreatedAt.Equal(groups[j].CreatedAt) {
+			return groups[i].CreatedAt.After(groups[j].CreatedAt)
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// nativeGroupSnapshotInfo 根据 VolumeGroupSnapshot 及其成员快照汇总快照组状态
+func nativeGroupSnapshotInfo(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, vgs *groupsnapshotv1alpha1.VolumeGroupSnapshot) *models.GroupSnapshotInfo {
+	info := &models.GroupSnapshotInfo{
+		Name:      vgs.Name,
+		Namespace: vgs.Namespace,
+		Mode:      models.GroupSnapshotModeNative,
+		CreatedBy: vgs.Annotations["k8s-volume-snapshots/created-by"],
+		CreatedAt: vgs.CreationTimestamp.Time,
+		Members:   []models.GroupSnapshotMember{},
+	}
+	if vgs.Spec.VolumeGroupSnapshotClassName != nil {
+		info.ClassName = *vgs.Spec.VolumeGroupSnapshotClassName
+	}
+
+	groupReady := false
+	if vgs.Status != nil {
+		groupReady = vgs.Status.ReadyToUse != nil && *vgs.Status.ReadyToUse
+		if vgs.Status.Error != nil && vgs.Status.Error.Message != nil {
+			info.Error = *vgs.Status.Error.Message
+		}
+
+		// 控制器创建的成员快照以 VolumeSnapshotContent 为源，通过卷句柄找回源 PVC
+		resolvePVC := sourcePVCResolver(ctx, clientSet, snapshotClientSet)
+		for _, ref := range vgs.Status.VolumeSnapshotRefList {
+			namespace := ref.Namespace
+			if namespace == "" {
+				namespace = vgs.Namespace
+			}
+			vs, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+			if err != nil {
+				info.Members = append(info.Members, models.GroupSnapshotMember{SnapshotName: ref.Name, Error: err.Error()})
+				continue
+			}
+			member := groupSnapshotMember(vs)
+			if member.PVCName == "" && member.BoundContentName != "" {
+				member.PVCName = resolvePVC(vs.Namespace, member.BoundContentName)
+			}
+			info.Members = append(info.Members, member)
+		}
+	}
+
+	finishGroupSnapshotInfo(info, groupReady, 0)
+	return info
+}
+
+// fallbackGroupSnapshotInfo 根据带组标签的成员快照汇总快照组状态
+func fallbackGroupSnapshotInfo(namespace, name string, items []snapshotv1.VolumeSnapshot) *models.GroupSnapshotInfo {
+	info := &models.GroupSnapshotInfo{
+		Name:      name,
+		Namespace: namespace,
+		Mode:      models.GroupSnapshotModeFallback,
+		Members:   []models.GroupSnapshotMember{},
+	}
+
+	expected := 0
+	for i, vs := range items {
+		if i == 0 || vs.CreationTimestamp.Time.Before(info.CreatedAt) {
+			info.CreatedAt = vs.CreationTimestamp.Time
+		}
+		if info.CreatedBy == "" {
+			info.CreatedBy = vs.Annotations["k8s-volume-snapshots/created-by"]
+		}
+		if info.ClassName == "" && vs.Spec.VolumeSnapshotClassName != nil {
+			info.ClassName = *vs.Spec.VolumeSnapshotClassName
+		}
+		if size, err := strconv.Atoi(vs.Annotations[groupSizeAnnotation]); err == nil && size > expected {
+			expected = size
+		}
+		info.Members = append(info.Members, groupSnapshotMember(&vs))
+	}
+
+	finishGroupSnapshotInfo(info, true, expected)
+	return info
+}
+
+// groupSnapshotMember 提取成员快照的状态
+func groupSnapshotMember(vs *snapshotv1.VolumeSnapshot) models.GroupSnapshotMember {
+	member := models.GroupSnapshotMember{SnapshotName: vs.Name}
+	if vs.Spec.Source.PersistentVolumeClaimName != nil {
+		member.PVCName = *vs.Spec.Source.PersistentVolumeClaimName
+	}
+	if vs.DeletionTimestamp != nil {
+		member.Error = "快照正在删除"
+	}
+	if vs.Status != nil {
+		member.ReadyToUse = vs.Status.ReadyToUse != nil && *vs.Status.ReadyToUse
+		if vs.Status.RestoreSize != nil {
+			member.RestoreSize = vs.Status.RestoreSize.String()
+		}
+		if vs.Status.BoundVolumeSnapshotContentName != nil {
+			member.BoundContentName = *vs.Status.BoundVolumeSnapshotContentName
+		}
+		if vs.Status.Error != nil && vs.Status.Error.Message != nil {
+			member.Error = *vs.Status.Error.Message
+		}
+	}
+	return member
+}
+
+// finishGroupSnapshotInfo 排序成员并计算汇总状态：任一成员报错或成员缺失为 failed，全部就绪为 ready
+// expected 为创建时记录的成员数，为 0 时不检查
+func finishGroupSnapshotInfo(info *models.GroupSnapshotInfo, groupReady bool, expected int) {
+	sort.Slice(info.Members, func(i, j int) bool {
+		if info.Members[i].PVCName != info.Members[j].PVCName {
+			return info.Members[i].PVCName < info.Members[j].PVCName
+		}
+		return info.Members[i].SnapshotName < info.Members[j].SnapshotName
+	})
+
+	allReady := groupReady && len(info.Members) > 0
+	for _, member := range info.Members {
+		if !member.ReadyToUse {
+			allReady = false
+		}
+		if member.Error != "" && info.Error == "" {
+			info.Error = member.SnapshotName + ": " + member.Error
+		}
+	}
+	if info.Error == "" && len(info.Members) < expected {
+		info.Error = fmt.Sprintf("成员快照缺失（%d/%d）", len(info.Members), expected)
+	}
+
+	switch {
+	case info.Error != "":
+		info.Phase = models.GroupSnapshotPhaseFailed
+	case allReady:
+		info.Phase = models.GroupSnapshotPhaseReady
+	default:
+		info.Phase = models.GroupSnapshotPhasePending
+	}
+	info.ReadyToUse = info.Phase == models.GroupSnapshotPhaseReady
+}
+
+// sourcePVCResolver 返回通过 VolumeSnapshotContent 的卷句柄查找源 PVC 的函数，PV 列表在首次使用时加载
+// 找不到时返回空字符串
+func sourcePVCResolver(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface) func(namespace, contentName string) string {
+	var claims map[string]*corev1.ObjectReference
+	return func(namespace, contentName string) string {
+		content, err := snapshotClientSet.SnapshotV1().VolumeSnapshotContents().Get(ctx, contentName, metav1.GetOptions{})
+		if err != nil || content.Spec.Source.VolumeHandle == nil {
+			return ""
+		}
+		if claims == nil {
+			claims = make(map[string]*corev1.ObjectReference)
+			pvList, err := clientSet.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return ""
+			}
+			for _, pv := range pvList.Items {
+				if pv.Spec.CSI != nil && pv.Spec.ClaimRef != nil {
+					claims[pv.Spec.CSI.VolumeHandle] = pv.Spec.ClaimRef
+				}
+			}
+		}
+		if ref := claims[*content.Spec.Source.VolumeHandle]; ref != nil && ref.Namespace == namespace {
+			return ref.Name
+		}
+		return ""
+	}
+}
+
+// deleteGroupSnapshot 删除快照组：native 模式删除 VolumeGroupSnapshot（成员快照由控制器按删除策略处理），
+// fallback 模式删除所有成员快照
+func deleteGroupSnapshot(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, namespace, name string) error {
+	if groupSnapshotSupported(snapshotClientSet) {
+		err := snapshotClientSet.GroupsnapshotV1alpha1().VolumeGroupSnapshots(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		if err == nil {
+			if err := clearGroupTargetLabels(ctx, clientSet, namespace, name, nil); err != nil {
+				fmt.Printf("清理快照组 %s/%s 的 PVC 标签失败: %v\n", namespace, name, err)
+			}
+			return nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	vsList, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: GroupSnapshotLabel + "=" + name,
+	})
+	if err != nil {
+		return err
+	}
+	if len(vsList.Items) == 0 {
+		return ErrGroupSnapshotNotFound
+	}
+
+	var failures []string
+	for _, vs := range vsList.Items {
+		if err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Delete(ctx, vs.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			failures = append(failures, fmt.Sprintf("%s: %v", vs.Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("删除成员快照失败: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemberSnapshotName(t *testing.T) {
+	if name := memberSnapshotName("pg", "data-0"); name != "pg-data-0" {
+		t.Fatalf("短名称不应改变: %s", name)
+	}
+
+	prefix := strings.Repeat("g", 200)
+	first := memberSnapshotName(prefix, strings.Repeat("p", 60)+"-a")
+	second := memberSnapshotName(prefix, strings.Repeat("p", 60)+"-b")
+	if len(first) > 253 || len(second) > 253 {
+		t.Fatalf("名称超过 253 个字符: %d, %d", len(first), len(second))
+	}
+	if first == second {
+		t.Fatalf("截断后的名称冲突: %s", first)
+	}
+}
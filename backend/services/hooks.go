@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 钩子命令默认超时
+	DefaultHookTimeout = 30 * time.Second
+	// 钩子命令最长超时
+	maxHookTimeout = 10 * time.Minute
+	// 等待快照切点（status.creationTime）的最长时间，超时后仍执行 post 钩子，避免应用长时间处于冻结状态
+	hookSnapshotCutTimeout = 2 * time.Minute
+	// 钩子输出保存的最大长度
+	hookOutputLimit = 4096
+)
+
+// ErrPreHookFailed pre 钩子失败且 onError 为 abort，快照未创建
+var ErrPreHookFailed = errors.New("pre 钩子执行失败，已取消创建快照")
+
+// ValidateSnapshotHooks 校验快照钩子配置
+func ValidateSnapshotHooks(hooks *models.SnapshotHooks) error {
+	if hooks == nil {
+		return nil
+	}
+	if len(hooks.Pre) == 0 && len(hooks.Post) == 0 {
+		return fmt.Errorf("钩子至少需要 pre 或 post 命令")
+	}
+	if hooks.Timeout != "" {
+		timeout, err := time.ParseDuration(hooks.Timeout)
+		if err != nil || timeout <= 0 || timeout > maxHookTimeout {
+			return fmt.Errorf("无效的钩子超时时间 %q（最长 %v）", hooks.Timeout, maxHookTimeout)
+		}
+	}
+	switch hooks.OnError {
+	case "", models.HookOnErrorAbort, models.HookOnErrorContinue:
+	default:
+		return fmt.Errorf("无效的 onError %q，可选 abort、continue", hooks.OnError)
+	}
+	if hooks.PodSelector != "" {
+		if _, err := labels.Parse(hooks.PodSelector); err != nil {
+			return fmt.Errorf("无效的 podSelector: %v", err)
+		}
+	}
+	return nil
+}
+
+// FailedHook 返回指定阶段第一个失败的钩子结果，没有失败时返回 nil
+func FailedHook(results []models.HookResult, phase string) *models.HookResult {
+	for i := range results {
+		if results[i].Phase == phase && results[i].Error != "" {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+// hookTarget 执行钩子的 Pod 和容器
+type hookTarget struct {
+	pod       string
+	container string
+}
+
+// createVolumeSnapshotWithHooks 在挂载源 PVC 的 Pod 中执行 pre 钩子、创建快照、等待快照切点后执行 post 钩子
+// 返回的错误只表示快照未创建；post 钩子失败记录在结果中，可用 FailedHook 检查
+func createVolumeSnapshotWithHooks(ctx context.Context, config *rest.Config, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, namespace string, vs *snapshotv1.VolumeSnapshot, hooks *models.SnapshotHooks) (*snapshotv1.VolumeSnapshot, []models.HookResult, error) {
+	if hooks == nil {
+		created, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Create(ctx, vs, metav1.CreateOptions{})
+		return created, nil, err
+	}
+	if vs.Spec.Source.PersistentVolumeClaimName == nil {
+		return nil, nil, fmt.Errorf("快照未指定源 PVC，无法执行钩子")
+	}
+
+	var created *snapshotv1.VolumeSnapshot
+	results, err := runWithHooks(ctx, config, clientSet, namespace, []string{*vs.Spec.Source.PersistentVolumeClaimName}, hooks, func() (func() error, error) {
+		var err error
+		created, err = snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Create(ctx, vs, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return func() error { return waitForSnapshotCut(ctx, snapshotClientSet, namespace, created.Name) }, nil
+	})
+	if err != nil {
+		return nil, results, err
+	}
+	return created, results, nil
+}
+
+// runWithHooks 在挂载 pvcNames 的 Pod 中执行 pre 钩子后调用 snapshot 创建快照，
+// 再调用 snapshot 返回的 waitCut 等待快照切点完成，最后执行 post 钩子
+// post 钩子总会在执行过 pre 钩子的 Pod 中运行（包括 pre 失败和快照创建失败的情况）
+func runWithHooks(ctx context.Context, config *rest.Config, clientSet kubernetes.Interface, namespace string, pvcNames []string, hooks *models.SnapshotHooks, snapshot func() (waitCut func() error, err error)) ([]models.HookResult, error) {
+	if hooks == nil {
+		_, err := snapshot()
+		return nil, err
+	}
+
+	timeout := DefaultHookTimeout
+	if hooks.Timeout != "" {
+		if parsed, err := time.ParseDuration(hooks.Timeout); err == nil && parsed > 0 {
+			timeout = parsed
+		}
+	}
+
+	results := []models.HookResult{}
+	targets, err := hookTargets(ctx, clientSet, namespace, pvcNames, hooks)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		// PVC 未被挂载时没有需要静默的应用，直接创建快照
+		results = append(results, models.HookResult{Phase: models.HookPhasePre, Skipped: true, StartedAt: time.Now()})
+		_, err := snapshot()
+		return results, err
+	}
+
+	// pre 钩子：记录已执行的 Pod，post 钩子只在这些 Pod 中执行
+	var executed []hookTarget
+	preFailed := false
+	if len(hooks.Pre) > 0 {
+		for _, target := range targets {
+			result := runHook(ctx, config, clientSet, namespace, target, models.HookPhasePre, hooks.Pre, timeout)
+			results = append(results, result)
+			executed = append(executed, target)
+			if result.Error != "" {
+				preFailed = true
+				if hooks.OnError != models.HookOnErrorContinue {
+					break
+				}
+			}
+		}
+	} else {
+		executed = targets
+	}
+
+	// post 钩子在函数返回前总会执行，请求被取消（如客户端断开）时也不能让应用停留在冻结状态
+	runPost := func() {
+		if len(hooks.Post) == 0 {
+			return
+		}
+		postCtx := context.WithoutCancel(ctx)
+		for _, target := range executed {
+			results = append(results, runHook(postCtx, config, clientSet, namespace, target, models.HookPhasePost, hooks.Post, timeout))
+		}
+	}
+
+	if preFailed && hooks.OnError != models.HookOnErrorContinue {
+		runPost()
+		return results, ErrPreHookFailed
+	}
+
+	waitCut, err := snapshot()
+	if err != nil {
+		runPost()
+		return results, err
+	}
+
+	// 快照对象创建后 CSI 驱动才会异步切出快照，等到 creationTime 出现再解除静默
+	if err := waitCut(); err != nil {
+		fmt.Printf("等待快照切点失败，继续执行 post 钩子: %v\n", err)
+	}
+	runPost()
+	return results, nil
+}
+
+// hookTargets 查找挂载任一 PVC 的运行中 Pod 及执行命令的容器，同时挂载多个 PVC 的 Pod 只执行一次
+func hookTargets(ctx context.Context, clientSet kubernetes.Interface, namespace string, pvcNames []string, hooks *models.SnapshotHooks) ([]hookTarget, error) {
+	var pods []corev1.Pod
+	seen := make(map[string]bool)
+	for _, pvcName := range pvcNames {
+		using, err := podsUsingPVC(ctx, clientSet, namespace, pvcName)
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range using {
+			if !seen[pod.Name] {
+				seen[pod.Name] = true
+				pods = append(pods, pod)
+			}
+		}
+	}
+
+	selector := labels.Everything()
+	if hooks.PodSelector != "" {
+		var err error
+		if selector, err = labels.Parse(hooks.PodSelector); err != nil {
+			return nil, fmt.Errorf("无效的 podSelector: %v", err)
+		}
+	}
+
+	var targets []hookTarget
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		container := hooks.Container
+		if container == "" {
+			container = pod.Spec.Containers[0].Name
+		} else if !podHasContainer(&pod, container) {
+			return nil, fmt.Errorf("Pod %s 中没有容器 %s", pod.Name, container)
+		}
+		targets = append(targets, hookTarget{pod: pod.Name, container: container})
+	}
+	return targets, nil
+}
+
+func podHasContainer(pod *corev1.Pod, name string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runHook 在容器中执行一条钩子命令并记录输出
+func runHook(ctx context.Context, config *rest.Config, clientSet kubernetes.Interface, namespace string, target hookTarget, phase string, command []string, timeout time.Duration) models.HookResult {
+	result := models.HookResult{
+		Phase:     phase,
+		Pod:       target.pod,
+		Container: target.container,
+		Command:   command,
+		StartedAt: time.Now(),
+	}
+
+	stdout, stderr, err := execInPod(ctx, config, clientSet, namespace, target.pod, target.container, command, timeout)
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	result.Stdout = truncateHookOutput(stdout)
+	result.Stderr = truncateHookOutput(stderr)
+	if err != nil {
+		var exitErr utilexec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitStatus()
+		} else {
+			result.ExitCode = -1
+		}
+		result.Error = err.Error()
+		fmt.Printf("%s 钩子在 %s/%s (%s) 中执行失败: %v\n", phase, namespace, target.pod, target.container, err)
+	}
+	return result
+}
+
+// execInPod 通过 exec 子资源在容器中执行命令，超时后断开连接
+func execInPod(ctx context.Context, config *rest.Config, clientSet kubernetes.Interface, namespace, pod, container string, command []string, timeout time.Duration) (string, string, error) {
+	req := clientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return "", "", fmt.Errorf("创建 exec 连接失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("命令执行超过 %v", timeout)
+	}
+	return stdout.String(), stderr.String(), err
+}
+
+// waitForSnapshotCut 等待快照切点完成（creationTime 已设置、已就绪或已报错）
+func waitForSnapshotCut(ctx context.Context, snapshotClientSet snapshotclientset.Interface, namespace, name string) error {
+	return pollUntil(ctx, hookSnapshotCutTimeout, func() (bool, error) {
+		vs, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if vs.Status == nil {
+			return false, nil
+		}
+		return vs.Status.CreationTime != nil || vs.Status.Error != nil ||
+			(vs.Status.ReadyToUse != nil && *vs.Status.ReadyToUse), nil
+	}, fmt.Sprintf("等待快照 %s 切点超时", name))
+}
+
+func truncateHookOutput(output string) string {
+	if len(output) > hookOutputLimit {
+		return output[:hookOutputLimit] + "\n...（输出已截断）"
+	}
+	return output
+}
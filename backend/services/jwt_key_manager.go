@@ -0,0 +1,632 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// JWT 签名密钥默认存储文件路径
+	JWTKeyDataFile = "/data/jwt_keys.json"
+	// Secret 中保存密钥集的键名
+	jwtKeySecretKey = "keys.json"
+	// 从存储重新加载密钥集的间隔，多副本部署时用于同步其他副本的轮换
+	jwtKeyReloadInterval = time.Minute
+	// 遇到未知 kid 时重新加载密钥集的最小间隔，避免伪造 kid 的请求频繁访问存储
+	jwtKeyMissReloadInterval = 5 * time.Second
+	// 轮换后旧密钥继续用于验证的默认时长，应大于访问令牌有效期
+	DefaultJWTKeyRetireAfter = 24 * time.Hour
+)
+
+// 密钥状态
+const (
+	JWTKeyActive  = "active"  // 用于签名和验证
+	JWTKeyVerify  = "verify"  // 已被轮换，只用于验证
+	JWTKeyRetired = "retired" // 不再使用
+)
+
+// 支持的签名算法
+var supportedJWTAlgorithms = map[string]jwt.SigningMethod{
+	"HS256": jwt.SigningMethodHS256,
+	"RS256": jwt.SigningMethodRS256,
+	"EdDSA": jwt.SigningMethodEdDSA,
+}
+
+// errJWTKeySetConflict 其他副本已经更新了密钥集
+var errJWTKeySetConflict = errors.New("密钥集已被其他副本更新")
+
+type jwtKeySet struct {
+	Keys []models.JWTKey `json:"keys"`
+}
+
+// jwtKeyStore 密钥集的持久化存储，version 用于多副本并发更新时的乐观锁
+type jwtKeyStore interface {
+	load() (*jwtKeySet, string, error)
+	save(set *jwtKeySet, version string) (string, error)
+	describe() string
+}
+
+// parsedJWTKey 解析后可直接用于签名和验证的密钥
+type parsedJWTKey struct {
+	meta      models.JWTKey
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// JWTKeyManager 管理 JWT 签名密钥：使用当前密钥签名并在头部写入 kid，
+// 用所有未退役的密钥验证，并可按计划自动轮换
+type JWTKeyManager struct {
+	store            jwtKeyStore
+	algorithm        string
+	rotationInterval time.Duration
+	retireAfter      time.Duration
+
+	mutex     sync.RWMutex
+	keys      map[string]*parsedJWTKey
+	activeKid string
+	version   string
+
+	// missMutex 串行化未知 kid 触发的重新加载
+	missMutex      sync.Mutex
+	lastMissReload time.Time
+}
+
+// NewJWTKeyManager 根据环境变量创建密钥管理器，存储中没有密钥时生成第一个密钥
+func NewJWTKeyManager() (*JWTKeyManager, error) {
+	manager := &JWTKeyManager{
+		algorithm:   envOrDefault("JWT_SIGNING_ALG", "HS256"),
+		retireAfter: DefaultJWTKeyRetireAfter,
+		keys:        make(map[string]*parsedJWTKey),
+	}
+
+	if _, ok := supportedJWTAlgorithms[manager.algorithm]; !ok {
+		return nil, fmt.Errorf("不支持的 JWT_SIGNING_ALG: %s（可选 HS256、RS256、EdDSA）", manager.algorithm)
+	}
+	if value := os.Getenv("JWT_KEY_ROTATION_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("无效的 JWT_KEY_ROTATION_INTERVAL: %s", value)
+		}
+		manager.rotationInterval = d
+	}
+	if value := os.Getenv("JWT_KEY_RETIRE_AFTER"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("无效的 JWT_KEY_RETIRE_AFTER: %s", value)
+		}
+		manager.retireAfter = d
+	}
+
+	store, err := newJWTKeyStore()
+	if err != nil {
+		return nil, err
+	}
+	manager.store = store
+
+	if err := manager.reload(); err != nil {
+		return nil, err
+	}
+	if err := manager.maintain(false); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("JWT 签名密钥: %s，当前 kid=%s\n", store.describe(), manager.activeKid)
+
+	go manager.run()
+	return manager, nil
+}
+
+// newJWTKeyStore JWT_KEYS_SECRET（namespace/name）优先，否则使用 JWT_KEYS_FILE
+func newJWTKeyStore() (jwtKeyStore, error) {
+	if ref := os.Getenv("JWT_KEYS_SECRET"); ref != "" {
+		parts := strings.SplitN(ref, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("JWT_KEYS_SECRET 格式应为 namespace/name: %s", ref)
+		}
+		config, err := getConfig()
+		if err != nil {
+			return nil, fmt.Errorf("创建 Kubernetes 客户端失败: %v", err)
+		}
+		clientSet, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("创建 Kubernetes 客户端失败: %v", err)
+		}
+		return &secretJWTKeyStore{client: clientSet, namespace: parts[0], name: parts[1]}, nil
+	}
+
+	return &fileJWTKeyStore{path: envOrDefault("JWT_KEYS_FILE", JWTKeyDataFile)}, nil
+}
+
+func (m *JWTKeyManager) run() {
+	ticker := time.NewTicker(jwtKeyReloadInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.reload(); err != nil {
+			fmt.Printf("重新加载 JWT 签名密钥失败: %v\n", err)
+			continue
+		}
+		if err := m.maintain(false); err != nil {
+			fmt.Printf("轮换 JWT 签名密钥失败: %v\n", err)
+		}
+	}
+}
+
+// reload 从存储加载密钥集，版本未变化时跳过解析
+func (m *JWTKeyManager) reload() error {
+	set, version, err := m.store.load()
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if version != "" && version == m.version {
+		return nil
+	}
+	return m.applyLocked(set, version)
+}
+
+func (m *JWTKeyManager) applyLocked(set *jwtKeySet, version string) error {
+	keys := make(map[string]*parsedJWTKey)
+	activeKid := ""
+	var activeCreated time.Time
+
+	for _, meta := range set.Keys {
+		parsed, err := parseJWTKey(meta)
+		if err != nil {
+			return fmt.Errorf("解析密钥 %s 失败: %v", meta.ID, err)
+		}
+		keys[meta.ID] = parsed
+		if meta.Status == JWTKeyActive && meta.CreatedAt.After(activeCreated) {
+			activeKid = meta.ID
+			activeCreated = meta.CreatedAt
+		}
+	}
+
+	m.keys = keys
+	m.activeKid = activeKid
+	m.version = version
+	return nil
+}
+
+// maintain 确保存在当前密钥，按计划轮换并退役过期的旧密钥；force 为 true 时立即轮换
+func (m *JWTKeyManager) maintain(force bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	set := m.snapshotLocked()
+	now := time.Now()
+	changed := false
+
+	active, hasActive := m.keys[m.activeKid]
+	switch {
+	case !hasActive && len(set.Keys) == 0 && os.Getenv("JWT_SECRET") != "":
+		// 兼容旧部署：以 JWT_SECRET 作为第一个密钥，之前签发的令牌（没有 kid）仍可验证
+		set.Keys = append(set.Keys, models.JWTKey{
+			ID:        legacyJWTKeyID,
+			Algorithm: "HS256",
+			Secret:    base64.StdEncoding.EncodeToString([]byte(os.Getenv("JWT_SECRET"))),
+			Status:    JWTKeyActive,
+			CreatedAt: now,
+		})
+		changed = true
+		if m.algorithm != "HS256" {
+			force = true
+		}
+	case !hasActive:
+		force = true
+	case m.rotationInterval > 0 && now.Sub(active.meta.CreatedAt) >= m.rotationInterval:
+		force = true
+	case active.meta.Algorithm != m.algorithm:
+		// 修改了 JWT_SIGNING_ALG，立即切换到新算法
+		force = true
+	}
+
+	if force {
+		key, err := generateJWTKey(m.algorithm, now)
+		if err != nil {
+			return err
+		}
+		for i := range set.Keys {
+			if set.Keys[i].Status == JWTKeyActive {
+				set.Keys[i].Status = JWTKeyVerify
+				set.Keys[i].RotatedAt = &now
+			}
+		}
+		set.Keys = append(set.Keys, key)
+		changed = true
+		fmt.Printf("JWT 签名密钥已轮换，新 kid=%s (%s)\n", key.ID, key.Algorithm)
+	}
+
+	kept := set.Keys[:0]
+	for _, key := range set.Keys {
+		if key.Status == JWTKeyVerify && key.RotatedAt != nil && now.Sub(*key.RotatedAt) >= m.retireAfter {
+			key.Status = JWTKeyRetired
+			changed = true
+		}
+		// 退役密钥再保留一个周期便于排查，之后删除
+		if key.Status == JWTKeyRetired && key.RotatedAt != nil && now.Sub(*key.RotatedAt) >= 2*m.retireAfter {
+			changed = true
+			continue
+		}
+		kept = append(kept, key)
+	}
+	set.Keys = kept
+
+	if !changed {
+		return nil
+	}
+
+	version, err := m.store.save(set, m.version)
+	if errors.Is(err, errJWTKeySetConflict) {
+		// 其他副本已经更新（如同时启动时都生成了第一个密钥），立即采用存储中的密钥集，
+		// 否则在下次定时加载之前没有可用的签名密钥
+		latest, latestVersion, err := m.store.load()
+		if err != nil {
+			return err
+		}
+		return m.applyLocked(latest, latestVersion)
+	}
+	if err != nil {
+		return err
+	}
+	return m.applyLocked(set, version)
+}
+
+func (m *JWTKeyManager) snapshotLocked() *jwtKeySet {
+	set := &jwtKeySet{Keys: make([]models.JWTKey, 0, len(m.keys))}
+	for _, key := range m.keys {
+		set.Keys = append(set.Keys, key.meta)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].CreatedAt.Before(set.Keys[j].CreatedAt)
+	})
+	return set
+}
+
+// Rotate 立即轮换签名密钥，旧密钥在 JWT_KEY_RETIRE_AFTER 内仍可验证
+func (m *JWTKeyManager) Rotate() error {
+	if err := m.reload(); err != nil {
+		return err
+	}
+	return m.maintain(true)
+}
+
+// Keys 列出密钥元数据（不包含密钥内容）
+func (m *JWTKeyManager) Keys() []models.JWTKey {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	set := m.snapshotLocked()
+	for i := range set.Keys {
+		set.Keys[i].Secret = ""
+		set.Keys[i].PrivateKey = ""
+	}
+	return set.Keys
+}
+
+// Sign 使用当前密钥签名，并在头部写入 kid
+func (m *JWTKeyManager) Sign(claims jwt.Claims) (string, error) {
+	m.mutex.RLock()
+	key, exists := m.keys[m.activeKid]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return "", errors.New("没有可用的JWT签名密钥")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.meta.ID
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc 按 kid 选择验证密钥，退役密钥和算法不匹配的令牌会被拒绝
+func (m *JWTKeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// 启用密钥管理之前签发的令牌
+		kid = legacyJWTKeyID
+	}
+
+	m.mutex.RLock()
+	key, exists := m.keys[kid]
+	m.mutex.RUnlock()
+
+	if !exists {
+		// 可能是其他副本刚轮换出的密钥
+		m.reloadOnUnknownKid()
+		m.mutex.RLock()
+		key, exists = m.keys[kid]
+		m.mutex.RUnlock()
+	}
+
+	if !exists || key.meta.Status == JWTKeyRetired {
+		return nil, fmt.Errorf("未知或已退役的签名密钥: %s", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("签名算法不匹配: %s", token.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// reloadOnUnknownKid 遇到未知 kid 时重新加载密钥集，间隔不小于 jwtKeyMissReloadInterval
+// 并发的请求等待正在进行的加载完成，之后各自重新查找密钥
+func (m *JWTKeyManager) reloadOnUnknownKid() {
+	m.missMutex.Lock()
+	defer m.missMutex.Unlock()
+
+	if time.Since(m.lastMissReload) < jwtKeyMissReloadInterval {
+		return
+	}
+	m.lastMissReload = time.Now()
+	if err := m.reload(); err != nil {
+		fmt.Printf("重新加载 JWT 签名密钥失败: %v\n", err)
+	}
+}
+
+// ValidMethods 允许的签名算法
+func (m *JWTKeyManager) ValidMethods() []string {
+	methods := make([]string, 0, len(supportedJWTAlgorithms))
+	for alg := range supportedJWTAlgorithms {
+		methods = append(methods, alg)
+	}
+	return methods
+}
+
+// JWKS 未退役的非对称密钥的公钥集合，HS256 密钥不会公开
+func (m *JWTKeyManager) JWKS() models.JWKS {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	jwks := models.JWKS{Keys: []models.JWK{}}
+	for _, key := range m.keys {
+		if key.meta.Status == JWTKeyRetired {
+			continue
+		}
+		jwk := models.JWK{Kid: key.meta.ID, Alg: key.meta.Algorithm, Use: "sig"}
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// legacyJWTKeyID 由 JWT_SECRET 导入的密钥，也用于验证没有 kid 的旧令牌
+const legacyJWTKeyID = "jwt-secret"
+
+func generateJWTKey(algorithm string, now time.Time) (models.JWTKey, error) {
+	kid, err := randomHex(8)
+	if err != nil {
+		return models.JWTKey{}, err
+	}
+	key := models.JWTKey{
+		ID:        kid,
+		Algorithm: algorithm,
+		Status:    JWTKeyActive,
+		CreatedAt: now,
+	}
+
+	var private crypto.PrivateKey
+	switch algorithm {
+	case "HS256":
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return key, err
+		}
+		key.Secret = base64.StdEncoding.EncodeToString(secret)
+		return key, nil
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return key, fmt.Errorf("不支持的签名算法: %s", algorithm)
+	}
+	if err != nil {
+		return key, fmt.Errorf("生成签名密钥失败: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return key, err
+	}
+	key.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	return key, nil
+}
+
+func parseJWTKey(meta models.JWTKey) (*parsedJWTKey, error) {
+	method, ok := supportedJWTAlgorithms[meta.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的签名算法: %s", meta.Algorithm)
+	}
+	parsed := &parsedJWTKey{meta: meta, method: method}
+
+	if meta.Algorithm == "HS256" {
+		secret, err := base64.StdEncoding.DecodeString(meta.Secret)
+		if err != nil || len(secret) == 0 {
+			return nil, errors.New("无效的 HMAC 密钥")
+		}
+		parsed.signKey = secret
+		parsed.verifyKey = secret
+		return parsed, nil
+	}
+
+	block, _ := pem.Decode([]byte(meta.PrivateKey))
+	if block == nil {
+		return nil, errors.New("无效的 PEM 私钥")
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		if meta.Algorithm != "RS256" {
+			return nil, errors.New("私钥类型与算法不匹配")
+		}
+		parsed.signKey = key
+		parsed.verifyKey = &key.PublicKey
+	case ed25519.PrivateKey:
+		if meta.Algorithm != "EdDSA" {
+			return nil, errors.New("私钥类型与算法不匹配")
+		}
+		parsed.signKey = key
+		parsed.verifyKey = key.Public()
+	default:
+		return nil, errors.New("不支持的私钥类型")
+	}
+	return parsed, nil
+}
+
+// fileJWTKeyStore 本地文件存储，适用于单副本部署
+type fileJWTKeyStore struct {
+	path string
+}
+
+func (s *fileJWTKeyStore) describe() string {
+	return "文件 " + s.path
+}
+
+func (s *fileJWTKeyStore) load() (*jwtKeySet, string, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &jwtKeySet{}, "", nil
+		}
+		return nil, "", fmt.Errorf("读取JWT密钥文件失败: %v", err)
+	}
+
+	var set jwtKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, "", fmt.Errorf("解析JWT密钥文件失败: %v", err)
+	}
+	return &set, "", nil
+}
+
+func (s *fileJWTKeyStore) save(set *jwtKeySet, _ string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return "", fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化JWT密钥失败: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.path, data, 0600); err != nil {
+		return "", fmt.Errorf("写入JWT密钥文件失败: %v", err)
+	}
+	return "", nil
+}
+
+// secretJWTKeyStore Kubernetes Secret 存储，多副本共享同一个密钥集，
+// 使用 resourceVersion 避免多个副本同时轮换
+type secretJWTKeyStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+func (s *secretJWTKeyStore) describe() string {
+	return fmt.Sprintf("Secret %s/%s", s.namespace, s.name)
+}
+
+func (s *secretJWTKeyStore) load() (*jwtKeySet, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	secret, err := s.client.CoreV1().Secrets(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &jwtKeySet{}, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("读取JWT密钥 Secret 失败: %v", err)
+	}
+
+	set := &jwtKeySet{}
+	if data := secret.Data[jwtKeySecretKey]; len(data) > 0 {
+		if err := json.Unmarshal(data, set); err != nil {
+			return nil, "", fmt.Errorf("解析JWT密钥 Secret 失败: %v", err)
+		}
+	}
+	return set, secret.ResourceVersion, nil
+}
+
+func (s *secretJWTKeyStore) save(set *jwtKeySet, version string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		return "", fmt.Errorf("序列化JWT密钥失败: %v", err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            s.name,
+			Namespace:       s.namespace,
+			ResourceVersion: version,
+			Labels:          map[string]string{"app": "k8s-volume-snapshots"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{jwtKeySecretKey: data},
+	}
+
+	var saved *corev1.Secret
+	if version == "" {
+		saved, err = s.client.CoreV1().Secrets(s.namespace).Create(ctx, secret, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return "", errJWTKeySetConflict
+		}
+	} else {
+		saved, err = s.client.CoreV1().Secrets(s.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			return "", errJWTKeySetConflict
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("保存JWT密钥 Secret 失败: %v", err)
+	}
+	return saved.ResourceVersion, nil
+}
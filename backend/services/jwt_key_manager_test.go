@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memoryJWTKeyStore 带版本号的内存存储，版本不一致时返回冲突，模拟 Secret 的乐观锁
+type memoryJWTKeyStore struct {
+	mutex   sync.Mutex
+	set     *jwtKeySet
+	version int
+}
+
+func (s *memoryJWTKeyStore) describe() string { return "memory" }
+
+func (s *memoryJWTKeyStore) load() (*jwtKeySet, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.set == nil {
+		return &jwtKeySet{}, "", nil
+	}
+	return &jwtKeySet{Keys: append(s.set.Keys[:0:0], s.set.Keys...)}, fmt.Sprint(s.version), nil
+}
+
+func (s *memoryJWTKeyStore) save(set *jwtKeySet, version string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	current := ""
+	if s.set != nil {
+		current = fmt.Sprint(s.version)
+	}
+	if version != current {
+		return "", errJWTKeySetConflict
+	}
+	s.set = &jwtKeySet{Keys: append(set.Keys[:0:0], set.Keys...)}
+	s.version++
+	return fmt.Sprint(s.version), nil
+}
+
+func newTestJWTKeyManager(t *testing.T, store jwtKeyStore) *JWTKeyManager {
+	t.Setenv("JWT_SECRET", "")
+	manager := &JWTKeyManager{
+		store:       store,
+		algorithm:   "HS256",
+		retireAfter: DefaultJWTKeyRetireAfter,
+		keys:        make(map[string]*parsedJWTKey),
+	}
+	if err := manager.reload(); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func verifyWith(manager *JWTKeyManager, token string) error {
+	_, err := jwt.Parse(token, manager.Keyfunc, jwt.WithValidMethods(manager.ValidMethods()))
+	return err
+}
+
+func TestJWTKeyManagerAdoptsKeySetOnConflict(t *testing.T) {
+	store := &memoryJWTKeyStore{}
+	// 两个副本同时启动，都看到空的密钥集
+	first := newTestJWTKeyManager(t, store)
+	second := newTestJWTKeyManager(t, store)
+
+	if err := first.maintain(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.maintain(false); err != nil {
+		t.Fatal(err)
+	}
+	if second.activeKid == "" || second.activeKid != first.activeKid {
+		t.Fatalf("冲突的副本应采用已保存的密钥: %q vs %q", second.activeKid, first.activeKid)
+	}
+
+	token, err := second.Sign(jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyWith(first, token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJWTKeyManagerReloadsOnUnknownKid(t *testing.T) {
+	store := &memoryJWTKeyStore{}
+	first := newTestJWTKeyManager(t, store)
+	if err := first.maintain(false); err != nil {
+		t.Fatal(err)
+	}
+	second := newTestJWTKeyManager(t, store)
+
+	// 第一个副本轮换后签发的令牌，第二个副本立即可以验证
+	if err := first.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	token, err := first.Sign(jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyWith(second, token); err != nil {
+		t.Fatalf("未知 kid 应触发重新加载: %v", err)
+	}
+
+	// 间隔内的未知 kid 不再访问存储
+	if err := first.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	token, err = first.Sign(jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyWith(second, token); err == nil {
+		t.Fatal("重新加载应受频率限制")
+	}
+	second.lastMissReload = time.Now().Add(-jwtKeyMissReloadInterval)
+	if err := verifyWith(second, token); err != nil {
+		t.Fatal(err)
+	}
+}
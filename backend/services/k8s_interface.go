@@ -21,17 +21,40 @@ type K8sServiceInterface interface {
 	
 	// VolumeSnapshot 相关方法
 	GetVolumeSnapshots(ctx context.Context, namespace string) ([]snapshotv1.VolumeSnapshot, error)
+	GetVolumeSnapshotsBySelector(ctx context.Context, namespace, labelSelector string) ([]snapshotv1.VolumeSnapshot, error)
+	GetVolumeSnapshotInfos(ctx context.Context, opts models.VolumeSnapshotListOptions) (*models.VolumeSnapshotList, error)
 	CreateVolumeSnapshot(ctx context.Context, namespace string, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error)
+	CreateVolumeSnapshotWithHooks(ctx context.Context, namespace string, vs *snapshotv1.VolumeSnapshot, hooks *models.SnapshotHooks) (*snapshotv1.VolumeSnapshot, []models.HookResult, error)
 	GetVolumeSnapshot(ctx context.Context, namespace, name string) (*snapshotv1.VolumeSnapshot, error)
 	DeleteVolumeSnapshot(ctx context.Context, namespace, name string) error
 	ForceDeleteVolumeSnapshot(ctx context.Context, namespace, name string) error
+	RestoreVolumeSnapshot(ctx context.Context, namespace, name string, req models.RestoreVolumeSnapshotRequest) (*corev1.PersistentVolumeClaim, error)
+	RollbackToVolumeSnapshot(ctx context.Context, info *models.VolumeSnapshotInfo, requestedBy string, report ProgressFunc) error
+	
+	// 快照组相关方法
+	CreateGroupSnapshot(ctx context.Context, req models.CreateGroupSnapshotRequest) (*models.GroupSnapshotInfo, []models.HookResult, error)
+	GetGroupSnapshots(ctx context.Context, namespace string) ([]models.GroupSnapshotInfo, error)
+	GetGroupSnapshot(ctx context.Context, namespace, name string) (*models.GroupSnapshotInfo, error)
+	DeleteGroupSnapshot(ctx context.Context, namespace, name string) error
+	
+	// 命名空间备份相关方法
+	ExportNamespaceResources(ctx context.Context, namespace string, kinds []string) ([]models.BundleResource, error)
+	RetainBackupSnapshot(ctx context.Context, namespace, name string) (*models.BundleSnapshot, error)
+	DeleteBackupSnapshot(ctx context.Context, namespace string, snapshot models.BundleSnapshot) error
+	RestoreNamespaceBundle(ctx context.Context, bundle *models.NamespaceBackupBundle, targetNamespace string, report ProgressFunc) error
+	
+	// 快照数据导出相关方法
+	ExportSnapshotData(ctx context.Context, spec DataMoverSpec, snapshotName string, req models.ExportSnapshotRequest, report ProgressFunc) (*models.ExportArtifact, error)
+	ForgetExportedData(ctx context.Context, spec DataMoverSpec, resticSnapshotID string, report ProgressFunc) error
 	
 	// VolumeSnapshotContent 相关方法
 	GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error)
 	
 	// PVC 相关方法
 	GetPVCs(ctx context.Context, namespace string) ([]corev1.PersistentVolumeClaim, error)
-	GetPVCsWithPVInfo(ctx context.Context, namespace string) ([]models.PVCWithPVInfo, error)
+	GetPVC(ctx context.Context, namespace, name string) (*corev1.PersistentVolumeClaim, error)
+	GetPVCsWithPVInfo(ctx context.Context, opts models.PVCListOptions) (*models.PVCList, error)
+	ClonePVC(ctx context.Context, namespace, name string, req models.ClonePVCRequest) (*corev1.PersistentVolumeClaim, error)
 	
 	// Namespace 相关方法
 	GetNamespaces(ctx context.Context) ([]corev1.Namespace, error)
@@ -43,10 +66,14 @@ type MultiClusterK8sServiceInterface interface {
 	
 	// 多集群管理方法
 	GetClusters() ([]*models.ClusterInfo, error)
-	GetCurrentCluster() string
-	SwitchCluster(clusterName string) error
+	GetCurrentCluster(ctx context.Context) string
+	GetDefaultCluster() string
+	ValidateCluster(clusterName string) error
 	
 	// 在指定集群中执行操作
 	CreateVolumeSnapshotInCluster(ctx context.Context, clusterName, namespace string, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error)
 	GetPVCsInCluster(ctx context.Context, clusterName, namespace string) ([]corev1.PersistentVolumeClaim, error)
+	GetVolumeSnapshotInCluster(ctx context.Context, clusterName, namespace, name string) (*snapshotv1.VolumeSnapshot, error)
+	GetVolumeSnapshotsInCluster(ctx context.Context, clusterName, namespace, labelSelector string) ([]snapshotv1.VolumeSnapshot, error)
+	DeleteVolumeSnapshotInCluster(ctx context.Context, clusterName, namespace, name string) error
 }
\ No newline at end of file
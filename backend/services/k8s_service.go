@@ -116,11 +116,67 @@ func (k *K8sService) GetVolumeSnapshots(ctx context.Context, namespace string) (
 	return vsList.Items, nil
 }
 
+// GetVolumeSnapshotsBySelector 按标签选择器获取 VolumeSnapshot 列表
+func (k *K8sService) GetVolumeSnapshotsBySelector(ctx context.Context, namespace, labelSelector string) ([]snapshotv1.VolumeSnapshot, error) {
+	vsList, err := k.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+	return vsList.Items, nil
+}
+
+// GetVolumeSnapshotInfos 按条件过滤、排序、分页获取快照，并附带绑定的 VolumeSnapshotContent 和源 PVC
+func (k *K8sService) GetVolumeSnapshotInfos(ctx context.Context, opts models.VolumeSnapshotListOptions) (*models.VolumeSnapshotList, error) {
+	namespace := opts.Namespace
+	if namespace == "all" {
+		namespace = ""
+	}
+
+	selector, err := volumeSnapshotSelector(opts)
+	if err != nil {
+		return nil, err
+	}
+	vsList, err := k.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	// 一次性获取 VolumeSnapshotContent 和 PVC，避免逐个快照请求
+	vscList, err := k.SnapshotClientSet.SnapshotV1().VolumeSnapshotContents().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string]*snapshotv1.VolumeSnapshotContent, len(vscList.Items))
+	for i := range vscList.Items {
+		contents[vscList.Items[i].Name] = &vscList.Items[i]
+	}
+
+	pvcList, err := k.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvcs := make(map[string]*corev1.PersistentVolumeClaim, len(pvcList.Items))
+	for i := range pvcList.Items {
+		pvcs[pvcList.Items[i].Namespace+"/"+pvcList.Items[i].Name] = &pvcList.Items[i]
+	}
+
+	return pageVolumeSnapshotInfos(vsList.Items, opts, func(name string) *snapshotv1.VolumeSnapshotContent {
+		return contents[name]
+	}, func(namespace, name string) *corev1.PersistentVolumeClaim {
+		return pvcs[namespace+"/"+name]
+	})
+}
+
 // CreateVolumeSnapshot 创建 VolumeSnapshot
 func (k *K8sService) CreateVolumeSnapshot(ctx context.Context, namespace string, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error) {
 	return k.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Create(ctx, vs, metav1.CreateOptions{})
 }
 
+// CreateVolumeSnapshotWithHooks 执行 pre/post 钩子并创建 VolumeSnapshot
+func (k *K8sService) CreateVolumeSnapshotWithHooks(ctx context.Context, namespace string, vs *snapshotv1.VolumeSnapshot, hooks *models.SnapshotHooks) (*snapshotv1.VolumeSnapshot, []models.HookResult, error) {
+	return createVolumeSnapshotWithHooks(ctx, k.Config, k.ClientSet, k.SnapshotClientSet, namespace, vs, hooks)
+}
+
 // GetVolumeSnapshot 获取单个 VolumeSnapshot
 func (k *K8sService) GetVolumeSnapshot(ctx context.Context, namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
 	return k.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
@@ -158,6 +214,66 @@ func (k *K8sService) ForceDeleteVolumeSnapshot(ctx context.Context, namespace, n
 	return nil
 }
 
+// RestoreVolumeSnapshot 从快照恢复出新的 PVC
+func (k *K8sService) RestoreVolumeSnapshot(ctx context.Context, namespace, name string, req models.RestoreVolumeSnapshotRequest) (*corev1.PersistentVolumeClaim, error) {
+	return restoreVolumeSnapshot(ctx, k.ClientSet, k.SnapshotClientSet, namespace, name, req)
+}
+
+// RollbackToVolumeSnapshot 将快照的源 PVC 原地回滚到该快照
+func (k *K8sService) RollbackToVolumeSnapshot(ctx context.Context, info *models.VolumeSnapshotInfo, requestedBy string, report ProgressFunc) error {
+	return rollbackToVolumeSnapshot(ctx, k.ClientSet, k.SnapshotClientSet, info, requestedBy, report)
+}
+
+// CreateGroupSnapshot 创建快照组
+func (k *K8sService) CreateGroupSnapshot(ctx context.Context, req models.CreateGroupSnapshotRequest) (*models.GroupSnapshotInfo, []models.HookResult, error) {
+	return createGroupSnapshot(ctx, k.Config, k.ClientSet, k.SnapshotClientSet, req)
+}
+
+// GetGroupSnapshots 获取命名空间中的快照组列表
+func (k *K8sService) GetGroupSnapshots(ctx context.Context, namespace string) ([]models.GroupSnapshotInfo, error) {
+	return listGroupSnapshots(ctx, k.ClientSet, k.SnapshotClientSet, namespace)
+}
+
+// GetGroupSnapshot 获取单个快照组
+func (k *K8sService) GetGroupSnapshot(ctx context.Context, namespace, name string) (*models.GroupSnapshotInfo, error) {
+	return getGroupSnapshot(ctx, k.ClientSet, k.SnapshotClientSet, namespace, name)
+}
+
+// DeleteGroupSnapshot 删除快照组
+func (k *K8sService) DeleteGroupSnapshot(ctx context.Context, namespace, name string) error {
+	return deleteGroupSnapshot(ctx, k.ClientSet, k.SnapshotClientSet, namespace, name)
+}
+
+// ExportNamespaceResources 导出命名空间中的资源清单
+func (k *K8sService) ExportNamespaceResources(ctx context.Context, namespace string, kinds []string) ([]models.BundleResource, error) {
+	return exportNamespaceResources(ctx, k.ClientSet, namespace, kinds)
+}
+
+// RetainBackupSnapshot 保留备份快照的底层存储快照
+func (k *K8sService) RetainBackupSnapshot(ctx context.Context, namespace, name string) (*models.BundleSnapshot, error) {
+	return retainBackupSnapshot(ctx, k.SnapshotClientSet, namespace, name)
+}
+
+// DeleteBackupSnapshot 删除备份快照及其底层存储快照
+func (k *K8sService) DeleteBackupSnapshot(ctx context.Context, namespace string, snapshot models.BundleSnapshot) error {
+	return deleteBackupSnapshot(ctx, k.SnapshotClientSet, namespace, snapshot)
+}
+
+// RestoreNamespaceBundle 从备份包恢复命名空间
+func (k *K8sService) RestoreNamespaceBundle(ctx context.Context, bundle *models.NamespaceBackupBundle, targetNamespace string, report ProgressFunc) error {
+	return restoreNamespaceBundle(ctx, k.ClientSet, k.SnapshotClientSet, bundle, targetNamespace, report)
+}
+
+// ExportSnapshotData 将快照数据导出到对象存储
+func (k *K8sService) ExportSnapshotData(ctx context.Context, spec DataMoverSpec, snapshotName string, req models.ExportSnapshotRequest, report ProgressFunc) (*models.ExportArtifact, error) {
+	return exportSnapshotData(ctx, k.ClientSet, k.SnapshotClientSet, spec, snapshotName, req, report)
+}
+
+// ForgetExportedData 从对象存储中删除导出的数据
+func (k *K8sService) ForgetExportedData(ctx context.Context, spec DataMoverSpec, resticSnapshotID string, report ProgressFunc) error {
+	return forgetExportedData(ctx, k.ClientSet, spec, resticSnapshotID, report)
+}
+
 // GetVolumeSnapshotContent 获取 VolumeSnapshotContent
 func (k *K8sService) GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error) {
 	return k.SnapshotClientSet.SnapshotV1().VolumeSnapshotContents().Get(ctx, name, metav1.GetOptions{})
@@ -185,6 +301,11 @@ func (k *K8sService) GetPVCs(ctx context.Context, namespace string) ([]corev1.Pe
 	return pvcList.Items, nil
 }
 
+// GetPVC 获取单个 PVC
+func (k *K8sService) GetPVC(ctx context.Context, namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	return k.ClientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
 // refreshPVCache 刷新PV缓存
 func (k *K8sService) refreshPVCache(ctx context.Context) error {
 	// 缓存有效期5分钟
@@ -224,8 +345,9 @@ func (k *K8sService) getPVFromCache(pvName string) *corev1.PersistentVolume {
 	return k.pvCache[pvName]
 }
 
-// GetPVCsWithPVInfo 获取包含PV详细信息的PVC列表（优化版本）
-func (k *K8sService) GetPVCsWithPVInfo(ctx context.Context, namespace string) ([]models.PVCWithPVInfo, error) {
+// GetPVCsWithPVInfo 按条件过滤、排序、分页获取包含PV详细信息的PVC列表
+func (k *K8sService) GetPVCsWithPVInfo(ctx context.Context, opts models.PVCListOptions) (*models.PVCList, error) {
+	namespace := opts.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
@@ -236,50 +358,26 @@ func (k *K8sService) GetPVCsWithPVInfo(ctx context.Context, namespace string) ([
 		// 在生产环境中应该使用适当的日志记录
 	}
 
-	var pvcList *corev1.PersistentVolumeClaimList
-	var err error
-
 	// 支持查询所有命名空间
 	if namespace == "all" {
-		pvcList, err = k.ClientSet.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		pvcList, err = k.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
+		namespace = ""
 	}
 
-	result := make([]models.PVCWithPVInfo, 0, len(pvcList.Items))
-
-	for _, pvc := range pvcList.Items {
-		pvcWithPV := models.PVCWithPVInfo{
-			PVC: pvc,
-		}
-
-		// 如果PVC绑定了PV，从缓存获取PV的VolumeAttributes信息
-		if pvc.Spec.VolumeName != "" {
-			pv := k.getPVFromCache(pvc.Spec.VolumeName)
-			if pv != nil && pv.Spec.CSI != nil && pv.Spec.CSI.VolumeAttributes != nil {
-				pvcWithPV.VolumeAttributes = &models.PVVolumeAttributes{
-					ImageName: pv.Spec.CSI.VolumeAttributes["imageName"],
-					Pool:      pv.Spec.CSI.VolumeAttributes["pool"],
-				}
-			} else {
-				// 设置空的VolumeAttributes来确保字段存在
-				pvcWithPV.VolumeAttributes = &models.PVVolumeAttributes{}
-			}
-		} else {
-			// 如果没有绑定PV，设置空的VolumeAttributes
-			pvcWithPV.VolumeAttributes = &models.PVVolumeAttributes{}
-		}
-
-		result = append(result, pvcWithPV)
+	selector, err := pvcSelector(opts)
+	if err != nil {
+		return nil, err
 	}
+	pvcList, err := k.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	return pagePVCsWithPVInfo(pvcList.Items, opts, k.getPVFromCache)
+}
 
-	return result, nil
+// ClonePVC 以现有 PVC 为数据源克隆新的 PVC
+func (k *K8sService) ClonePVC(ctx context.Context, namespace, name string, req models.ClonePVCRequest) (*corev1.PersistentVolumeClaim, error) {
+	return clonePVC(ctx, k.ClientSet, namespace, name, req)
 }
 
 // GetNamespaces 获取所有命名空间
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"k8s-volume-snapshots/models"
+)
+
+// LDAPConfig LDAP / Active Directory 认证配置，从环境变量读取
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	CAFile             string
+	Timeout            time.Duration
+
+	// 用于查找用户和组的服务账号，为空时匿名绑定
+	BindDN       string
+	BindPassword string
+
+	// UserFilter 中的 {username} 会被替换为转义后的登录名
+	UserBaseDN        string
+	UserFilter        string
+	UsernameAttribute string
+	EmailAttribute    string
+
+	// GroupBaseDN 为空时从用户条目的 memberOf 属性读取组；
+	// GroupFilter 中的 {dn}、{username} 会被替换为用户 DN 和登录名
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupNameAttribute string
+
+	RoleMapping RoleMapping
+}
+
+// LDAPAuthProvider 先用服务账号搜索用户，再以用户 DN 和密码绑定验证
+type LDAPAuthProvider struct {
+	config    LDAPConfig
+	tlsConfig *tls.Config
+}
+
+// NewLDAPAuthProvider 根据环境变量创建 LDAP 认证后端，未配置 LDAP_URL 时返回 nil
+func NewLDAPAuthProvider() (*LDAPAuthProvider, error) {
+	config := LDAPConfig{
+		URL:                os.Getenv("LDAP_URL"),
+		StartTLS:           os.Getenv("LDAP_START_TLS") == "true",
+		InsecureSkipVerify: os.Getenv("LDAP_INSECURE_SKIP_VERIFY") == "true",
+		CAFile:             os.Getenv("LDAP_CA_FILE"),
+		BindDN:             os.Getenv("LDAP_BIND_DN"),
+		BindPassword:       os.Getenv("LDAP_BIND_PASSWORD"),
+		UserBaseDN:         os.Getenv("LDAP_USER_BASE_DN"),
+		UserFilter:         envOrDefault("LDAP_USER_FILTER", "(uid={username})"),
+		UsernameAttribute:  envOrDefault("LDAP_USERNAME_ATTRIBUTE", "uid"),
+		EmailAttribute:     envOrDefault("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		GroupBaseDN:        os.Getenv("LDAP_GROUP_BASE_DN"),
+		GroupFilter:        envOrDefault("LDAP_GROUP_FILTER", "(member={dn})"),
+		GroupNameAttribute: envOrDefault("LDAP_GROUP_NAME_ATTRIBUTE", "cn"),
+		Timeout:            10 * time.Second,
+	}
+
+	if config.URL == "" {
+		return nil, nil
+	}
+	if config.UserBaseDN == "" {
+		return nil, errors.New("LDAP_USER_BASE_DN 不能为空")
+	}
+	if !strings.Contains(config.UserFilter, "{username}") {
+		return nil, errors.New("LDAP_USER_FILTER 必须包含 {username}")
+	}
+	if timeout := os.Getenv("LDAP_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("无效的 LDAP_TIMEOUT: %s", timeout)
+		}
+		config.Timeout = d
+	}
+
+	mapping, err := roleMappingFromEnv("LDAP")
+	if err != nil {
+		return nil, err
+	}
+	config.RoleMapping = mapping
+
+	tlsConfig, err := ldapTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LDAPAuthProvider{config: config, tlsConfig: tlsConfig}, nil
+}
+
+func ldapTLSConfig(config LDAPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	parsed, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 LDAP_URL: %v", err)
+	}
+	tlsConfig.ServerName = parsed.Hostname()
+
+	if config.CAFile != "" {
+		data, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 LDAP_CA_FILE 失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("LDAP_CA_FILE 中没有有效的证书: %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func (p *LDAPAuthProvider) Name() string {
+	return models.AuthSourceLDAP
+}
+
+// Authenticate 搜索用户 DN 并以其密码绑定，成功后查询所属组并映射角色
+func (p *LDAPAuthProvider) Authenticate(username, password string) (*ExternalIdentity, error) {
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := p.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := p.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP 认证失败: %v", err)
+	}
+
+	// 以服务账号身份查询组，普通用户通常没有读取组的权限
+	if err := p.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+
+	groups, err := p.findGroups(conn, entry, username)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{
+		Subject:  entry.DN,
+		Username: entry.GetAttributeValue(p.config.UsernameAttribute),
+		Email:    entry.GetAttributeValue(p.config.EmailAttribute),
+		Groups:   groups,
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+	identity.Role = p.config.RoleMapping.Map(identity.Email, identity.Groups)
+	return identity, nil
+}
+
+func (p *LDAPAuthProvider) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(p.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.config.Timeout}),
+		ldap.DialWithTLSConfig(p.tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("连接 LDAP 服务器失败: %v", err)
+	}
+	conn.SetTimeout(p.config.Timeout)
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(p.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS 失败: %v", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (p *LDAPAuthProvider) bindServiceAccount(conn *ldap.Conn) error {
+	var err error
+	if p.config.BindDN == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(p.config.BindDN, p.config.BindPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("LDAP 服务账号绑定失败: %v", err)
+	}
+	return nil
+}
+
+// findUser 用户不存在时返回 ErrUnknownUser，匹配到多个条目视为配置错误
+func (p *LDAPAuthProvider) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(p.config.UserFilter, "{username}", ldap.EscapeFilter(username))
+	attributes := []string{p.config.UsernameAttribute, p.config.EmailAttribute}
+	if p.config.GroupBaseDN == "" {
+		attributes = append(attributes, "memberOf")
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.config.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(p.config.Timeout.Seconds()), false, filter, attributes, nil))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("LDAP 搜索用户失败: %v", err)
+	}
+
+	switch {
+	case result == nil || len(result.Entries) == 0:
+		return nil, ErrUnknownUser
+	case len(result.Entries) > 1:
+		return nil, fmt.Errorf("LDAP 中匹配到多个用户: %s", username)
+	}
+	return result.Entries[0], nil
+}
+
+func (p *LDAPAuthProvider) findGroups(conn *ldap.Conn, entry *ldap.Entry, username string) ([]string, error) {
+	if p.config.GroupBaseDN == "" {
+		var groups []string
+		for _, groupDN := range entry.GetAttributeValues("memberOf") {
+			if name := firstRDNValue(groupDN); name != "" {
+				groups = append(groups, name)
+			}
+		}
+		return groups, nil
+	}
+
+	filter := strings.NewReplacer(
+		"{dn}", ldap.EscapeFilter(entry.DN),
+		"{username}", ldap.EscapeFilter(username),
+	).Replace(p.config.GroupFilter)
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(p.config.Timeout.Seconds()), false, filter, []string{p.config.GroupNameAttribute}, nil))
+	if err != nil {
+		return nil, fmt.Errorf("LDAP 搜索用户组失败: %v", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, group := range result.Entries {
+		if name := group.GetAttributeValue(p.config.GroupNameAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// firstRDNValue 取 DN 第一个 RDN 的值，例如 cn=ops,ou=groups,dc=example,dc=com 返回 ops
+func firstRDNValue(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	ldapTestBindDN       = "cn=svc,dc=example,dc=com"
+	ldapTestBindPassword = "svc-secret"
+)
+
+// ldapTestEntry 测试目录中的条目，password 为空时不能绑定
+type ldapTestEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// ldapTestServer 进程内的 LDAP 服务器，只实现简单绑定和等值过滤的搜索；
+// 搜索要求以服务账号绑定，用于验证组查询前重新绑定了服务账号
+type ldapTestServer struct {
+	listener net.Listener
+	entries  []ldapTestEntry
+}
+
+func newLDAPTestServer(t *testing.T, entries []ldapTestEntry) *ldapTestServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &ldapTestServer{listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *ldapTestServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *ldapTestServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	boundDN := ""
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, _ := request.Children[1].Value.(string)
+			password := request.Children[2].Data.String()
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if entry := s.find(dn); entry != nil && entry.password != "" && entry.password == password {
+				code = ldap.LDAPResultSuccess
+				boundDN = dn
+			} else {
+				boundDN = ""
+			}
+			s.write(conn, messageID, ldapTestResult(ldap.ApplicationBindResponse, code))
+
+		case ldap.ApplicationSearchRequest:
+			if boundDN != ldapTestBindDN {
+				s.write(conn, messageID, ldapTestResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights))
+				continue
+			}
+			baseDN, _ := request.Children[0].Value.(string)
+			filter, err := ldap.DecompileFilter(request.Children[6])
+			if err != nil {
+				s.write(conn, messageID, ldapTestResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultOperationsError))
+				continue
+			}
+			for _, entry := range s.search(baseDN, filter) {
+				s.write(conn, messageID, ldapTestSearchEntry(entry))
+			}
+			s.write(conn, messageID, ldapTestResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *ldapTestServer) find(dn string) *ldapTestEntry {
+	for i := range s.entries {
+		if strings.EqualFold(s.entries[i].dn, dn) {
+			return &s.entries[i]
+		}
+	}
+	return nil
+}
+
+// search 只支持 (attr=value) 形式的等值过滤
+func (s *ldapTestServer) search(baseDN, filter string) []ldapTestEntry {
+	attribute, value, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(filter, "("), ")"), "=")
+	if !ok {
+		return nil
+	}
+
+	var result []ldapTestEntry
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.dn), ","+strings.ToLower(baseDN)) {
+			continue
+		}
+		for _, candidate := range entry.attributes[attribute] {
+			if strings.EqualFold(candidate, value) {
+				result = append(result, entry)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (s *ldapTestServer) write(w io.Writer, messageID int64, response *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	packet.AppendChild(response)
+	w.Write(packet.Bytes())
+}
+
+func ldapTestResult(tag ber.Tag, code uint16) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return packet
+}
+
+func ldapTestSearchEntry(entry ldapTestEntry) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	packet.AppendChild(attributes)
+	return packet
+}
+
+func newTestLDAPProvider(t *testing.T, groupBaseDN string) *LDAPAuthProvider {
+	server := newLDAPTestServer(t, []ldapTestEntry{
+		{dn: ldapTestBindDN, password: ldapTestBindPassword},
+		{dn: "uid=alice,ou=people,dc=example,dc=com", password: "alice-secret", attributes: map[string][]string{
+			"uid":      {"alice"},
+			"mail":     {"alice@example.com"},
+			"memberOf": {"cn=ops,ou=groups,dc=example,dc=com"},
+		}},
+		{dn: "uid=bob,ou=people,dc=example,dc=com", password: "bob-secret", attributes: map[string][]string{
+			"uid": {"bob"},
+		}},
+		{dn: "cn=ops,ou=groups,dc=example,dc=com", attributes: map[string][]string{
+			"cn":     {"ops"},
+			"member": {"uid=alice,ou=people,dc=example,dc=com"},
+		}},
+		{dn: "cn=viewers,ou=groups,dc=example,dc=com", attributes: map[string][]string{
+			"cn":     {"viewers"},
+			"member": {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+		}},
+	})
+
+	t.Setenv("LDAP_URL", server.URL())
+	t.Setenv("LDAP_BIND_DN", ldapTestBindDN)
+	t.Setenv("LDAP_BIND_PASSWORD", ldapTestBindPassword)
+	t.Setenv("LDAP_USER_BASE_DN", "ou=people,dc=example,dc=com")
+	t.Setenv("LDAP_GROUP_BASE_DN", groupBaseDN)
+	t.Setenv("LDAP_ADMIN_GROUPS", "ops")
+	t.Setenv("LDAP_READONLY_GROUPS", "viewers")
+	provider, err := NewLDAPAuthProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestLDAPAuthenticateMemberOf(t *testing.T) {
+	provider := newTestLDAPProvider(t, "")
+
+	identity, err := provider.Authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "uid=alice,ou=people,dc=example,dc=com" || identity.Username != "alice" ||
+		identity.Email != "alice@example.com" || identity.Role != "admin" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+	if len(identity.Groups) != 1 || identity.Groups[0] != "ops" {
+		t.Fatalf("unexpected groups: %v", identity.Groups)
+	}
+}
+
+func TestLDAPAuthenticateGroupSearch(t *testing.T) {
+	provider := newTestLDAPProvider(t, "ou=groups,dc=example,dc=com")
+
+	identity, err := provider.Authenticate("bob", "bob-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Role != "readonly" || len(identity.Groups) != 1 || identity.Groups[0] != "viewers" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+}
+
+func TestLDAPAuthenticateFailures(t *testing.T) {
+	provider := newTestLDAPProvider(t, "")
+
+	if _, err := provider.Authenticate("alice", "wrong"); !errors.Is(err, errInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	// 过滤器中的通配符需要转义，不能匹配任意用户
+	for _, username := range []string{"carol", "*", "alice)(uid=*"} {
+		if _, err := provider.Authenticate(username, "alice-secret"); !errors.Is(err, ErrUnknownUser) {
+			t.Fatalf("%q: expected unknown user, got %v", username, err)
+		}
+	}
+	// 空密码的简单绑定在很多服务器上等同于未认证绑定，必须被拒绝
+	if _, err := provider.Authenticate("alice", ""); err == nil {
+		t.Fatal("空密码应当失败")
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateListOptions 校验标签选择器、排序参数和 continue 令牌
+func ValidateListOptions(opts models.ListOptions) error {
+	if _, err := labels.Parse(opts.LabelSelector); err != nil {
+		return fmt.Errorf("无效的标签选择器: %v", err)
+	}
+	switch opts.SortBy {
+	case "", models.SortByAge, models.SortByName, models.SortBySize:
+	default:
+		return fmt.Errorf("不支持的排序字段: %s", opts.SortBy)
+	}
+	switch opts.Order {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("不支持的排序方向: %s", opts.Order)
+	}
+	if opts.Limit < 0 {
+		return fmt.Errorf("limit 不能为负数")
+	}
+	if _, err := decodeContinue(opts.Continue); err != nil {
+		return err
+	}
+	return nil
+}
+
+// volumeSnapshotSelector 将标签类过滤条件合并到标签选择器中，由 List 在缓存或 apiserver 侧过滤
+func volumeSnapshotSelector(opts models.VolumeSnapshotListOptions) (string, error) {
+	extra := map[string]string{}
+	if opts.CreatedBy != "" {
+		extra["created-by"] = CreatedByLabelValue(opts.CreatedBy)
+	}
+	if opts.ScheduledTaskID != "" {
+		extra["scheduled-task-id"] = opts.ScheduledTaskID
+	}
+	return mergeLabelSelector(opts.LabelSelector, extra)
+}
+
+// pvcSelector 将标签类过滤条件合并到标签选择器中
+func pvcSelector(opts models.PVCListOptions) (string, error) {
+	extra := map[string]string{}
+	if opts.CreatedBy != "" {
+		extra["created-by"] = CreatedByLabelValue(opts.CreatedBy)
+	}
+	return mergeLabelSelector(opts.LabelSelector, extra)
+}
+
+// CreatedByLabelValue 返回 created-by 标签的值
+// 外部账户的用户名可能是邮箱或 DOMAIN\user，包含标签值不允许的字符或超过 63 个字符，
+// 此时替换非法字符并附加用户名哈希；原始用户名保存在 k8s-volume-snapshots/created-by 注解中
+func CreatedByLabelValue(username string) string {
+	if len(validation.IsValidLabelValue(username)) == 0 {
+		return username
+	}
+
+	sum := sha256.Sum256([]byte(username))
+	suffix := hex.EncodeToString(sum[:])[:8]
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, username)
+	if maxLength := validation.LabelValueMaxLength - len(suffix) - 1; len(clean) > maxLength {
+		clean = clean[:maxLength]
+	}
+	clean = strings.Trim(clean, "-_.")
+	if clean == "" {
+		return suffix
+	}
+	return clean + "-" + suffix
+}
+
+func mergeLabelSelector(base string, extra map[string]string) (string, error) {
+	selector, err := labels.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range extra {
+		requirement, err := labels.NewRequirement(key, selection.Equals, []string{value})
+		if err != nil {
+			return "", err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector.String(), nil
+}
+
+// queryVolumeSnapshots 按非标签条件过滤、排序并分页，返回当前页、过滤后总数和下一页令牌
+func queryVolumeSnapshots(snapshots []snapshotv1.VolumeSnapshot, opts models.VolumeSnapshotListOptions) ([]snapshotv1.VolumeSnapshot, int, string, error) {
+	filtered := make([]snapshotv1.VolumeSnapshot, 0, len(snapshots))
+	for _, vs := range snapshots {
+		if !namespaceAllowed(vs.Namespace, opts.ListOptions) || !createdWithin(vs.CreationTimestamp, opts.ListOptions) {
+			continue
+		}
+		if opts.PVCName != "" && (vs.Spec.Source.PersistentVolumeClaimName == nil || *vs.Spec.Source.PersistentVolumeClaimName != opts.PVCName) {
+			continue
+		}
+		if opts.Ready != nil {
+			ready := vs.Status != nil && vs.Status.ReadyToUse != nil && *vs.Status.ReadyToUse
+			if ready != *opts.Ready {
+				continue
+			}
+		}
+		filtered = append(filtered, vs)
+	}
+
+	sortItems(len(filtered), opts.ListOptions, func(i int) (metav1.Time, string, resource.Quantity) {
+		vs := filtered[i]
+		var size resource.Quantity
+		if vs.Status != nil && vs.Status.RestoreSize != nil {
+			size = *vs.Status.RestoreSize
+		}
+		return vs.CreationTimestamp, vs.Namespace + "/" + vs.Name, size
+	}, func(i, j int) {
+		filtered[i], filtered[j] = filtered[j], filtered[i]
+	})
+
+	start, end, next, err := pageBounds(len(filtered), opts.ListOptions)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return filtered[start:end], len(filtered), next, nil
+}
+
+// queryPVCs 按非标签条件过滤、排序并分页
+func queryPVCs(pvcs []corev1.PersistentVolumeClaim, opts models.PVCListOptions) ([]corev1.PersistentVolumeClaim, int, string, error) {
+	filtered := make([]corev1.PersistentVolumeClaim, 0, len(pvcs))
+	for _, pvc := range pvcs {
+		if namespaceAllowed(pvc.Namespace, opts.ListOptions) && createdWithin(pvc.CreationTimestamp, opts.ListOptions) {
+			filtered = append(filtered, pvc)
+		}
+	}
+
+	sortItems(len(filtered), opts.ListOptions, func(i int) (metav1.Time, string, resource.Quantity) {
+		pvc := filtered[i]
+		size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+			size = capacity
+		}
+		return pvc.CreationTimestamp, pvc.Namespace + "/" + pvc.Name, size
+	}, func(i, j int) {
+		filtered[i], filtered[j] = filtered[j], filtered[i]
+	})
+
+	start, end, next, err := pageBounds(len(filtered), opts.ListOptions)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return filtered[start:end], len(filtered), next, nil
+}
+
+// pageVolumeSnapshotInfos 过滤、排序、分页后只为当前页附加 VolumeSnapshotContent 和源 PVC
+func pageVolumeSnapshotInfos(snapshots []snapshotv1.VolumeSnapshot, opts models.VolumeSnapshotListOptions, getContent func(name string) *snapshotv1.VolumeSnapshotContent, getPVC func(namespace, name string) *corev1.PersistentVolumeClaim) (*models.VolumeSnapshotList, error) {
+	page, total, next, err := queryVolumeSnapshots(snapshots, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &models.VolumeSnapshotList{
+		Items:    buildVolumeSnapshotInfos(page, getContent, getPVC),
+		Total:    total,
+		Continue: next,
+	}, nil
+}
+
+// pagePVCsWithPVInfo 过滤、排序、分页后只为当前页附加 PV 信息
+func pagePVCsWithPVInfo(pvcs []corev1.PersistentVolumeClaim, opts models.PVCListOptions, getPV func(name string) *corev1.PersistentVolume) (*models.PVCList, error) {
+	page, total, next, err := queryPVCs(pvcs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.PVCWithPVInfo, 0, len(page))
+	for _, pvc := range page {
+		var pv *corev1.PersistentVolume
+		if pvc.Spec.VolumeName != "" {
+			pv = getPV(pvc.Spec.VolumeName)
+		}
+		items = append(items, newPVCWithPVInfo(pvc, pv))
+	}
+
+	return &models.PVCList{
+		Items:    items,
+		Total:    total,
+		Continue: next,
+	}, nil
+}
+
+func namespaceAllowed(namespace string, opts models.ListOptions) bool {
+	return opts.AllowNamespace == nil || opts.AllowNamespace(namespace)
+}
+
+func createdWithin(created metav1.Time, opts models.ListOptions) bool {
+	if opts.CreatedAfter != nil && created.Time.Before(*opts.CreatedAfter) {
+		return false
+	}
+	if opts.CreatedBefore != nil && created.Time.After(*opts.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// sortItems 按排序字段排序，名称相同时保证顺序稳定
+// 默认顺序：age 从新到旧，size 从大到小，name 升序
+func sortItems(n int, opts models.ListOptions, key func(i int) (metav1.Time, string, resource.Quantity), swap func(i, j int)) {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = models.SortByAge
+	}
+	desc := sortBy != models.SortByName
+	if opts.Order != "" {
+		desc = opts.Order == "desc"
+	}
+
+	sort.Stable(sortable{n: n, swap: swap, less: func(i, j int) bool {
+		createdI, nameI, sizeI := key(i)
+		createdJ, nameJ, sizeJ := key(j)
+
+		var cmp int
+		switch sortBy {
+		case models.SortBySize:
+			cmp = sizeI.Cmp(sizeJ)
+		case models.SortByAge:
+			// age 按创建时间比较，desc 时最新的在前
+			cmp = compareTime(createdI.Time, createdJ.Time)
+		case models.SortByName:
+			cmp = strings.Compare(nameI, nameJ)
+		}
+		if cmp == 0 {
+			// 名称作为次要排序键始终升序
+			return nameI < nameJ
+		}
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	}})
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+type sortable struct {
+	n    int
+	less func(i, j int) bool
+	swap func(i, j int)
+}
+
+func (s sortable) Len() int           { return s.n }
+func (s sortable) Less(i, j int) bool { return s.less(i, j) }
+func (s sortable) Swap(i, j int)      { s.swap(i, j) }
+
+// pageBounds 根据 limit 和 continue 令牌计算当前页范围
+// 令牌记录下一页的起始偏移量；数据在两次请求之间变化时分页可能出现少量重复或遗漏
+func pageBounds(total int, opts models.ListOptions) (int, int, string, error) {
+	start, err := decodeContinue(opts.Continue)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if start > total {
+		start = total
+	}
+	if opts.Limit <= 0 {
+		return start, total, "", nil
+	}
+
+	end := start + opts.Limit
+	if end >= total {
+		return start, total, "", nil
+	}
+	return start, end, encodeContinue(end), nil
+}
+
+func encodeContinue(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeContinue(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("无效的 continue 令牌")
+	}
+	offset, err := strconv.Atoi(string(data))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("无效的 continue 令牌")
+	}
+	return offset, nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestCreatedByLabelValue(t *testing.T) {
+	if got := CreatedByLabelValue("alice"); got != "alice" {
+		t.Fatalf("合法的用户名应原样使用，实际 %q", got)
+	}
+
+	seen := map[string]string{}
+	for _, username := range []string{
+		"alice@example.com",
+		"alice_example.com",
+		`CORP\alice`,
+		"@@@",
+		strings.Repeat("a", 80) + "@example.com",
+		strings.Repeat("a", 80) + "@example.org",
+	} {
+		value := CreatedByLabelValue(username)
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			t.Fatalf("%q -> %q 不是合法的标签值: %v", username, value, errs)
+		}
+		if other, exists := seen[value]; exists {
+			t.Fatalf("%q 和 %q 映射到同一个标签值 %q", username, other, value)
+		}
+		seen[value] = username
+	}
+}
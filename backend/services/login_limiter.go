@@ -0,0 +1,315 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 同一用户名连续失败多少次后锁定
+	DefaultLoginMaxFailures = 5
+	// 同一来源 IP 连续失败多少次后锁定（可能在尝试多个用户名），IP 不做退避以免影响共享出口的其他用户
+	DefaultLoginIPMaxFailures = 20
+	// 锁定时长
+	DefaultLoginLockoutDuration = 15 * time.Minute
+	// 第二次失败后的等待时间，之后每次失败翻倍，最长不超过锁定时长
+	DefaultLoginBackoffBase = time.Second
+	// 清理过期记录的间隔
+	loginLimiterPruneInterval = time.Minute
+)
+
+var (
+	// ErrLoginThrottled 失败次数过多，需要等待后重试
+	ErrLoginThrottled = errors.New("登录失败次数过多，请稍后重试")
+	// ErrAccountLocked 账户或来源 IP 已被临时锁定
+	ErrAccountLocked = errors.New("登录失败次数过多，账户已被临时锁定，请稍后重试或联系管理员解锁")
+)
+
+// loginAttempts 某个用户名或 IP 的连续失败记录
+type loginAttempts struct {
+	failures    int
+	pending     int // 已通过检查、尚未得出结果的尝试
+	lastFailure time.Time
+	nextAllowed time.Time
+	lockedUntil time.Time
+}
+
+// LoginLimiter 按用户名和来源 IP 限制登录尝试：同一用户名连续失败时指数退避，
+// 用户名或 IP 失败次数超过阈值后临时锁定
+// 记录只保存在内存中，重启后清空
+type LoginLimiter struct {
+	maxFailures   int
+	ipMaxFailures int
+	lockout       time.Duration
+	backoffBase   time.Duration
+
+	mutex     sync.Mutex
+	users     map[string]*loginAttempts
+	ips       map[string]*loginAttempts
+	lastPrune time.Time
+}
+
+// NewLoginLimiter 根据环境变量创建登录限制器
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{
+		maxFailures:   envInt("LOGIN_MAX_FAILURES", DefaultLoginMaxFailures),
+		ipMaxFailures: envInt("LOGIN_IP_MAX_FAILURES", DefaultLoginIPMaxFailures),
+		lockout:       envDuration("LOGIN_LOCKOUT_DURATION", DefaultLoginLockoutDuration),
+		backoffBase:   envDuration("LOGIN_BACKOFF_BASE", DefaultLoginBackoffBase),
+		users:         make(map[string]*loginAttempts),
+		ips:           make(map[string]*loginAttempts),
+	}
+}
+
+// LoginAttempt 一次已预留的登录尝试，必须以 Fail、Succeed 或 Release 结束，重复调用无效
+type LoginAttempt struct {
+	limiter  *LoginLimiter
+	ip       string
+	username string
+	done     bool
+}
+
+// Reserve 登录前检查并预留一次尝试，被限制时返回需要等待的时长
+// 检查与预留在同一把锁内完成，并发请求中进行中的尝试也计入失败阈值，
+// 不会出现多个请求同时通过检查而绕过锁定的情况
+func (l *LoginLimiter) Reserve(ip, username string) (*LoginAttempt, time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.pruneLocked(now)
+
+	name := normalizeLoginName(username)
+	var wait time.Duration
+	var result error
+	for _, entry := range []struct {
+		attempts    *loginAttempts
+		maxFailures int
+	}{{l.ips[ip], l.ipMaxFailures}, {l.users[name], l.maxFailures}} {
+		attempts := entry.attempts
+		if attempts == nil {
+			continue
+		}
+		if now.Before(attempts.lockedUntil) {
+			if d := attempts.lockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+			result = ErrAccountLocked
+		} else if now.Before(attempts.nextAllowed) {
+			if d := attempts.nextAllowed.Sub(now); d > wait {
+				wait = d
+			}
+			if result == nil {
+				result = ErrLoginThrottled
+			}
+		} else if entry.maxFailures > 0 && l.activeFailures(attempts, now)+attempts.pending >= entry.maxFailures {
+			// 进行中的尝试全部失败就会触发锁定，等它们有结果后再试
+			if wait < l.backoffBase {
+				wait = l.backoffBase
+			}
+			if result == nil {
+				result = ErrLoginThrottled
+			}
+		}
+	}
+	if result != nil {
+		return nil, wait, result
+	}
+
+	l.entryLocked(l.users, name).pending++
+	l.entryLocked(l.ips, ip).pending++
+	return &LoginAttempt{limiter: l, ip: ip, username: name}, 0, nil
+}
+
+// Fail 记录一次失败的登录，用户名不存在时同样计数，避免通过锁定行为枚举用户
+func (a *LoginAttempt) Fail() {
+	l := a.limiter
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !a.finishLocked() {
+		return
+	}
+
+	now := time.Now()
+	if l.recordLocked(l.users, a.username, l.maxFailures, true, now) {
+		fmt.Printf("用户 %s 连续登录失败 %d 次，锁定 %v（来源 %s）\n", a.username, l.maxFailures, l.lockout, a.ip)
+	}
+	if l.recordLocked(l.ips, a.ip, l.ipMaxFailures, false, now) {
+		fmt.Printf("来源 %s 连续登录失败 %d 次，锁定 %v\n", a.ip, l.ipMaxFailures, l.lockout)
+	}
+}
+
+// Succeed 登录成功后清除该用户名的失败记录，IP 记录保留到自然过期
+func (a *LoginAttempt) Succeed() {
+	l := a.limiter
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !a.finishLocked() {
+		return
+	}
+
+	if attempts := l.users[a.username]; attempts != nil {
+		// 保留其他进行中的尝试
+		*attempts = loginAttempts{pending: attempts.pending}
+	}
+}
+
+// Release 结束尝试但不计入成功或失败（如服务不可用、需要第二步验证）
+func (a *LoginAttempt) Release() {
+	l := a.limiter
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	a.finishLocked()
+}
+
+// finishLocked 释放预留，已结束时返回 false
+func (a *LoginAttempt) finishLocked() bool {
+	if a.done {
+		return false
+	}
+	a.done = true
+	for _, attempts := range []*loginAttempts{a.limiter.users[a.username], a.limiter.ips[a.ip]} {
+		if attempts != nil && attempts.pending > 0 {
+			attempts.pending--
+		}
+	}
+	return true
+}
+
+// activeFailures 未过期的连续失败次数，超过锁定时长没有失败记录时重新计数
+func (l *LoginLimiter) activeFailures(attempts *loginAttempts, now time.Time) int {
+	if now.Sub(attempts.lastFailure) > l.lockout {
+		return 0
+	}
+	return attempts.failures
+}
+
+// entryLocked 获取或创建记录
+func (l *LoginLimiter) entryLocked(entries map[string]*loginAttempts, key string) *loginAttempts {
+	attempts, exists := entries[key]
+	if !exists {
+		attempts = &loginAttempts{}
+		entries[key] = attempts
+	}
+	return attempts
+}
+
+// recordLocked 累加失败次数并计算下次允许尝试的时间，达到阈值时返回 true
+func (l *LoginLimiter) recordLocked(entries map[string]*loginAttempts, key string, maxFailures int, backoff bool, now time.Time) bool {
+	attempts := l.entryLocked(entries, key)
+	// 超过锁定时长没有失败记录时重新计数
+	if now.Sub(attempts.lastFailure) > l.lockout {
+		*attempts = loginAttempts{pending: attempts.pending}
+	}
+
+	attempts.failures++
+	attempts.lastFailure = now
+
+	if maxFailures > 0 && attempts.failures >= maxFailures {
+		attempts.lockedUntil = now.Add(l.lockout)
+		attempts.failures = 0
+		return true
+	}
+
+	if backoff && attempts.failures > 1 {
+		wait := l.backoffBase << uint(attempts.failures-2)
+		if wait <= 0 || wait > l.lockout {
+			wait = l.lockout
+		}
+		attempts.nextAllowed = now.Add(wait)
+	}
+	return false
+}
+
+// Unlock 管理员解除用户名或 IP 的锁定
+func (l *LoginLimiter) Unlock(username, ip string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	_, userLocked := l.users[normalizeLoginName(username)]
+	_, ipLocked := l.ips[ip]
+	delete(l.users, normalizeLoginName(username))
+	delete(l.ips, ip)
+	return userLocked || ipLocked
+}
+
+// Lockouts 列出当前被锁定的用户名和 IP
+func (l *LoginLimiter) Lockouts() []models.LoginLockout {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	lockouts := []models.LoginLockout{}
+	collect := func(entries map[string]*loginAttempts, kind string) {
+		for key, attempts := range entries {
+			if now.Before(attempts.lockedUntil) {
+				lockouts = append(lockouts, models.LoginLockout{
+					Type:        kind,
+					Key:         key,
+					LockedUntil: attempts.lockedUntil,
+				})
+			}
+		}
+	}
+	collect(l.users, "username")
+	collect(l.ips, "ip")
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LockedUntil.After(lockouts[j].LockedUntil)
+	})
+	return lockouts
+}
+
+// pruneLocked 定期清理已经过期的记录，避免针对随机用户名的尝试占满内存
+func (l *LoginLimiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < loginLimiterPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for _, entries := range []map[string]*loginAttempts{l.users, l.ips} {
+		for key, attempts := range entries {
+			if attempts.pending == 0 && now.After(attempts.lockedUntil) && now.Sub(attempts.lastFailure) > l.lockout {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+func normalizeLoginName(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func envInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		fmt.Printf("警告: 无效的 %s %q，使用默认值 %d\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		fmt.Printf("警告: 无效的 %s %q，使用默认值 %v\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
@@ -16,7 +16,9 @@ import (
 	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -27,12 +29,14 @@ import (
 type MultiClusterK8sService struct {
 	config           *models.MultiClusterConfig
 	clusters         map[string]*ClusterClient
-	currentCluster   string
+	defaultCluster   string // 请求未指定集群且用户没有默认集群时使用
 	mutex            sync.RWMutex
 	configPath       string
 	clusterInfoCache map[string]*models.ClusterInfo
 	cacheMutex       sync.RWMutex
 	lastCacheUpdate  time.Time
+	// 资源变更事件，由各集群的 informer 发布
+	events *EventHub
 }
 
 type ClusterClient struct {
@@ -42,10 +46,8 @@ type ClusterClient struct {
 	ClusterInfo       *models.ClusterConfig
 	Status            string
 	LastCheck         time.Time
-	// PV缓存
-	pvCache      map[string]*corev1.PersistentVolume
-	pvCacheMutex sync.RWMutex
-	pvCacheTime  time.Time
+	// informer 缓存，集群初始化失败时为 nil
+	Cache *ClusterCache
 }
 
 func NewMultiClusterK8sService() (*MultiClusterK8sService, error) {
@@ -55,6 +57,7 @@ func NewMultiClusterK8sService() (*MultiClusterK8sService, error) {
 		clusters:         make(map[string]*ClusterClient),
 		configPath:       configPath,
 		clusterInfoCache: make(map[string]*models.ClusterInfo),
+		events:           NewEventHub(),
 	}
 
 	// 尝试加载配置文件
@@ -70,7 +73,7 @@ func NewMultiClusterK8sService() (*MultiClusterK8sService, error) {
 	}
 
 	// 设置默认当前集群
-	service.currentCluster = service.config.DefaultCluster
+	service.defaultCluster = service.config.DefaultCluster
 
 	return service, nil
 }
@@ -140,7 +143,7 @@ func (m *MultiClusterK8sService) createFallbackService() (*MultiClusterK8sServic
 	}
 
 	m.config = defaultConfig
-	m.currentCluster = "default"
+	m.defaultCluster = "default"
 
 	// 初始化默认集群
 	if err := m.initializeClusters(); err != nil {
@@ -164,7 +167,6 @@ func (m *MultiClusterK8sService) initializeClusters() error {
 				ClusterInfo: clusterConfig,
 				Status:      "error",
 				LastCheck:   time.Now(),
-				pvCache:     make(map[string]*corev1.PersistentVolume),
 			}
 		} else {
 			client.Status = "online"
@@ -230,26 +232,44 @@ func (m *MultiClusterK8sService) createClusterClient(clusterConfig *models.Clust
 		return nil, err
 	}
 
+	// 启动 informer 缓存，同步完成前读操作直接访问 API
+	clusterCache := NewClusterCache(clientSet, snapshotClientSet)
+	if err := clusterCache.AddEventHandlers(clusterConfig.Name, m.events.Publish); err != nil {
+		log.Printf("Failed to register event handlers for cluster %s: %v", clusterConfig.Name, err)
+	}
+	clusterCache.Start(clusterConfig.Name)
+
 	return &ClusterClient{
 		Config:            config,
 		ClientSet:         clientSet,
 		SnapshotClientSet: snapshotClientSet,
 		ClusterInfo:       clusterConfig,
-		pvCache:           make(map[string]*corev1.PersistentVolume),
+		Cache:             clusterCache,
 	}, nil
 }
 
-// GetCurrentCluster 获取当前集群名称
-func (m *MultiClusterK8sService) GetCurrentCluster() string {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-	return m.currentCluster
+// Events 返回资源变更事件中心
+func (m *MultiClusterK8sService) Events() *EventHub {
+	return m.events
+}
+
+// GetCurrentCluster 获取本次请求的目标集群名称（context 中未指定时为默认集群）
+func (m *MultiClusterK8sService) GetCurrentCluster(ctx context.Context) string {
+	if clusterName := ClusterFromContext(ctx); clusterName != "" {
+		return clusterName
+	}
+	return m.defaultCluster
+}
+
+// GetDefaultCluster 获取配置的默认集群名称
+func (m *MultiClusterK8sService) GetDefaultCluster() string {
+	return m.defaultCluster
 }
 
-// SwitchCluster 切换到指定集群
-func (m *MultiClusterK8sService) SwitchCluster(clusterName string) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+// ValidateCluster 检查集群是否存在且可用
+func (m *MultiClusterK8sService) ValidateCluster(clusterName string) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
 	if _, exists := m.clusters[clusterName]; !exists {
 		return fmt.Errorf("cluster %s not found", clusterName)
@@ -263,7 +283,6 @@ func (m *MultiClusterK8sService) SwitchCluster(clusterName string) error {
 		return fmt.Errorf("cluster %s is in error state", clusterName)
 	}
 
-	m.currentCluster = clusterName
 	return nil
 }
 
@@ -293,6 +312,7 @@ func (m *MultiClusterK8sService) GetClusters() ([]*models.ClusterInfo, error) {
 			Enabled:     client.ClusterInfo.Enabled,
 			Status:      status,
 			LastCheck:   client.LastCheck,
+			CacheSynced: client.Cache.CoreSynced() && client.Cache.SnapshotsSynced(),
 		}
 		clusters = append(clusters, info)
 	}
@@ -335,32 +355,37 @@ func (m *MultiClusterK8sService) checkClusterStatus(client *ClusterClient) strin
 	return "online"
 }
 
-// GetCurrentClient 获取当前集群的客户端
-func (m *MultiClusterK8sService) GetCurrentClient() (*ClusterClient, error) {
+// CachesSynced 所有集群的 informer 缓存是否已完成首次同步（成功或超时）
+func (m *MultiClusterK8sService) CachesSynced() bool {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	client, exists := m.clusters[m.currentCluster]
-	if !exists {
-		return nil, fmt.Errorf("current cluster %s not found", m.currentCluster)
-	}
-
-	if client.Status == "error" || client.ClientSet == nil {
-		return nil, fmt.Errorf("current cluster %s is not available", m.currentCluster)
+	for _, client := range m.clusters {
+		if !client.Cache.SyncDone() {
+			return false
+		}
 	}
+	return true
+}
 
-	return client, nil
+// GetCurrentClient 获取本次请求目标集群的客户端
+func (m *MultiClusterK8sService) GetCurrentClient(ctx context.Context) (*ClusterClient, error) {
+	return m.getClusterClient(m.GetCurrentCluster(ctx))
 }
 
-// 以下方法将当前集群的操作转发到具体的客户端
+// 以下方法将操作转发到 context 指定集群（未指定时为默认集群）的客户端
 // 这样可以保持与原有 K8sService 接口的兼容性
 
 func (m *MultiClusterK8sService) GetVolumeSnapshotClasses(ctx context.Context) ([]snapshotv1.VolumeSnapshotClass, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if client.Cache.SnapshotsSynced() {
+		return client.Cache.ListVolumeSnapshotClasses()
+	}
+
 	vscList, err := client.SnapshotClientSet.SnapshotV1().VolumeSnapshotClasses().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
@@ -369,11 +394,15 @@ func (m *MultiClusterK8sService) GetVolumeSnapshotClasses(ctx context.Context) (
 }
 
 func (m *MultiClusterK8sService) GetStorageClasses(ctx context.Context) ([]storagev1.StorageClass, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if client.Cache.CoreSynced() {
+		return client.Cache.ListStorageClasses()
+	}
+
 	scList, err := client.ClientSet.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
@@ -382,28 +411,61 @@ func (m *MultiClusterK8sService) GetStorageClasses(ctx context.Context) ([]stora
 }
 
 func (m *MultiClusterK8sService) GetVolumeSnapshots(ctx context.Context, namespace string) ([]snapshotv1.VolumeSnapshot, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if namespace == "" || namespace == "all" {
-		vsList, err := client.SnapshotClientSet.SnapshotV1().VolumeSnapshots("").List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
-		return vsList.Items, nil
+	if namespace == "all" {
+		namespace = ""
 	}
+	return client.listVolumeSnapshots(ctx, namespace, "")
+}
 
-	vsList, err := client.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).List(ctx, metav1.ListOptions{})
+// GetVolumeSnapshotInfos 按条件过滤、排序、分页获取当前集群的快照，并附带绑定的 VolumeSnapshotContent 和源 PVC
+func (m *MultiClusterK8sService) GetVolumeSnapshotInfos(ctx context.Context, opts models.VolumeSnapshotListOptions) (*models.VolumeSnapshotList, error) {
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return vsList.Items, nil
+
+	namespace := opts.Namespace
+	if namespace == "all" {
+		namespace = ""
+	}
+	selector, err := volumeSnapshotSelector(opts)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := client.listVolumeSnapshots(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	getContent, err := client.volumeSnapshotContentGetter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	getPVC, err := client.pvcGetter(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return pageVolumeSnapshotInfos(snapshots, opts, getContent, getPVC)
+}
+
+// GetVolumeSnapshotsBySelector 按标签选择器获取当前集群的VolumeSnapshot列表
+func (m *MultiClusterK8sService) GetVolumeSnapshotsBySelector(ctx context.Context, namespace, labelSelector string) ([]snapshotv1.VolumeSnapshot, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.listVolumeSnapshots(ctx, namespace, labelSelector)
 }
 
 func (m *MultiClusterK8sService) CreateVolumeSnapshot(ctx context.Context, namespace string, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -411,43 +473,82 @@ func (m *MultiClusterK8sService) CreateVolumeSnapshot(ctx context.Context, names
 	return client.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Create(ctx, vs, metav1.CreateOptions{})
 }
 
-// CreateVolumeSnapshotInCluster 在指定集群中创建VolumeSnapshot
-func (m *MultiClusterK8sService) CreateVolumeSnapshotInCluster(ctx context.Context, clusterName, namespace string, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error) {
+// CreateVolumeSnapshotWithHooks 在当前集群中执行 pre/post 钩子并创建VolumeSnapshot
+func (m *MultiClusterK8sService) CreateVolumeSnapshotWithHooks(ctx context.Context, namespace string, vs *snapshotv1.VolumeSnapshot, hooks *models.SnapshotHooks) (*snapshotv1.VolumeSnapshot, []models.HookResult, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return createVolumeSnapshotWithHooks(ctx, client.Config, client.ClientSet, client.SnapshotClientSet, namespace, vs, hooks)
+}
+
+// getClusterClient 获取指定集群的客户端
+func (m *MultiClusterK8sService) getClusterClient(clusterName string) (*ClusterClient, error) {
 	m.mutex.RLock()
 	client, exists := m.clusters[clusterName]
 	m.mutex.RUnlock()
-	
+
 	if !exists {
 		return nil, fmt.Errorf("cluster %s not found", clusterName)
 	}
-	
+
 	if !client.ClusterInfo.Enabled {
 		return nil, fmt.Errorf("cluster %s is disabled", clusterName)
 	}
-	
+
 	if client.Status == "error" || client.ClientSet == nil {
 		return nil, fmt.Errorf("cluster %s is not available", clusterName)
 	}
+
+	return client, nil
+}
+
+// CreateVolumeSnapshotInCluster 在指定集群中创建VolumeSnapshot
+func (m *MultiClusterK8sService) CreateVolumeSnapshotInCluster(ctx context.Context, clusterName, namespace string, vs *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error) {
+	client, err := m.getClusterClient(clusterName)
+	if err != nil {
+		return nil, err
+	}
 	
 	return client.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Create(ctx, vs, metav1.CreateOptions{})
 }
 
-// GetPVCsInCluster 获取指定集群中的PVC列表
-func (m *MultiClusterK8sService) GetPVCsInCluster(ctx context.Context, clusterName, namespace string) ([]corev1.PersistentVolumeClaim, error) {
-	m.mutex.RLock()
-	client, exists := m.clusters[clusterName]
-	m.mutex.RUnlock()
-	
-	if !exists {
-		return nil, fmt.Errorf("cluster %s not found", clusterName)
+// GetVolumeSnapshotInCluster 获取指定集群中的单个VolumeSnapshot
+func (m *MultiClusterK8sService) GetVolumeSnapshotInCluster(ctx context.Context, clusterName, namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
+	client, err := m.getClusterClient(clusterName)
+	if err != nil {
+		return nil, err
 	}
-	
-	if !client.ClusterInfo.Enabled {
-		return nil, fmt.Errorf("cluster %s is disabled", clusterName)
+
+	return client.getVolumeSnapshot(ctx, namespace, name)
+}
+
+// GetVolumeSnapshotsInCluster 按标签选择器获取指定集群中的VolumeSnapshot列表
+func (m *MultiClusterK8sService) GetVolumeSnapshotsInCluster(ctx context.Context, clusterName, namespace, labelSelector string) ([]snapshotv1.VolumeSnapshot, error) {
+	client, err := m.getClusterClient(clusterName)
+	if err != nil {
+		return nil, err
 	}
-	
-	if client.Status == "error" || client.ClientSet == nil {
-		return nil, fmt.Errorf("cluster %s is not available", clusterName)
+
+	return client.listVolumeSnapshots(ctx, namespace, labelSelector)
+}
+
+// DeleteVolumeSnapshotInCluster 删除指定集群中的VolumeSnapshot
+func (m *MultiClusterK8sService) DeleteVolumeSnapshotInCluster(ctx context.Context, clusterName, namespace, name string) error {
+	client, err := m.getClusterClient(clusterName)
+	if err != nil {
+		return err
+	}
+
+	return client.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// GetPVCsInCluster 获取指定集群中的PVC列表
+func (m *MultiClusterK8sService) GetPVCsInCluster(ctx context.Context, clusterName, namespace string) ([]corev1.PersistentVolumeClaim, error) {
+	client, err := m.getClusterClient(clusterName)
+	if err != nil {
+		return nil, err
 	}
 	
 	if namespace == "" {
@@ -456,31 +557,23 @@ func (m *MultiClusterK8sService) GetPVCsInCluster(ctx context.Context, clusterNa
 	
 	// 支持查询所有命名空间
 	if namespace == "all" {
-		pvcList, err := client.ClientSet.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
-		return pvcList.Items, nil
+		namespace = ""
 	}
 	
-	pvcList, err := client.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
-	return pvcList.Items, nil
+	return client.listPVCs(ctx, namespace, "")
 }
 
 func (m *MultiClusterK8sService) GetVolumeSnapshot(ctx context.Context, namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
+	return client.getVolumeSnapshot(ctx, namespace, name)
 }
 
 func (m *MultiClusterK8sService) DeleteVolumeSnapshot(ctx context.Context, namespace, name string) error {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return err
 	}
@@ -489,7 +582,7 @@ func (m *MultiClusterK8sService) DeleteVolumeSnapshot(ctx context.Context, names
 }
 
 func (m *MultiClusterK8sService) ForceDeleteVolumeSnapshot(ctx context.Context, namespace, name string) error {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return err
 	}
@@ -519,17 +612,143 @@ func (m *MultiClusterK8sService) ForceDeleteVolumeSnapshot(ctx context.Context,
 	return nil
 }
 
+// RestoreVolumeSnapshot 从快照恢复出新的 PVC
+func (m *MultiClusterK8sService) RestoreVolumeSnapshot(ctx context.Context, namespace, name string, req models.RestoreVolumeSnapshotRequest) (*corev1.PersistentVolumeClaim, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return restoreVolumeSnapshot(ctx, client.ClientSet, client.SnapshotClientSet, namespace, name, req)
+}
+
+// RollbackToVolumeSnapshot 将快照的源 PVC 原地回滚到该快照
+func (m *MultiClusterK8sService) RollbackToVolumeSnapshot(ctx context.Context, info *models.VolumeSnapshotInfo, requestedBy string, report ProgressFunc) error {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return rollbackToVolumeSnapshot(ctx, client.ClientSet, client.SnapshotClientSet, info, requestedBy, report)
+}
+
+// CreateGroupSnapshot 在当前集群中创建快照组
+func (m *MultiClusterK8sService) CreateGroupSnapshot(ctx context.Context, req models.CreateGroupSnapshotRequest) (*models.GroupSnapshotInfo, []models.HookResult, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return createGroupSnapshot(ctx, client.Config, client.ClientSet, client.SnapshotClientSet, req)
+}
+
+// GetGroupSnapshots 获取当前集群命名空间中的快照组列表
+func (m *MultiClusterK8sService) GetGroupSnapshots(ctx context.Context, namespace string) ([]models.GroupSnapshotInfo, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return listGroupSnapshots(ctx, client.ClientSet, client.SnapshotClientSet, namespace)
+}
+
+// GetGroupSnapshot 获取当前集群中的单个快照组
+func (m *MultiClusterK8sService) GetGroupSnapshot(ctx context.Context, namespace, name string) (*models.GroupSnapshotInfo, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return getGroupSnapshot(ctx, client.ClientSet, client.SnapshotClientSet, namespace, name)
+}
+
+// DeleteGroupSnapshot 删除当前集群中的快照组
+func (m *MultiClusterK8sService) DeleteGroupSnapshot(ctx context.Context, namespace, name string) error {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return deleteGroupSnapshot(ctx, client.ClientSet, client.SnapshotClientSet, namespace, name)
+}
+
+// ExportNamespaceResources 导出当前集群命名空间中的资源清单
+func (m *MultiClusterK8sService) ExportNamespaceResources(ctx context.Context, namespace string, kinds []string) ([]models.BundleResource, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return exportNamespaceResources(ctx, client.ClientSet, namespace, kinds)
+}
+
+// RetainBackupSnapshot 保留当前集群中备份快照的底层存储快照
+func (m *MultiClusterK8sService) RetainBackupSnapshot(ctx context.Context, namespace, name string) (*models.BundleSnapshot, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return retainBackupSnapshot(ctx, client.SnapshotClientSet, namespace, name)
+}
+
+// DeleteBackupSnapshot 删除当前集群中的备份快照及其底层存储快照
+func (m *MultiClusterK8sService) DeleteBackupSnapshot(ctx context.Context, namespace string, snapshot models.BundleSnapshot) error {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return deleteBackupSnapshot(ctx, client.SnapshotClientSet, namespace, snapshot)
+}
+
+// RestoreNamespaceBundle 在当前集群中从备份包恢复命名空间
+func (m *MultiClusterK8sService) RestoreNamespaceBundle(ctx context.Context, bundle *models.NamespaceBackupBundle, targetNamespace string, report ProgressFunc) error {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return restoreNamespaceBundle(ctx, client.ClientSet, client.SnapshotClientSet, bundle, targetNamespace, report)
+}
+
+// ExportSnapshotData 将当前集群中的快照数据导出到对象存储
+func (m *MultiClusterK8sService) ExportSnapshotData(ctx context.Context, spec DataMoverSpec, snapshotName string, req models.ExportSnapshotRequest, report ProgressFunc) (*models.ExportArtifact, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return exportSnapshotData(ctx, client.ClientSet, client.SnapshotClientSet, spec, snapshotName, req, report)
+}
+
+// ForgetExportedData 通过当前集群中的搬运 Job 从对象存储中删除导出的数据
+func (m *MultiClusterK8sService) ForgetExportedData(ctx context.Context, spec DataMoverSpec, resticSnapshotID string, report ProgressFunc) error {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	return forgetExportedData(ctx, client.ClientSet, spec, resticSnapshotID, report)
+}
+
 func (m *MultiClusterK8sService) GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if client.Cache.SnapshotsSynced() {
+		if vsc, err := client.Cache.GetVolumeSnapshotContent(name); err == nil {
+			return vsc, nil
+		}
+	}
+
 	return client.SnapshotClientSet.SnapshotV1().VolumeSnapshotContents().Get(ctx, name, metav1.GetOptions{})
 }
 
 func (m *MultiClusterK8sService) GetPVCs(ctx context.Context, namespace string) ([]corev1.PersistentVolumeClaim, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -540,86 +759,81 @@ func (m *MultiClusterK8sService) GetPVCs(ctx context.Context, namespace string)
 
 	// 支持查询所有命名空间
 	if namespace == "all" {
-		pvcList, err := client.ClientSet.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
-		return pvcList.Items, nil
+		namespace = ""
 	}
 
-	pvcList, err := client.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	return client.listPVCs(ctx, namespace, "")
+}
+
+func (m *MultiClusterK8sService) GetPVC(ctx context.Context, namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return pvcList.Items, nil
+
+	// 刚创建的 PVC 可能尚未进入缓存，缓存未命中时直接访问 API
+	if client.Cache.CoreSynced() {
+		if pvc, err := client.Cache.GetPVC(namespace, name); err == nil {
+			return pvc, nil
+		}
+	}
+
+	return client.ClientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
-func (m *MultiClusterK8sService) GetPVCsWithPVInfo(ctx context.Context, namespace string) ([]models.PVCWithPVInfo, error) {
-	client, err := m.GetCurrentClient()
+func (m *MultiClusterK8sService) GetPVCsWithPVInfo(ctx context.Context, opts models.PVCListOptions) (*models.PVCList, error) {
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	namespace := opts.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	// 先刷新PV缓存
-	if err := m.refreshPVCache(ctx, client); err != nil {
-		// 如果刷新缓存失败，记录错误但继续执行
-	}
-
-	var pvcList *corev1.PersistentVolumeClaimList
-
 	// 支持查询所有命名空间
 	if namespace == "all" {
-		pvcList, err = client.ClientSet.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		pvcList, err = client.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			return nil, err
-		}
+		namespace = ""
 	}
 
-	result := make([]models.PVCWithPVInfo, 0, len(pvcList.Items))
+	selector, err := pvcSelector(opts)
+	if err != nil {
+		return nil, err
+	}
+	pvcs, err := client.listPVCs(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, pvc := range pvcList.Items {
-		pvcWithPV := models.PVCWithPVInfo{
-			PVC: pvc,
-		}
+	getPV, err := client.pvGetter(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		// 如果PVC绑定了PV，从缓存获取PV的VolumeAttributes信息
-		if pvc.Spec.VolumeName != "" {
-			pv := m.getPVFromCache(client, pvc.Spec.VolumeName)
-			if pv != nil && pv.Spec.CSI != nil && pv.Spec.CSI.VolumeAttributes != nil {
-				pvcWithPV.VolumeAttributes = &models.PVVolumeAttributes{
-					ImageName: pv.Spec.CSI.VolumeAttributes["imageName"],
-					Pool:      pv.Spec.CSI.VolumeAttributes["pool"],
-				}
-			} else {
-				// 设置空的VolumeAttributes来确保字段存在
-				pvcWithPV.VolumeAttributes = &models.PVVolumeAttributes{}
-			}
-		} else {
-			// 如果没有绑定PV，设置空的VolumeAttributes
-			pvcWithPV.VolumeAttributes = &models.PVVolumeAttributes{}
-		}
+	return pagePVCsWithPVInfo(pvcs, opts, getPV)
+}
 
-		result = append(result, pvcWithPV)
+// ClonePVC 以现有 PVC 为数据源克隆新的 PVC
+func (m *MultiClusterK8sService) ClonePVC(ctx context.Context, namespace, name string, req models.ClonePVCRequest) (*corev1.PersistentVolumeClaim, error) {
+	client, err := m.GetCurrentClient(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return clonePVC(ctx, client.ClientSet, namespace, name, req)
 }
 
 func (m *MultiClusterK8sService) GetNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
-	client, err := m.GetCurrentClient()
+	client, err := m.GetCurrentClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if client.Cache.CoreSynced() {
+		return client.Cache.ListNamespaces()
+	}
+
 	nsList, err := client.ClientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
@@ -627,42 +841,128 @@ func (m *MultiClusterK8sService) GetNamespaces(ctx context.Context) ([]corev1.Na
 	return nsList.Items, nil
 }
 
-// refreshPVCache 刷新指定客户端的PV缓存
-func (m *MultiClusterK8sService) refreshPVCache(ctx context.Context, client *ClusterClient) error {
-	// 缓存有效期5分钟
-	client.pvCacheMutex.RLock()
-	if time.Since(client.pvCacheTime) < 5*time.Minute && len(client.pvCache) > 0 {
-		client.pvCacheMutex.RUnlock()
-		return nil
+// listVolumeSnapshots 列出快照，缓存已同步时从缓存读取，namespace 为空表示所有命名空间
+func (c *ClusterClient) listVolumeSnapshots(ctx context.Context, namespace, labelSelector string) ([]snapshotv1.VolumeSnapshot, error) {
+	if c.Cache.SnapshotsSynced() {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return nil, err
+		}
+		return c.Cache.ListVolumeSnapshots(namespace, selector)
 	}
-	client.pvCacheMutex.RUnlock()
 
-	// 批量获取所有PV
-	pvList, err := client.ClientSet.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	vsList, err := c.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return vsList.Items, nil
+}
 
-	// 更新缓存
-	client.pvCacheMutex.Lock()
-	defer client.pvCacheMutex.Unlock()
+// getVolumeSnapshot 获取单个快照，缓存未命中时直接访问 API（刚创建的快照可能尚未进入缓存）
+func (c *ClusterClient) getVolumeSnapshot(ctx context.Context, namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
+	if c.Cache.SnapshotsSynced() {
+		vs, err := c.Cache.GetVolumeSnapshot(namespace, name)
+		if err == nil {
+			return vs, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
 
-	newCache := make(map[string]*corev1.PersistentVolume)
-	for i := range pvList.Items {
-		pv := &pvList.Items[i]
-		newCache[pv.Name] = pv
+	return c.SnapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// listPVCs 列出 PVC，缓存已同步时从缓存读取，namespace 为空表示所有命名空间
+func (c *ClusterClient) listPVCs(ctx context.Context, namespace, labelSelector string) ([]corev1.PersistentVolumeClaim, error) {
+	if c.Cache.CoreSynced() {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return nil, err
+		}
+		return c.Cache.ListPVCs(namespace, selector)
 	}
 
-	client.pvCache = newCache
-	client.pvCacheTime = time.Now()
+	pvcList, err := c.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+	return pvcList.Items, nil
+}
 
-	return nil
+// volumeSnapshotContentGetter 返回按名称查找 VolumeSnapshotContent 的函数
+// 缓存未同步时一次性 List 所有 VolumeSnapshotContent，避免逐个快照请求
+func (c *ClusterClient) volumeSnapshotContentGetter(ctx context.Context) (func(name string) *snapshotv1.VolumeSnapshotContent, error) {
+	if c.Cache.SnapshotsSynced() {
+		return func(name string) *snapshotv1.VolumeSnapshotContent {
+			vsc, err := c.Cache.GetVolumeSnapshotContent(name)
+			if err != nil {
+				return nil
+			}
+			return vsc
+		}, nil
+	}
+
+	vscList, err := c.SnapshotClientSet.SnapshotV1().VolumeSnapshotContents().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string]*snapshotv1.VolumeSnapshotContent, len(vscList.Items))
+	for i := range vscList.Items {
+		contents[vscList.Items[i].Name] = &vscList.Items[i]
+	}
+	return func(name string) *snapshotv1.VolumeSnapshotContent {
+		return contents[name]
+	}, nil
 }
 
-// getPVFromCache 从指定客户端的缓存获取PV信息
-func (m *MultiClusterK8sService) getPVFromCache(client *ClusterClient, pvName string) *corev1.PersistentVolume {
-	client.pvCacheMutex.RLock()
-	defer client.pvCacheMutex.RUnlock()
-	return client.pvCache[pvName]
+// pvcGetter 返回按命名空间和名称查找 PVC 的函数
+// 缓存未同步时一次性 List 指定命名空间（为空时为所有命名空间）的 PVC
+func (c *ClusterClient) pvcGetter(ctx context.Context, namespace string) (func(namespace, name string) *corev1.PersistentVolumeClaim, error) {
+	if c.Cache.CoreSynced() {
+		return func(namespace, name string) *corev1.PersistentVolumeClaim {
+			pvc, err := c.Cache.GetPVC(namespace, name)
+			if err != nil {
+				return nil
+			}
+			return pvc
+		}, nil
+	}
+
+	pvcList, err := c.ClientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvcs := make(map[string]*corev1.PersistentVolumeClaim, len(pvcList.Items))
+	for i := range pvcList.Items {
+		pvcs[pvcList.Items[i].Namespace+"/"+pvcList.Items[i].Name] = &pvcList.Items[i]
+	}
+	return func(namespace, name string) *corev1.PersistentVolumeClaim {
+		return pvcs[namespace+"/"+name]
+	}, nil
 }
 
+// pvGetter 返回按名称查找 PV 的函数，缓存未同步时一次性 List 所有 PV
+func (c *ClusterClient) pvGetter(ctx context.Context) (func(name string) *corev1.PersistentVolume, error) {
+	if c.Cache.CoreSynced() {
+		return func(name string) *corev1.PersistentVolume {
+			pv, err := c.Cache.GetPV(name)
+			if err != nil {
+				return nil
+			}
+			return pv
+		}, nil
+	}
+
+	pvList, err := c.ClientSet.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pvs := make(map[string]*corev1.PersistentVolume, len(pvList.Items))
+	for i := range pvList.Items {
+		pvs[pvList.Items[i].Name] = &pvList.Items[i]
+	}
+	return func(name string) *corev1.PersistentVolume {
+		return pvs[name]
+	}, nil
+}
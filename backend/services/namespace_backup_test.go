@@ -0,0 +1,43 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestNewBackupID(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 30, 15, 0, time.UTC)
+
+	first, err := newBackupID("db", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newBackupID("db", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("同一秒内的备份 ID 冲突: %s", first)
+	}
+	if !strings.HasPrefix(first, "db-20240501-083015-") {
+		t.Fatalf("unexpected id: %s", first)
+	}
+	if createdAt, ok := backupIDCreatedAt(first); !ok || !createdAt.Equal(now) {
+		t.Fatalf("解析创建时间失败: %v %v", createdAt, ok)
+	}
+	// 升级前的备份 ID 没有随机后缀
+	if createdAt, ok := backupIDCreatedAt("db-20240501-083015"); !ok || !createdAt.Equal(now) {
+		t.Fatalf("解析旧 ID 创建时间失败: %v %v", createdAt, ok)
+	}
+
+	long, err := newBackupID(strings.Repeat("n", 63), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := validation.IsValidLabelValue(long); len(errs) > 0 {
+		t.Fatalf("备份 ID 不是合法的标签值: %s %v", long, errs)
+	}
+}
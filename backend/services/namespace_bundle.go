@@ -0,0 +1,659 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// NamespaceBackupLabel 标记命名空间备份创建的快照，值为备份 ID
+	NamespaceBackupLabel = "k8s-volume-snapshots/backup"
+	// defaultServiceAccountName 每个命名空间自动创建的 ServiceAccount
+	defaultServiceAccountName = "default"
+	// rootCAConfigMapName 每个命名空间自动发布的集群 CA
+	rootCAConfigMapName = "kube-root-ca.crt"
+)
+
+// backupKindOrder 可备份的资源类型及恢复顺序：被引用的资源先于引用它们的工作负载创建
+var backupKindOrder = []string{
+	"Namespace",
+	"ServiceAccount",
+	"ConfigMap",
+	"Secret",
+	"PersistentVolumeClaim",
+	"Service",
+	"Ingress",
+	"Deployment",
+	"StatefulSet",
+	"DaemonSet",
+	"CronJob",
+}
+
+// backupKindVersions 各资源类型导出时使用的 API 版本
+var backupKindVersions = map[string]schema.GroupVersion{
+	"Namespace":             corev1.SchemeGroupVersion,
+	"ServiceAccount":        corev1.SchemeGroupVersion,
+	"ConfigMap":             corev1.SchemeGroupVersion,
+	"Secret":                corev1.SchemeGroupVersion,
+	"PersistentVolumeClaim": corev1.SchemeGroupVersion,
+	"Service":               corev1.SchemeGroupVersion,
+	"Ingress":               networkingv1.SchemeGroupVersion,
+	"Deployment":            appsv1.SchemeGroupVersion,
+	"StatefulSet":           appsv1.SchemeGroupVersion,
+	"DaemonSet":             appsv1.SchemeGroupVersion,
+	"CronJob":               batchv1.SchemeGroupVersion,
+}
+
+// PVC 绑定和调度相关的注解，恢复时由控制器重新设置
+var pvcBindAnnotations = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// DefaultBackupKinds 未指定 kinds 时导出的资源类型（Namespace 和 PersistentVolumeClaim 总是导出）
+func DefaultBackupKinds() []string {
+	kinds := []string{}
+	for _, kind := range backupKindOrder {
+		if kind != "Namespace" && kind != "PersistentVolumeClaim" {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// normalizeBackupKinds 校验资源类型并按恢复顺序排列，补上必须导出的类型
+func normalizeBackupKinds(kinds []string) ([]string, error) {
+	if len(kinds) == 0 {
+		kinds = DefaultBackupKinds()
+	}
+	selected := map[string]bool{"Namespace": true, "PersistentVolumeClaim": true}
+	for _, kind := range kinds {
+		if _, ok := backupKindVersions[kind]; !ok {
+			return nil, fmt.Errorf("不支持备份的资源类型 %q，可选: %s", kind, strings.Join(DefaultBackupKinds(), ", "))
+		}
+		selected[kind] = true
+	}
+
+	normalized := []string{}
+	for _, kind := range backupKindOrder {
+		if selected[kind] {
+			normalized = append(normalized, kind)
+		}
+	}
+	return normalized, nil
+}
+
+// exportNamespaceResources 导出命名空间中指定类型的资源清单
+// 由控制器创建的资源（带 ownerReferences）和集群自动创建的资源不导出，恢复后会被重新生成
+func exportNamespaceResources(ctx context.Context, clientSet kubernetes.Interface, namespace string, kinds []string) ([]models.BundleResource, error) {
+	kinds, err := normalizeBackupKinds(kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []models.BundleResource{}
+	for _, kind := range kinds {
+		objects, err := listBackupObjects(ctx, clientSet, namespace, kind)
+		if err != nil {
+			return nil, fmt.Errorf("导出 %s 失败: %v", kind, err)
+		}
+
+		for _, obj := range objects {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return nil, err
+			}
+			if skipBackupObject(obj, accessor) {
+				continue
+			}
+
+			sanitizeBackupObject(obj, accessor)
+			obj.GetObjectKind().SetGroupVersionKind(backupKindVersions[kind].WithKind(kind))
+			manifest, err := json.Marshal(obj)
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, models.BundleResource{Kind: kind, Name: accessor.GetName(), Manifest: manifest})
+		}
+	}
+	return resources, nil
+}
+
+// listBackupObjects 列出命名空间中某种类型的资源
+func listBackupObjects(ctx context.Context, clientSet kubernetes.Interface, namespace, kind string) ([]runtime.Object, error) {
+	objects := []runtime.Object{}
+	opts := metav1.ListOptions{}
+
+	switch kind {
+	case "Namespace":
+		ns, err := clientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, ns)
+	case "ServiceAccount":
+		list, err := clientSet.CoreV1().ServiceAccounts(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "ConfigMap":
+		list, err := clientSet.CoreV1().ConfigMaps(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "Secret":
+		list, err := clientSet.CoreV1().Secrets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "PersistentVolumeClaim":
+		list, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "Service":
+		list, err := clientSet.CoreV1().Services(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "Ingress":
+		list, err := clientSet.NetworkingV1().Ingresses(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "Deployment":
+		list, err := clientSet.AppsV1().Deployments(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "StatefulSet":
+		list, err := clientSet.AppsV1().StatefulSets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "DaemonSet":
+		list, err := clientSet.AppsV1().DaemonSets(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case "CronJob":
+		list, err := clientSet.BatchV1().CronJobs(namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	}
+	return objects, nil
+}
+
+// skipBackupObject 判断资源是否由控制器或集群自动管理
+func skipBackupObject(obj runtime.Object, accessor metav1.Object) bool {
+	if len(accessor.GetOwnerReferences()) > 0 {
+		return true
+	}
+	switch o := obj.(type) {
+	case *corev1.ServiceAccount:
+		return o.Name == defaultServiceAccountName
+	case *corev1.ConfigMap:
+		return o.Name == rootCAConfigMapName
+	case *corev1.Secret:
+		return o.Type == corev1.SecretTypeServiceAccountToken
+	}
+	return false
+}
+
+// sanitizeBackupObject 去除 uid、status 等集群相关字段，使清单可以在其他命名空间中重新创建
+func sanitizeBackupObject(obj runtime.Object, accessor metav1.Object) {
+	accessor.SetUID("")
+	accessor.SetResourceVersion("")
+	accessor.SetGeneration(0)
+	accessor.SetCreationTimestamp(metav1.Time{})
+	accessor.SetDeletionTimestamp(nil)
+	accessor.SetDeletionGracePeriodSeconds(nil)
+	accessor.SetManagedFields(nil)
+	accessor.SetSelfLink("")
+
+	switch o := obj.(type) {
+	case *corev1.Namespace:
+		delete(o.Labels, corev1.LabelMetadataName)
+		o.Spec = corev1.NamespaceSpec{}
+		o.Status = corev1.NamespaceStatus{}
+	case *corev1.ServiceAccount:
+		o.Secrets = nil
+	case *corev1.PersistentVolumeClaim:
+		for _, key := range pvcBindAnnotations {
+			delete(o.Annotations, key)
+		}
+		o.Spec.VolumeName = ""
+		o.Spec.DataSource = nil
+		o.Spec.DataSourceRef = nil
+		o.Status = corev1.PersistentVolumeClaimStatus{}
+	case *corev1.Service:
+		if o.Spec.ClusterIP != corev1.ClusterIPNone {
+			o.Spec.ClusterIP = ""
+			o.Spec.ClusterIPs = nil
+		}
+		o.Status = corev1.ServiceStatus{}
+	case *networkingv1.Ingress:
+		o.Status = networkingv1.IngressStatus{}
+	case *appsv1.Deployment:
+		delete(o.Annotations, "deployment.kubernetes.io/revision")
+		o.Status = appsv1.DeploymentStatus{}
+	case *appsv1.StatefulSet:
+		o.Status = appsv1.StatefulSetStatus{}
+	case *appsv1.DaemonSet:
+		delete(o.Annotations, "deprecated.daemonset.template.generation")
+		o.Status = appsv1.DaemonSetStatus{}
+	case *batchv1.CronJob:
+		o.Status = batchv1.CronJobStatus{}
+	}
+}
+
+// retainBackupSnapshot 将就绪快照绑定的内容设为 Retain，删除命名空间时不会删除底层存储快照
+// 返回在任意命名空间中重建该快照所需的信息
+func retainBackupSnapshot(ctx context.Context, snapshotClientSet snapshotclientset.Interface, namespace, name string) (*models.BundleSnapshot, error) {
+	vs, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if vs.Status == nil || vs.Status.BoundVolumeSnapshotContentName == nil {
+		return nil, fmt.Errorf("快照 %s 尚未绑定 VolumeSnapshotContent", name)
+	}
+
+	contentName := *vs.Status.BoundVolumeSnapshotContentName
+	content, err := snapshotClientSet.SnapshotV1().VolumeSnapshotContents().Get(ctx, contentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if content.Status == nil || content.Status.SnapshotHandle == nil {
+		return nil, fmt.Errorf("快照内容 %s 缺少 snapshotHandle", contentName)
+	}
+
+	if content.Spec.DeletionPolicy != snapshotv1.VolumeSnapshotContentRetain {
+		if err := setSnapshotContentDeletionPolicy(ctx, snapshotClientSet, contentName, snapshotv1.VolumeSnapshotContentRetain); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := &models.BundleSnapshot{
+		SnapshotName:   name,
+		ContentName:    contentName,
+		SnapshotHandle: *content.Status.SnapshotHandle,
+		Driver:         content.Spec.Driver,
+	}
+	if vs.Spec.Source.PersistentVolumeClaimName != nil {
+		snapshot.PVCName = *vs.Spec.Source.PersistentVolumeClaimName
+	}
+	if content.Spec.VolumeSnapshotClassName != nil {
+		snapshot.VolumeSnapshotClassName = *content.Spec.VolumeSnapshotClassName
+	}
+	if vs.Status.RestoreSize != nil {
+		snapshot.RestoreSize = vs.Status.RestoreSize.String()
+	}
+	return snapshot, nil
+}
+
+// deleteBackupSnapshot 删除备份快照及其底层存储快照
+// 先把内容的删除策略改回 Delete；快照已随命名空间删除时直接删除内容
+func deleteBackupSnapshot(ctx context.Context, snapshotClientSet snapshotclientset.Interface, namespace string, snapshot models.BundleSnapshot) error {
+	err := setSnapshotContentDeletionPolicy(ctx, snapshotClientSet, snapshot.ContentName, snapshotv1.VolumeSnapshotContentDelete)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	err = snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Delete(ctx, snapshot.SnapshotName, metav1.DeleteOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	err = snapshotClientSet.SnapshotV1().VolumeSnapshotContents().Delete(ctx, snapshot.ContentName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func setSnapshotContentDeletionPolicy(ctx context.Context, snapshotClientSet snapshotclientset.Interface, name string, policy snapshotv1.DeletionPolicy) error {
+	patch := fmt.Sprintf(`{"spec":{"deletionPolicy":%q}}`, policy)
+	_, err := snapshotClientSet.SnapshotV1().VolumeSnapshotContents().Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// restoreNamespaceBundle 按备份包在目标命名空间中重建资源
+// PVC 从备份快照恢复；目标命名空间中没有对应快照时（命名空间已删除或恢复到新名称），
+// 通过 snapshotHandle 预置 VolumeSnapshotContent 重新导入快照。已存在的资源跳过，不覆盖
+func restoreNamespaceBundle(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, bundle *models.NamespaceBackupBundle, targetNamespace string, report ProgressFunc) error {
+	if targetNamespace == "" {
+		targetNamespace = bundle.Namespace
+	}
+	renamed := targetNamespace != bundle.Namespace
+
+	resourcesByKind := make(map[string][]models.BundleResource)
+	for _, res := range bundle.Resources {
+		resourcesByKind[res.Kind] = append(resourcesByKind[res.Kind], res)
+	}
+
+	// 命名空间必须先存在
+	step := "创建命名空间 " + targetNamespace
+	report(step, models.OperationStatusRunning, "")
+	namespace := &corev1.Namespace{}
+	if list := resourcesByKind["Namespace"]; len(list) > 0 {
+		if err := json.Unmarshal(list[0].Manifest, namespace); err != nil {
+			report(step, models.OperationStatusFailed, err.Error())
+			return fmt.Errorf("解析命名空间清单失败: %v", err)
+		}
+	}
+	namespace.Name = targetNamespace
+	if _, err := clientSet.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			report(step, models.OperationStatusFailed, err.Error())
+			return fmt.Errorf("创建命名空间失败: %v", err)
+		}
+		report(step, models.OperationStatusSkipped, "命名空间已存在")
+	} else {
+		report(step, models.OperationStatusSucceeded, "")
+	}
+
+	// 准备 PVC 的数据源快照
+	snapshotsByPVC := make(map[string]models.BundleSnapshot)
+	for _, snapshot := range bundle.Snapshots {
+		step := "准备快照 " + snapshot.SnapshotName
+		report(step, models.OperationStatusRunning, "")
+		if err := importBackupSnapshot(ctx, snapshotClientSet, bundle.ID, targetNamespace, snapshot); err != nil {
+			report(step, models.OperationStatusFailed, err.Error())
+			return err
+		}
+		if err := waitForSnapshotReady(ctx, snapshotClientSet, targetNamespace, snapshot.SnapshotName, SnapshotReadyTimeout); err != nil {
+			report(step, models.OperationStatusFailed, err.Error())
+			return err
+		}
+		report(step, models.OperationStatusSucceeded, "快照已就绪")
+		snapshotsByPVC[snapshot.PVCName] = snapshot
+	}
+
+	// 按依赖顺序创建资源，单个资源失败不影响其他资源
+	var failed []string
+	for _, kind := range backupKindOrder {
+		if kind == "Namespace" || len(resourcesByKind[kind]) == 0 {
+			continue
+		}
+
+		step := "恢复 " + kind
+		report(step, models.OperationStatusRunning, "")
+		created, skipped := 0, 0
+		var kindFailed []string
+		for _, res := range resourcesByKind[kind] {
+			err := createBundleResource(ctx, clientSet, res, targetNamespace, renamed, snapshotsByPVC)
+			switch {
+			case err == nil:
+				created++
+			case apierrors.IsAlreadyExists(err):
+				skipped++
+			default:
+				kindFailed = append(kindFailed, fmt.Sprintf("%s/%s: %v", kind, res.Name, err))
+			}
+		}
+
+		message := fmt.Sprintf("已创建 %d 个", created)
+		if skipped > 0 {
+			message += fmt.Sprintf("，%d 个已存在已跳过", skipped)
+		}
+		if len(kindFailed) > 0 {
+			report(step, models.OperationStatusFailed, message+"；失败: "+strings.Join(kindFailed, "; "))
+			failed = append(failed, kindFailed...)
+		} else {
+			report(step, models.OperationStatusSucceeded, message)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d 个资源恢复失败: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// importBackupSnapshot 确保目标命名空间中存在可用于恢复的快照
+func importBackupSnapshot(ctx context.Context, snapshotClientSet snapshotclientset.Interface, backupID, targetNamespace string, snapshot models.BundleSnapshot) error {
+	_, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(targetNamespace).Get(ctx, snapshot.SnapshotName, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	labels := map[string]string{
+		NamespaceBackupLabel: backupID,
+		"app":                "k8s-volume-snapshots",
+	}
+
+	// 预置的内容与备份快照共享底层存储快照，必须为 Retain，删除恢复出的快照时不影响备份
+	contentName := fmt.Sprintf("%s-%s-%s", targetNamespace, snapshot.SnapshotName, time.Now().UTC().Format("20060102150405"))
+	handle := snapshot.SnapshotHandle
+	content := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   contentName,
+			Labels: labels,
+		},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			VolumeSnapshotRef: corev1.ObjectReference{
+				Namespace: targetNamespace,
+				Name:      snapshot.SnapshotName,
+			},
+			DeletionPolicy: snapshotv1.VolumeSnapshotContentRetain,
+			Driver:         snapshot.Driver,
+			Source: snapshotv1.VolumeSnapshotContentSource{
+				SnapshotHandle: &handle,
+			},
+		},
+	}
+	if snapshot.VolumeSnapshotClassName != "" {
+		className := snapshot.VolumeSnapshotClassName
+		content.Spec.VolumeSnapshotClassName = &className
+	}
+	if _, err := snapshotClientSet.SnapshotV1().VolumeSnapshotContents().Create(ctx, content, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("导入快照 %s 失败: %v", snapshot.SnapshotName, err)
+	}
+
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      snapshot.SnapshotName,
+			Namespace: targetNamespace,
+			Labels:    labels,
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				VolumeSnapshotContentName: &contentName,
+			},
+		},
+	}
+	if snapshot.VolumeSnapshotClassName != "" {
+		className := snapshot.VolumeSnapshotClassName
+		vs.Spec.VolumeSnapshotClassName = &className
+	}
+	if _, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(targetNamespace).Create(ctx, vs, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("导入快照 %s 失败: %v", snapshot.SnapshotName, err)
+	}
+	return nil
+}
+
+// createBundleResource 在目标命名空间中创建单个资源
+func createBundleResource(ctx context.Context, clientSet kubernetes.Interface, res models.BundleResource, namespace string, renamed bool, snapshotsByPVC map[string]models.BundleSnapshot) error {
+	opts := metav1.CreateOptions{}
+
+	switch res.Kind {
+	case "ServiceAccount":
+		obj := &corev1.ServiceAccount{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.CoreV1().ServiceAccounts(namespace).Create(ctx, obj, opts)
+		return err
+	case "ConfigMap":
+		obj := &corev1.ConfigMap{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.CoreV1().ConfigMaps(namespace).Create(ctx, obj, opts)
+		return err
+	case "Secret":
+		obj := &corev1.Secret{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.CoreV1().Secrets(namespace).Create(ctx, obj, opts)
+		return err
+	case "PersistentVolumeClaim":
+		obj := &corev1.PersistentVolumeClaim{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		if snapshot, ok := snapshotsByPVC[obj.Name]; ok {
+			if err := setPVCSnapshotSource(obj, snapshot); err != nil {
+				return err
+			}
+		}
+		_, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, obj, opts)
+		return err
+	case "Service":
+		obj := &corev1.Service{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		// 节点端口在集群内唯一，原命名空间仍在使用，恢复到新名称时重新分配
+		if renamed {
+			for i := range obj.Spec.Ports {
+				obj.Spec.Ports[i].NodePort = 0
+			}
+			obj.Spec.HealthCheckNodePort = 0
+		}
+		_, err := clientSet.CoreV1().Services(namespace).Create(ctx, obj, opts)
+		return err
+	case "Ingress":
+		obj := &networkingv1.Ingress{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.NetworkingV1().Ingresses(namespace).Create(ctx, obj, opts)
+		return err
+	case "Deployment":
+		obj := &appsv1.Deployment{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.AppsV1().Deployments(namespace).Create(ctx, obj, opts)
+		return err
+	case "StatefulSet":
+		obj := &appsv1.StatefulSet{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.AppsV1().StatefulSets(namespace).Create(ctx, obj, opts)
+		return err
+	case "DaemonSet":
+		obj := &appsv1.DaemonSet{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.AppsV1().DaemonSets(namespace).Create(ctx, obj, opts)
+		return err
+	case "CronJob":
+		obj := &batchv1.CronJob{}
+		if err := decodeBundleResource(res, namespace, obj); err != nil {
+			return err
+		}
+		_, err := clientSet.BatchV1().CronJobs(namespace).Create(ctx, obj, opts)
+		return err
+	}
+	return fmt.Errorf("不支持恢复的资源类型 %s", res.Kind)
+}
+
+// decodeBundleResource 解析资源清单并设置目标命名空间
+func decodeBundleResource(res models.BundleResource, namespace string, obj metav1.Object) error {
+	if err := json.Unmarshal(res.Manifest, obj); err != nil {
+		return fmt.Errorf("解析清单失败: %v", err)
+	}
+	obj.SetNamespace(namespace)
+	return nil
+}
+
+// setPVCSnapshotSource 将 PVC 的数据源设为备份快照，容量不小于快照的恢复大小
+func setPVCSnapshotSource(pvc *corev1.PersistentVolumeClaim, snapshot models.BundleSnapshot) error {
+	apiGroup := snapshotv1.GroupName
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     "VolumeSnapshot",
+		Name:     snapshot.SnapshotName,
+	}
+	pvc.Spec.DataSourceRef = nil
+
+	if snapshot.RestoreSize == "" {
+		return nil
+	}
+	restoreSize, err := resource.ParseQuantity(snapshot.RestoreSize)
+	if err != nil {
+		return fmt.Errorf("无效的快照恢复大小 %q: %v", snapshot.RestoreSize, err)
+	}
+	if pvc.Spec.Resources.Requests == nil {
+		pvc.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	if size, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; !ok || size.Cmp(restoreSize) < 0 {
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = restoreSize
+	}
+	return nil
+}
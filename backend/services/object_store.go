@@ -0,0 +1,436 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// 本地备份存储的默认目录
+	BackupDataDir = "/data/backups"
+	// 访问对象存储的超时时间
+	objectStoreTimeout = 5 * time.Minute
+)
+
+// ErrObjectNotFound 对象不存在
+var ErrObjectNotFound = errors.New("对象不存在")
+
+// ObjectInfo 存储中的对象
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ObjectStore 备份数据的存储后端，key 使用 / 分隔
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// NewObjectStoresFromEnv 创建可用的备份存储：local 总是可用，设置 BACKUP_S3_BUCKET 后启用 s3
+func NewObjectStoresFromEnv() (map[string]ObjectStore, error) {
+	stores := map[string]ObjectStore{
+		"local": &fileObjectStore{dir: envOrDefault("BACKUP_DIR", BackupDataDir)},
+	}
+
+	if config := BackupS3ConfigFromEnv(); config != nil {
+		store, err := newS3ObjectStore(*config)
+		if err != nil {
+			return nil, err
+		}
+		stores["s3"] = store
+	}
+	return stores, nil
+}
+
+// BackupS3ConfigFromEnv 读取 BACKUP_S3_* 配置，未设置存储桶时返回 nil
+func BackupS3ConfigFromEnv() *S3Config {
+	bucket := os.Getenv("BACKUP_S3_BUCKET")
+	if bucket == "" {
+		return nil
+	}
+	return &S3Config{
+		Endpoint:  os.Getenv("BACKUP_S3_ENDPOINT"),
+		Region:    envOrDefault("BACKUP_S3_REGION", "us-east-1"),
+		Bucket:    bucket,
+		Prefix:    os.Getenv("BACKUP_S3_PREFIX"),
+		AccessKey: os.Getenv("BACKUP_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("BACKUP_S3_SECRET_KEY"),
+		PathStyle: os.Getenv("BACKUP_S3_PATH_STYLE") != "false",
+	}
+}
+
+// validObjectKey 拒绝可能逃出存储目录的 key
+func validObjectKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("无效的对象 key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("无效的对象 key %q", key)
+		}
+	}
+	return nil
+}
+
+// fileObjectStore 本地目录存储
+type fileObjectStore struct {
+	dir string
+}
+
+func (s *fileObjectStore) path(key string) (string, error) {
+	if err := validObjectKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *fileObjectStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免留下不完整的对象
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *fileObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	}
+	return data, err
+}
+
+func (s *fileObjectStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrObjectNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *fileObjectStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		}
+		return nil
+	})
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, err
+}
+
+// S3Config S3 兼容对象存储的连接配置
+type S3Config struct {
+	Endpoint  string // 如 http://minio:9000，为空时使用 AWS 的区域端点
+	Region    string
+	Bucket    string
+	Prefix    string // 所有对象 key 的前缀
+	AccessKey string
+	SecretKey string
+	// SessionToken 临时凭据的会话令牌，长期密钥为空
+	SessionToken string
+	PathStyle    bool // 使用 <endpoint>/<bucket>/<key> 形式的地址（MinIO、Ceph RGW 等）
+}
+
+// s3ObjectStore 使用 AWS Signature V4 直接调用 S3 REST API
+type s3ObjectStore struct {
+	config S3Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+func newS3ObjectStore(config S3Config) (*s3ObjectStore, error) {
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("S3 存储需要设置 access key 和 secret key")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	base, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("无效的 S3 endpoint %q: %v", config.Endpoint, err)
+	}
+	if config.Prefix != "" && !strings.HasSuffix(config.Prefix, "/") {
+		config.Prefix += "/"
+	}
+
+	return &s3ObjectStore{
+		config: config,
+		base:   base,
+		client: &http.Client{Timeout: objectStoreTimeout},
+		now:    time.Now,
+	}, nil
+}
+
+func (s *s3ObjectStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := validObjectKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.config.Prefix+key, nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3ObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := validObjectKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.config.Prefix+key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *s3ObjectStore) Delete(ctx context.Context, key string) error {
+	if err := validObjectKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.config.Prefix+key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// s3ListResult ListObjectsV2 的响应
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3ObjectStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.config.Prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析 S3 列表响应失败: %v", err)
+		}
+
+		for _, item := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          strings.TrimPrefix(item.Key, s.config.Prefix),
+				Size:         item.Size,
+				LastModified: item.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do 发送签名后的请求，非 2xx 响应转换为错误，404 返回 ErrObjectNotFound
+func (s *s3ObjectStore) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	req, err := s.newRequest(ctx, method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("访问 S3 失败: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3 %s %s 返回 %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// newRequest 构造带 AWS Signature V4 签名的请求
+func (s *s3ObjectStore) newRequest(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Request, error) {
+	host := s.base.Host
+	path := strings.TrimRight(s.base.Path, "/")
+	if s.config.PathStyle {
+		path += "/" + s.config.Bucket
+	} else {
+		host = s.config.Bucket + "." + host
+	}
+	path += "/" + key
+	encodedPath := s3URIEncode(path, false)
+	encodedQuery := s3CanonicalQuery(query)
+
+	rawURL := s.base.Scheme + "://" + host + encodedPath
+	if encodedQuery != "" {
+		rawURL += "?" + encodedQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+
+	signV4(req, encodedPath, encodedQuery, body, s.config.AccessKey, s.config.SecretKey, s.config.SessionToken, s.config.Region, "s3", s.now())
+	return req, nil
+}
+
+// signV4 为请求添加 AWS Signature V4 签名，签名 host、x-amz-content-sha256 和 x-amz-date 三个请求头
+// 使用临时凭据时（sessionToken 不为空）同时签名 x-amz-security-token
+func signV4(req *http.Request, encodedPath, encodedQuery string, body []byte, accessKey, secretKey, sessionToken, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n"
+	if sessionToken != "" {
+		req.Header.Set("x-amz-security-token", sessionToken)
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + sessionToken + "\n"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		encodedPath,
+		encodedQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// s3URIEncode 按 SigV4 规则编码：只保留非保留字符，路径中的 / 不编码
+func s3URIEncode(value string, encodeSlash bool) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			builder.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
+
+// s3CanonicalQuery 按参数名排序并编码查询参数
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3URIEncode(key, true)+"="+s3URIEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
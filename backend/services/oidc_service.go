@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// 授权请求（state）的有效期，超时未回调的登录需要重新发起
+const OIDCStateTTL = 10 * time.Minute
+
+// OIDCConfig OIDC 单点登录配置，从环境变量读取
+type OIDCConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string
+	GroupsClaim   string
+	RoleMapping   RoleMapping
+	// LoginPageURL 回调完成后携带 token 跳转的前端登录页
+	LoginPageURL string
+}
+
+type oidcAuthRequest struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+// OIDCService 实现 OIDC 授权码登录（带 PKCE），并将 ID Token 声明映射为本地角色
+type OIDCService struct {
+	config OIDCConfig
+
+	// provider 在首次使用时通过 discovery 初始化，启动时身份提供方不可用不影响本地登录
+	provider     *oidc.Provider
+	oauth2Config oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+	providerMu   sync.Mutex
+
+	requests  map[string]*oidcAuthRequest
+	requestMu sync.Mutex
+}
+
+// NewOIDCService 根据环境变量创建 OIDC 服务，未配置 OIDC_ISSUER_URL 时返回 nil
+func NewOIDCService() (*OIDCService, error) {
+	config := OIDCConfig{
+		IssuerURL:     os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:        splitList(envOrDefault("OIDC_SCOPES", "openid,profile,email,groups")),
+		UsernameClaim: envOrDefault("OIDC_USERNAME_CLAIM", "preferred_username"),
+		GroupsClaim:   envOrDefault("OIDC_GROUPS_CLAIM", "groups"),
+		LoginPageURL:  envOrDefault("OIDC_LOGIN_PAGE_URL", "/login"),
+	}
+
+	if config.IssuerURL == "" {
+		return nil, nil
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC_CLIENT_ID 和 OIDC_REDIRECT_URL 不能为空")
+	}
+	mapping, err := roleMappingFromEnv("OIDC")
+	if err != nil {
+		return nil, err
+	}
+	config.RoleMapping = mapping
+
+	service := &OIDCService{
+		config:   config,
+		requests: make(map[string]*oidcAuthRequest),
+	}
+
+	// 尽早发现配置错误，失败时在首次登录时重试
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := service.initProvider(ctx); err != nil {
+		fmt.Printf("警告: 初始化 OIDC 提供方失败，将在首次登录时重试: %v\n", err)
+	}
+
+	return service, nil
+}
+
+// Enabled 是否启用了 OIDC 登录
+func (s *OIDCService) Enabled() bool {
+	return s != nil
+}
+
+// LoginPageURL 前端登录页地址
+func (s *OIDCService) LoginPageURL() string {
+	return s.config.LoginPageURL
+}
+
+func (s *OIDCService) initProvider(ctx context.Context) error {
+	s.providerMu.Lock()
+	defer s.providerMu.Unlock()
+
+	if s.provider != nil {
+		return nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, s.config.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("OIDC discovery 失败: %v", err)
+	}
+
+	s.provider = provider
+	s.oauth2Config = oauth2.Config{
+		ClientID:     s.config.ClientID,
+		ClientSecret: s.config.ClientSecret,
+		RedirectURL:  s.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       s.config.Scopes,
+	}
+	s.verifier = provider.Verifier(&oidc.Config{ClientID: s.config.ClientID})
+	return nil
+}
+
+// AuthCodeURL 发起授权请求，返回跳转到身份提供方的地址和 state
+// 调用方需要把 state 绑定到发起登录的浏览器，回调时比对，防止登录 CSRF
+func (s *OIDCService) AuthCodeURL(ctx context.Context) (authURL, state string, err error) {
+	if err := s.initProvider(ctx); err != nil {
+		return "", "", err
+	}
+
+	state, err = randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	s.requestMu.Lock()
+	s.pruneRequestsLocked()
+	s.requests[state] = &oidcAuthRequest{
+		nonce:     nonce,
+		verifier:  verifier,
+		expiresAt: time.Now().Add(OIDCStateTTL),
+	}
+	s.requestMu.Unlock()
+
+	return s.oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Exchange 校验 state，用授权码换取并验证 ID Token，返回映射后的用户身份
+func (s *OIDCService) Exchange(ctx context.Context, state, code string) (*ExternalIdentity, error) {
+	s.requestMu.Lock()
+	request, exists := s.requests[state]
+	delete(s.requests, state)
+	s.requestMu.Unlock()
+
+	if !exists || time.Now().After(request.expiresAt) {
+		return nil, errors.New("登录请求无效或已过期，请重新登录")
+	}
+	if err := s.initProvider(ctx); err != nil {
+		return nil, err
+	}
+
+	token, err := s.oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(request.verifier))
+	if err != nil {
+		return nil, fmt.Errorf("换取令牌失败: %v", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("身份提供方未返回 id_token")
+	}
+
+	idToken, err := s.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("验证 id_token 失败: %v", err)
+	}
+	if idToken.Nonce != request.nonce {
+		return nil, errors.New("id_token nonce 不匹配")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("解析 id_token 声明失败: %v", err)
+	}
+
+	return s.identityFromClaims(idToken.Subject, claims)
+}
+
+// identityFromClaims 提取用户名、邮箱和组，并按配置映射本地角色
+// 只有 email_verified 为 true 时才采用邮箱；未声明或未验证的邮箱不参与角色映射，也不作为用户名
+func (s *OIDCService) identityFromClaims(subject string, claims map[string]interface{}) (*ExternalIdentity, error) {
+	identity := &ExternalIdentity{
+		Subject: subject,
+		Groups:  stringsClaim(claims[s.config.GroupsClaim]),
+	}
+
+	if email, _ := claims["email"].(string); email != "" {
+		if verified, _ := claims["email_verified"].(bool); verified {
+			identity.Email = email
+		}
+	}
+
+	identity.Username, _ = claims[s.config.UsernameClaim].(string)
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		return nil, fmt.Errorf("id_token 缺少用户名声明 %s", s.config.UsernameClaim)
+	}
+
+	identity.Role = s.config.RoleMapping.Map(identity.Email, identity.Groups)
+	return identity, nil
+}
+
+func (s *OIDCService) pruneRequestsLocked() {
+	now := time.Now()
+	for state, request := range s.requests {
+		if now.After(request.expiresAt) {
+			delete(s.requests, state)
+		}
+	}
+}
+
+// stringsClaim 兼容字符串数组和单个字符串形式的声明
+func stringsClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func randomToken() (string, error) {
+	return randomHex(16)
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// stubIssuer 最小的 OIDC 身份提供方：discovery、JWKS 和令牌端点，令牌端点返回 claims 签名后的 id_token
+type stubIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]interface{}
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &stubIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"authorization_endpoint":                issuer.server.URL + "/auth",
+			"token_endpoint":                        issuer.server.URL + "/token",
+			"jwks_uri":                              issuer.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig",
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     issuer.sign(t),
+		})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *stubIssuer) sign(t *testing.T) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: i.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{
+		"iss": i.server.URL,
+		"aud": "kvs",
+		"sub": "user-1",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range i.claims {
+		claims[key] = value
+	}
+	payload, _ := json.Marshal(claims)
+	object, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := object.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func newTestOIDCService(t *testing.T, issuer *stubIssuer) *OIDCService {
+	t.Setenv("OIDC_ISSUER_URL", issuer.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", "kvs")
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost/api/auth/oidc/callback")
+	t.Setenv("OIDC_ADMIN_EMAILS", "alice@example.com")
+	service, err := NewOIDCService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+// beginLogin 发起授权请求，返回 state 和授权地址中的 nonce
+func beginLogin(t *testing.T, service *OIDCService) (string, string) {
+	authURL, state, err := service.AuthCodeURL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Query().Get("state") != state {
+		t.Fatalf("授权地址中的 state 与返回值不一致: %s", authURL)
+	}
+	return state, parsed.Query().Get("nonce")
+}
+
+func TestOIDCExchangeVerifiedEmail(t *testing.T) {
+	issuer := newStubIssuer(t)
+	service := newTestOIDCService(t, issuer)
+
+	state, nonce := beginLogin(t, service)
+	issuer.claims = map[string]interface{}{
+		"nonce":              nonce,
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"groups":             []string{"dev"},
+	}
+
+	identity, err := service.Exchange(context.Background(), state, "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" || identity.Role != "admin" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+
+	// state 只能使用一次
+	if _, err := service.Exchange(context.Background(), state, "code"); err == nil {
+		t.Fatal("重复使用 state 应当失败")
+	}
+}
+
+func TestOIDCExchangeIgnoresUnverifiedEmail(t *testing.T) {
+	for name, claims := range map[string]map[string]interface{}{
+		"未声明": {"email": "alice@example.com"},
+		"未验证": {"email": "alice@example.com", "email_verified": false},
+		"字符串": {"email": "alice@example.com", "email_verified": "true"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			issuer := newStubIssuer(t)
+			service := newTestOIDCService(t, issuer)
+
+			state, nonce := beginLogin(t, service)
+			claims["nonce"] = nonce
+			claims["preferred_username"] = "mallory"
+			issuer.claims = claims
+
+			identity, err := service.Exchange(context.Background(), state, "code")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Email != "" || identity.Role != "none" {
+				t.Fatalf("未验证的邮箱不应参与角色映射: %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeRejectsInvalidTokens(t *testing.T) {
+	issuer := newStubIssuer(t)
+	service := newTestOIDCService(t, issuer)
+
+	if _, err := service.Exchange(context.Background(), "unknown", "code"); err == nil {
+		t.Fatal("未知的 state 应当失败")
+	}
+
+	state, _ := beginLogin(t, service)
+	issuer.claims = map[string]interface{}{"nonce": "other", "preferred_username": "alice"}
+	if _, err := service.Exchange(context.Background(), state, "code"); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("nonce 不匹配应当失败，实际: %v", err)
+	}
+
+	state, nonce := beginLogin(t, service)
+	issuer.claims = map[string]interface{}{"nonce": nonce, "preferred_username": "alice", "aud": "other"}
+	if _, err := service.Exchange(context.Background(), state, "code"); err == nil {
+		t.Fatal("aud 不匹配应当失败")
+	}
+}
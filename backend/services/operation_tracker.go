@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 已结束的操作在内存中保留的时长
+	operationRetention = time.Hour
+)
+
+// ProgressFunc 操作进度回调，同名步骤会被更新而不是重复追加
+type ProgressFunc func(step, status, message string)
+
+// OperationTracker 记录异步操作的进度，供前端轮询
+type OperationTracker struct {
+	operations map[string]*models.Operation
+	mutex      sync.RWMutex
+}
+
+func NewOperationTracker() *OperationTracker {
+	return &OperationTracker{
+		operations: make(map[string]*models.Operation),
+	}
+}
+
+// Start 登记一个新的操作并返回其副本，cluster 和 namespace 用于查询时的权限校验
+func (t *OperationTracker) Start(opType, cluster, namespace, target, createdBy string) models.Operation {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pruneLocked()
+
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+
+	op := &models.Operation{
+		ID:        hex.EncodeToString(bytes),
+		Type:      opType,
+		Cluster:   cluster,
+		Namespace: namespace,
+		Target:    target,
+		Status:    models.OperationStatusRunning,
+		Steps:     []models.OperationStep{},
+		CreatedBy: createdBy,
+		StartedAt: time.Now(),
+	}
+	t.operations[op.ID] = op
+
+	return t.copyLocked(op)
+}
+
+// Reporter 返回指定操作的进度回调
+func (t *OperationTracker) Reporter(id string) ProgressFunc {
+	return func(step, status, message string) {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		op, exists := t.operations[id]
+		if !exists {
+			return
+		}
+
+		now := time.Now()
+		for i := range op.Steps {
+			if op.Steps[i].Name == step {
+				op.Steps[i].Status = status
+				op.Steps[i].Message = message
+				if status != models.OperationStatusRunning {
+					op.Steps[i].FinishedAt = &now
+				}
+				return
+			}
+		}
+
+		newStep := models.OperationStep{
+			Name:      step,
+			Status:    status,
+			Message:   message,
+			StartedAt: now,
+		}
+		if status != models.OperationStatusRunning {
+			newStep.FinishedAt = &now
+		}
+		op.Steps = append(op.Steps, newStep)
+	}
+}
+
+// Finish 标记操作结束，err 为 nil 表示成功
+func (t *OperationTracker) Finish(id string, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	op, exists := t.operations[id]
+	if !exists {
+		return
+	}
+
+	now := time.Now()
+	op.FinishedAt = &now
+	if err != nil {
+		op.Status = models.OperationStatusFailed
+		op.Error = err.Error()
+	} else {
+		op.Status = models.OperationStatusSucceeded
+	}
+}
+
+// Get 获取操作进度
+func (t *OperationTracker) Get(id string) (models.Operation, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	op, exists := t.operations[id]
+	if !exists {
+		return models.Operation{}, false
+	}
+	return t.copyLocked(op), true
+}
+
+// copyLocked 复制操作，避免调用方与后台更新并发访问同一切片
+func (t *OperationTracker) copyLocked(op *models.Operation) models.Operation {
+	result := *op
+	result.Steps = append([]models.OperationStep{}, op.Steps...)
+	return result
+}
+
+// pruneLocked 清理已结束且过期的操作
+func (t *OperationTracker) pruneLocked() {
+	for id, op := range t.operations {
+		if op.FinishedAt != nil && time.Since(*op.FinishedAt) > operationRetention {
+			delete(t.operations, id)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 默认最小密码长度
+	DefaultPasswordMinLength = 8
+	// 默认至少包含的字符类别数（小写、大写、数字、符号）
+	DefaultPasswordMinCharClasses = 2
+	// 引导管理员的初始密码，任何账户都不能设置为该密码
+	defaultAdminPassword = "admin123"
+)
+
+// passwordPolicyFromEnv 从环境变量读取密码策略
+func passwordPolicyFromEnv() models.PasswordPolicy {
+	policy := models.PasswordPolicy{
+		MinLength:        envInt("PASSWORD_MIN_LENGTH", DefaultPasswordMinLength),
+		MinCharClasses:   envInt("PASSWORD_MIN_CHAR_CLASSES", DefaultPasswordMinCharClasses),
+		DisallowUsername: os.Getenv("PASSWORD_ALLOW_USERNAME") != "true",
+	}
+	// 与请求校验的下限保持一致
+	if policy.MinLength < 6 {
+		policy.MinLength = 6
+	}
+	if policy.MinCharClasses > 4 {
+		policy.MinCharClasses = 4
+	}
+	return policy
+}
+
+// validatePassword 检查密码是否满足策略
+func validatePassword(policy models.PasswordPolicy, username, password string) error {
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("密码长度至少 %d 个字符", policy.MinLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < policy.MinCharClasses {
+		return fmt.Errorf("密码至少需要包含小写字母、大写字母、数字、符号中的 %d 类", policy.MinCharClasses)
+	}
+
+	if policy.DisallowUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("密码不能包含用户名")
+	}
+	if password == defaultAdminPassword {
+		return fmt.Errorf("不能使用默认密码")
+	}
+	return nil
+}
@@ -0,0 +1,582 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// RBAC 数据存储文件路径（自定义角色和角色绑定）
+	RBACDataFile = "/data/rbac.json"
+)
+
+// 内置角色，与 User.Role 同名，用户的 Role 字段等价于一个全局生效的绑定
+var builtInRoles = []models.Role{
+	{
+		Name:        "admin",
+		Description: "管理员，拥有所有权限",
+		Verbs:       []string{"*"},
+		BuiltIn:     true,
+	},
+	{
+		Name:        "readonly",
+		Description: "只读用户，可查看所有资源并切换默认集群",
+		Verbs:       []string{models.PermRead, models.PermClusterSwitch},
+		BuiltIn:     true,
+	},
+	{
+		Name:        "none",
+		Description: "无全局权限，只通过角色绑定获得指定集群和命名空间内的权限",
+		Verbs:       []string{},
+		BuiltIn:     true,
+	},
+}
+
+// Scope 权限检查的范围，Cluster 或 Namespace 为空表示集群级或全局操作，
+// 只有不限制对应范围的授权才能匹配
+type Scope struct {
+	Cluster   string
+	Namespace string
+}
+
+type rbacData struct {
+	Roles    []models.Role        `json:"roles"`
+	Bindings []models.RoleBinding `json:"bindings"`
+}
+
+// grant 用户从角色或绑定获得的一组权限及其范围
+type grant struct {
+	source     string
+	role       *models.Role
+	clusters   []string
+	namespaces []string
+	// tokenScopes 请求使用 API token 时的权限范围，与角色权限取交集
+	tokenScopes []string
+}
+
+// RBACService 管理自定义角色和角色绑定，并执行权限判断
+type RBACService struct {
+	roles    map[string]*models.Role
+	bindings map[string]*models.RoleBinding
+	mutex    sync.RWMutex
+	dataFile string
+}
+
+func NewRBACService() *RBACService {
+	service := &RBACService{
+		roles:    make(map[string]*models.Role),
+		bindings: make(map[string]*models.RoleBinding),
+		dataFile: RBACDataFile,
+	}
+
+	for i := range builtInRoles {
+		role := builtInRoles[i]
+		service.roles[role.Name] = &role
+	}
+
+	service.load()
+
+	return service
+}
+
+// load 从文件加载自定义角色和角色绑定
+func (s *RBACService) load() {
+	data, err := ioutil.ReadFile(s.dataFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("读取 RBAC 数据文件失败: %v\n", err)
+		}
+		return
+	}
+
+	var stored rbacData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		fmt.Printf("解析 RBAC 数据失败: %v\n", err)
+		return
+	}
+
+	for _, role := range stored.Roles {
+		if existing, exists := s.roles[role.Name]; exists && existing.BuiltIn {
+			continue
+		}
+		roleCopy := role
+		s.roles[role.Name] = &roleCopy
+	}
+	for _, binding := range stored.Bindings {
+		bindingCopy := binding
+		s.bindings[binding.ID] = &bindingCopy
+	}
+
+	fmt.Printf("成功加载 %d 个自定义角色和 %d 个角色绑定\n", len(stored.Roles), len(stored.Bindings))
+}
+
+// save 保存自定义角色和角色绑定到文件，调用方需持有锁
+func (s *RBACService) save() error {
+	dir := filepath.Dir(s.dataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	stored := rbacData{
+		Roles:    []models.Role{},
+		Bindings: []models.RoleBinding{},
+	}
+	for _, role := range s.roles {
+		if !role.BuiltIn {
+			stored.Roles = append(stored.Roles, *role)
+		}
+	}
+	for _, binding := range s.bindings {
+		stored.Bindings = append(stored.Bindings, *binding)
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 RBAC 数据失败: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.dataFile, data, 0600); err != nil {
+		return fmt.Errorf("写入 RBAC 数据文件失败: %v", err)
+	}
+
+	return nil
+}
+
+// Authorize 判断用户是否在指定范围内拥有某个权限
+func (s *RBACService) Authorize(user *models.User, verb string, scope Scope) bool {
+	if user == nil {
+		return false
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, g := range s.grantsLocked(user) {
+		if g.allows(verb) && matchScope(g.clusters, scope.Cluster) && matchScope(g.namespaces, scope.Namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasWritePermission 判断用户在任意范围内是否拥有修改资源的权限（read、cluster:switch、audit:read 以外的权限）
+func (s *RBACService) HasWritePermission(user *models.User) bool {
+	if user == nil {
+		return false
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, g := range s.grantsLocked(user) {
+		for _, permission := range models.AllPermissions {
+			if permission == models.PermRead || permission == models.PermClusterSwitch || permission == models.PermAuditRead {
+				continue
+			}
+			if g.allows(permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NamespaceFilter 返回用户在指定集群中拥有某个权限的命名空间判断函数
+// 返回 nil 表示不限制命名空间，调用方可以跳过过滤
+func (s *RBACService) NamespaceFilter(user *models.User, verb, cluster string) func(namespace string) bool {
+	if user == nil {
+		return func(string) bool { return false }
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var patterns []string
+	for _, g := range s.grantsLocked(user) {
+		if !g.allows(verb) || !matchScope(g.clusters, cluster) {
+			continue
+		}
+		if matchScope(g.namespaces, "") {
+			return nil
+		}
+		patterns = append(patterns, g.namespaces...)
+	}
+
+	return func(namespace string) bool {
+		return len(patterns) > 0 && matchScope(patterns, namespace)
+	}
+}
+
+// ValidateTokenScopes 校验 API token 的权限范围，每一项都必须是用户在某个范围内拥有的权限
+func (s *RBACService) ValidateTokenScopes(user *models.User, scopes []string) error {
+	if err := validateVerbs(scopes); err != nil {
+		return err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	grants := s.grantsLocked(user)
+	for _, scope := range scopes {
+		held := false
+		for _, permission := range models.AllPermissions {
+			if matched, _ := path.Match(scope, permission); scope != "*" && !matched {
+				continue
+			}
+			for _, g := range grants {
+				if g.allows(permission) {
+					held = true
+					break
+				}
+			}
+			if held {
+				break
+			}
+		}
+		if !held {
+			return fmt.Errorf("用户没有权限 %q，不能授予 API token", scope)
+		}
+	}
+	return nil
+}
+
+// EffectivePermissions 列出用户的所有授权来源
+func (s *RBACService) EffectivePermissions(user *models.User) []models.EffectivePermission {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	permissions := []models.EffectivePermission{}
+	for _, g := range s.grantsLocked(user) {
+		permissions = append(permissions, models.EffectivePermission{
+			Source:     g.source,
+			Role:       g.role.Name,
+			Verbs:      g.role.Verbs,
+			Clusters:   g.clusters,
+			Namespaces: g.namespaces,
+		})
+	}
+	return permissions
+}
+
+// grantsLocked 收集用户角色和匹配的角色绑定，调用方需持有锁
+func (s *RBACService) grantsLocked(user *models.User) []grant {
+	var grants []grant
+
+	if role, exists := s.roles[user.Role]; exists {
+		grants = append(grants, grant{source: "role", role: role, tokenScopes: user.TokenScopes})
+	}
+
+	for _, binding := range s.bindings {
+		if !bindingMatchesUser(binding, user) {
+			continue
+		}
+		role, exists := s.roles[binding.Role]
+		if !exists {
+			continue
+		}
+		grants = append(grants, grant{
+			source:      "binding:" + binding.ID,
+			role:        role,
+			clusters:    binding.Clusters,
+			namespaces:  binding.Namespaces,
+			tokenScopes: user.TokenScopes,
+		})
+	}
+
+	return grants
+}
+
+func bindingMatchesUser(binding *models.RoleBinding, user *models.User) bool {
+	for _, username := range binding.Users {
+		if username == user.Username {
+			return true
+		}
+	}
+	for _, group := range binding.Groups {
+		for _, userGroup := range user.Groups {
+			if group == userGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allows 判断授权是否包含某个权限，任何权限都隐含所在范围内的 read
+func (g grant) allows(verb string) bool {
+	if g.tokenScopes != nil && !verbsAllow(g.tokenScopes, verb) {
+		return false
+	}
+	return verbsAllow(g.role.Verbs, verb)
+}
+
+func verbsAllow(verbs []string, verb string) bool {
+	if verb == models.PermRead && len(verbs) > 0 {
+		return true
+	}
+	for _, pattern := range verbs {
+		if pattern == "*" {
+			return true
+		}
+		if matched, _ := path.Match(pattern, verb); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchScope 判断范围是否匹配通配符列表；列表为空或包含 * 时匹配任意值（包括空值）
+func matchScope(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+	}
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRoles 获取所有角色（包括内置角色），按名称排序
+func (s *RBACService) GetRoles() []models.Role {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	roles := make([]models.Role, 0, len(s.roles))
+	for _, role := range s.roles {
+		roles = append(roles, *role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	return roles
+}
+
+// CreateRole 创建自定义角色
+func (s *RBACService) CreateRole(role models.Role) (*models.Role, error) {
+	if err := validateVerbs(role.Verbs); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.roles[role.Name]; exists {
+		return nil, errors.New("角色已存在")
+	}
+
+	role.BuiltIn = false
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = time.Now()
+	s.roles[role.Name] = &role
+
+	if err := s.save(); err != nil {
+		delete(s.roles, role.Name)
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// UpdateRole 更新自定义角色的描述和权限，内置角色不可修改
+func (s *RBACService) UpdateRole(name string, req models.Role) (*models.Role, error) {
+	if err := validateVerbs(req.Verbs); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	role, exists := s.roles[name]
+	if !exists {
+		return nil, errors.New("角色不存在")
+	}
+	if role.BuiltIn {
+		return nil, errors.New("不能修改内置角色")
+	}
+
+	previous := *role
+	role.Description = req.Description
+	role.Verbs = req.Verbs
+	role.UpdatedAt = time.Now()
+
+	if err := s.save(); err != nil {
+		*role = previous
+		return nil, err
+	}
+
+	roleCopy := *role
+	return &roleCopy, nil
+}
+
+// DeleteRole 删除自定义角色，仍被绑定引用的角色不能删除
+func (s *RBACService) DeleteRole(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	role, exists := s.roles[name]
+	if !exists {
+		return errors.New("角色不存在")
+	}
+	if role.BuiltIn {
+		return errors.New("不能删除内置角色")
+	}
+	for _, binding := range s.bindings {
+		if binding.Role == name {
+			return fmt.Errorf("角色仍被绑定 %s 引用", binding.ID)
+		}
+	}
+
+	delete(s.roles, name)
+	return s.save()
+}
+
+// GetBindings 获取所有角色绑定，按创建时间排序
+func (s *RBACService) GetBindings() []models.RoleBinding {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	bindings := make([]models.RoleBinding, 0, len(s.bindings))
+	for _, binding := range s.bindings {
+		bindings = append(bindings, *binding)
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].CreatedAt.Before(bindings[j].CreatedAt)
+	})
+	return bindings
+}
+
+// CreateBinding 创建角色绑定
+func (s *RBACService) CreateBinding(binding models.RoleBinding, createdBy string) (*models.RoleBinding, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.validateBindingLocked(binding); err != nil {
+		return nil, err
+	}
+
+	binding.ID = generateBindingID()
+	binding.CreatedBy = createdBy
+	binding.CreatedAt = time.Now()
+	binding.UpdatedAt = time.Now()
+	s.bindings[binding.ID] = &binding
+
+	if err := s.save(); err != nil {
+		delete(s.bindings, binding.ID)
+		return nil, err
+	}
+
+	return &binding, nil
+}
+
+// UpdateBinding 更新角色绑定
+func (s *RBACService) UpdateBinding(id string, req models.RoleBinding) (*models.RoleBinding, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	binding, exists := s.bindings[id]
+	if !exists {
+		return nil, errors.New("角色绑定不存在")
+	}
+	if err := s.validateBindingLocked(req); err != nil {
+		return nil, err
+	}
+
+	previous := *binding
+	binding.Role = req.Role
+	binding.Users = req.Users
+	binding.Groups = req.Groups
+	binding.Clusters = req.Clusters
+	binding.Namespaces = req.Namespaces
+	binding.UpdatedAt = time.Now()
+
+	if err := s.save(); err != nil {
+		*binding = previous
+		return nil, err
+	}
+
+	bindingCopy := *binding
+	return &bindingCopy, nil
+}
+
+// DeleteBinding 删除角色绑定
+func (s *RBACService) DeleteBinding(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.bindings[id]; !exists {
+		return errors.New("角色绑定不存在")
+	}
+
+	delete(s.bindings, id)
+	return s.save()
+}
+
+func (s *RBACService) validateBindingLocked(binding models.RoleBinding) error {
+	if _, exists := s.roles[binding.Role]; !exists {
+		return fmt.Errorf("角色 %s 不存在", binding.Role)
+	}
+	if len(binding.Users) == 0 && len(binding.Groups) == 0 {
+		return errors.New("角色绑定至少需要指定一个用户或用户组")
+	}
+	for _, pattern := range append(append([]string{}, binding.Clusters...), binding.Namespaces...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("无效的通配符 %q", pattern)
+		}
+	}
+	return nil
+}
+
+// validateVerbs 校验权限动词，通配符必须至少匹配一个已知权限
+func validateVerbs(verbs []string) error {
+	if len(verbs) == 0 {
+		return errors.New("角色至少需要一个权限")
+	}
+	for _, verb := range verbs {
+		if verb == "*" {
+			continue
+		}
+		known := false
+		for _, permission := range models.AllPermissions {
+			matched, err := path.Match(verb, permission)
+			if err != nil {
+				return fmt.Errorf("无效的权限 %q", verb)
+			}
+			if matched {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("未知的权限 %q", verb)
+		}
+	}
+	return nil
+}
+
+func generateBindingID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return "rb-" + hex.EncodeToString(bytes)
+}
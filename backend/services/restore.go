@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// 等待恢复出的 PVC 绑定的最长时间
+	PVCBindTimeout = 10 * time.Minute
+	// 轮询 PVC 状态的间隔
+	pvcPollInterval = 2 * time.Second
+)
+
+// restoreVolumeSnapshot 基于 VolumeSnapshot 创建新的 PVC（单集群与多集群服务共用）
+func restoreVolumeSnapshot(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, namespace, name string, req models.RestoreVolumeSnapshotRequest) (*corev1.PersistentVolumeClaim, error) {
+	vs, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// 源 PVC 可能已被删除，此时只能使用请求中的参数
+	var sourcePVC *corev1.PersistentVolumeClaim
+	if vs.Spec.Source.PersistentVolumeClaimName != nil {
+		pvc, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, *vs.Spec.Source.PersistentVolumeClaimName, metav1.GetOptions{})
+		if err == nil {
+			sourcePVC = pvc
+		}
+	}
+
+	pvc, err := buildRestorePVC(vs, sourcePVC, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return clientSet.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{})
+}
+
+// buildRestorePVC 根据快照和请求参数生成 PVC 定义
+// 未指定的存储类、访问模式沿用源 PVC，容量默认为快照的 restoreSize
+func buildRestorePVC(vs *snapshotv1.VolumeSnapshot, sourcePVC *corev1.PersistentVolumeClaim, req models.RestoreVolumeSnapshotRequest) (*corev1.PersistentVolumeClaim, error) {
+	if vs.DeletionTimestamp != nil {
+		return nil, fmt.Errorf("快照 %s 正在删除中", vs.Name)
+	}
+	if vs.Status == nil || vs.Status.ReadyToUse == nil || !*vs.Status.ReadyToUse {
+		return nil, fmt.Errorf("快照 %s 尚未就绪，无法恢复", vs.Name)
+	}
+
+	// 容量：默认使用 restoreSize，显式指定时不能小于 restoreSize
+	var size resource.Quantity
+	if vs.Status.RestoreSize != nil {
+		size = vs.Status.RestoreSize.DeepCopy()
+	}
+	if req.Size != "" {
+		requested, err := resource.ParseQuantity(req.Size)
+		if err != nil {
+			return nil, fmt.Errorf("无效的容量 %q: %v", req.Size, err)
+		}
+		if !size.IsZero() && requested.Cmp(size) < 0 {
+			return nil, fmt.Errorf("容量 %s 小于快照的恢复大小 %s", requested.String(), size.String())
+		}
+		size = requested
+	}
+	if size.IsZero() {
+		return nil, fmt.Errorf("快照 %s 未提供 restoreSize，请指定容量", vs.Name)
+	}
+
+	// 存储类
+	var storageClassName *string
+	if req.StorageClassName != "" {
+		scName := req.StorageClassName
+		storageClassName = &scName
+	} else if sourcePVC != nil && sourcePVC.Spec.StorageClassName != nil {
+		scName := *sourcePVC.Spec.StorageClassName
+		storageClassName = &scName
+	}
+
+	// 访问模式
+	accessModes, err := parseAccessModes(req.AccessModes)
+	if err != nil {
+		return nil, err
+	}
+	if len(accessModes) == 0 {
+		if sourcePVC != nil && len(sourcePVC.Spec.AccessModes) > 0 {
+			accessModes = append(accessModes, sourcePVC.Spec.AccessModes...)
+		} else {
+			accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+		}
+	}
+
+	apiGroup := snapshotv1.GroupName
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.PVCName,
+			Namespace: vs.Namespace,
+			Labels: map[string]string{
+				"app": "k8s-volume-snapshots",
+			},
+			Annotations: map[string]string{
+				"k8s-volume-snapshots/restored-from": vs.Namespace + "/" + vs.Name,
+				"k8s-volume-snapshots/created-at":    time.Now().Format(time.RFC3339),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: storageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     vs.Name,
+			},
+		},
+	}
+
+	// 块设备快照必须恢复为块设备
+	if sourcePVC != nil && sourcePVC.Spec.VolumeMode != nil {
+		volumeMode := *sourcePVC.Spec.VolumeMode
+		pvc.Spec.VolumeMode = &volumeMode
+	}
+
+	if req.CreatedBy != "" {
+		pvc.Labels["created-by"] = CreatedByLabelValue(req.CreatedBy)
+		pvc.Annotations["k8s-volume-snapshots/created-by"] = req.CreatedBy
+	}
+
+	return pvc, nil
+}
+
+// parseAccessModes 校验并转换访问模式
+func parseAccessModes(modes []string) ([]corev1.PersistentVolumeAccessMode, error) {
+	var accessModes []corev1.PersistentVolumeAccessMode
+	for _, mode := range modes {
+		accessMode := corev1.PersistentVolumeAccessMode(mode)
+		switch accessMode {
+		case corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany, corev1.ReadWriteOncePod:
+			accessModes = append(accessModes, accessMode)
+		default:
+			return nil, fmt.Errorf("不支持的访问模式: %s", mode)
+		}
+	}
+	return accessModes, nil
+}
+
+// WaitForPVCBound 轮询 PVC 直到绑定、失败或超时，并通过 report 汇报进度
+// 对 WaitForFirstConsumer 类型的存储类，PVC 在被 Pod 使用前不会绑定，视为成功
+func WaitForPVCBound(ctx context.Context, k8sService K8sServiceInterface, namespace, name string, report ProgressFunc) error {
+	const step = "等待 PVC 绑定"
+	report(step, models.OperationStatusRunning, "PVC 已创建，等待存储卷供应")
+
+	ctx, cancel := context.WithTimeout(ctx, PVCBindTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pvcPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pvc, err := k8sService.GetPVC(ctx, namespace, name)
+		if err != nil {
+			report(step, models.OperationStatusFailed, err.Error())
+			return err
+		}
+
+		switch pvc.Status.Phase {
+		case corev1.ClaimBound:
+			report(step, models.OperationStatusSucceeded, fmt.Sprintf("PVC 已绑定到 %s", pvc.Spec.VolumeName))
+			return nil
+		case corev1.ClaimLost:
+			err := fmt.Errorf("PVC %s 处于 Lost 状态", name)
+			report(step, models.OperationStatusFailed, err.Error())
+			return err
+		}
+
+		if pvc.Spec.StorageClassName != nil && isWaitForFirstConsumer(ctx, k8sService, *pvc.Spec.StorageClassName) {
+			report(step, models.OperationStatusSucceeded, "存储类为 WaitForFirstConsumer 模式，PVC 将在首个 Pod 使用时绑定")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			err := fmt.Errorf("等待 PVC %s 绑定超时", name)
+			report(step, models.OperationStatusFailed, err.Error())
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// isWaitForFirstConsumer 判断存储类是否延迟绑定
+func isWaitForFirstConsumer(ctx context.Context, k8sService K8sServiceInterface, storageClassName string) bool {
+	scList, err := k8sService.GetStorageClasses(ctx)
+	if err != nil {
+		return false
+	}
+	for _, sc := range scList {
+		if sc.Name == storageClassName {
+			return sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+		}
+	}
+	return false
+}
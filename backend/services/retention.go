@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+)
+
+// 保留原因
+const (
+	retentionReasonLatest  = "latest"
+	retentionReasonKeepAll = "within-max-age"
+	retentionReasonLast    = "keep-last"
+	retentionReasonHourly  = "hourly"
+	retentionReasonDaily   = "daily"
+	retentionReasonWeekly  = "weekly"
+	retentionReasonMonthly = "monthly"
+)
+
+// RetentionFailedGracePeriod 报错的快照创建超过该时长后才清理，
+// CSI 驱动的临时错误可能在之后消失，快照仍会就绪
+const RetentionFailedGracePeriod = time.Hour
+
+// ParseRetentionAge 解析最长保留时间，支持 Go duration（如 "72h"）以及天数（如 "30d"）
+func ParseRetentionAge(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("无效的最长保留时间: %s", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("无效的最长保留时间: %s", value)
+	}
+	return duration, nil
+}
+
+// ValidateRetentionPolicy 校验保留策略
+func ValidateRetentionPolicy(policy *models.RetentionPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.KeepLast < 0 || policy.Hourly < 0 || policy.Daily < 0 || policy.Weekly < 0 || policy.Monthly < 0 {
+		return fmt.Errorf("保留数量不能为负数")
+	}
+	if policy.MaxAge != "" {
+		if _, err := ParseRetentionAge(policy.MaxAge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyRetentionPolicy 根据保留策略将快照划分为保留和清理两组
+//
+// 规则：
+//   - 正在创建中的快照（未就绪且未报错）既不计数也不清理
+//   - 报错的快照不计入任何规则，创建超过 RetentionFailedGracePeriod 后清理
+//   - 就绪的快照按创建时间从新到旧，命中 keepLast 或任一 GFS 桶即保留；
+//     未配置任何数量规则时全部保留
+//   - 配置了 maxAge 时，超过该时长的快照一律清理
+//   - 最新的一个就绪快照始终保留，避免策略配置错误导致没有可用快照
+func ApplyRetentionPolicy(snapshots []snapshotv1.VolumeSnapshot, policy *models.RetentionPolicy, now time.Time) *models.RetentionResult {
+	result := &models.RetentionResult{
+		Keep:  []models.RetentionDecision{},
+		Prune: []models.RetentionDecision{},
+	}
+	if policy == nil {
+		return result
+	}
+
+	var maxAge time.Duration
+	if policy.MaxAge != "" {
+		maxAge, _ = ParseRetentionAge(policy.MaxAge)
+	}
+
+	var ready []snapshotv1.VolumeSnapshot
+	for _, vs := range snapshots {
+		if vs.DeletionTimestamp != nil {
+			continue
+		}
+		switch {
+		case vs.Status != nil && vs.Status.ReadyToUse != nil && *vs.Status.ReadyToUse:
+			ready = append(ready, vs)
+		case vs.Status != nil && vs.Status.Error != nil:
+			if now.Sub(vs.CreationTimestamp.Time) > RetentionFailedGracePeriod {
+				result.Prune = append(result.Prune, newRetentionDecision(vs, "failed"))
+			}
+		}
+	}
+
+	// 从新到旧排序
+	sort.Slice(ready, func(i, j int) bool {
+		return ready[i].CreationTimestamp.Time.After(ready[j].CreationTimestamp.Time)
+	})
+
+	hasCountRules := policy.KeepLast > 0 || policy.Hourly > 0 || policy.Daily > 0 || policy.Weekly > 0 || policy.Monthly > 0
+	reasons := make([][]string, len(ready))
+
+	if !hasCountRules {
+		for i := range ready {
+			reasons[i] = append(reasons[i], retentionReasonKeepAll)
+		}
+	}
+	for i := 0; i < len(ready) && i < policy.KeepLast; i++ {
+		reasons[i] = append(reasons[i], retentionReasonLast)
+	}
+	markBuckets(ready, reasons, policy.Hourly, retentionReasonHourly, func(t time.Time) string {
+		return t.Format("2006-01-02 15")
+	})
+	markBuckets(ready, reasons, policy.Daily, retentionReasonDaily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	markBuckets(ready, reasons, policy.Weekly, retentionReasonWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+	markBuckets(ready, reasons, policy.Monthly, retentionReasonMonthly, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
+
+	for i, vs := range ready {
+		decision := newRetentionDecision(vs, reasons[i]...)
+
+		if i == 0 {
+			decision.Reasons = append([]string{retentionReasonLatest}, decision.Reasons...)
+			result.Keep = append(result.Keep, decision)
+			continue
+		}
+
+		if maxAge > 0 && now.Sub(vs.CreationTimestamp.Time) > maxAge {
+			decision.Reasons = []string{"older-than-max-age"}
+			result.Prune = append(result.Prune, decision)
+			continue
+		}
+
+		if len(reasons[i]) > 0 {
+			result.Keep = append(result.Keep, decision)
+		} else {
+			result.Prune = append(result.Prune, decision)
+		}
+	}
+
+	return result
+}
+
+// ApplyRetentionPolicyPerPVC 按源 PVC（命名空间 + 名称）分组分别应用保留策略并合并结果，
+// 选择器任务的每个 PVC 各自保留 keepLast 等数量的快照
+func ApplyRetentionPolicyPerPVC(snapshots []snapshotv1.VolumeSnapshot, policy *models.RetentionPolicy, now time.Time) *models.RetentionResult {
+	groups := make(map[string][]snapshotv1.VolumeSnapshot)
+	var keys []string
+	for _, vs := range snapshots {
+		source := ""
+		if vs.Spec.Source.PersistentVolumeClaimName != nil {
+			source = *vs.Spec.Source.PersistentVolumeClaimName
+		}
+		key := vs.Namespace + "/" + source
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], vs)
+	}
+	sort.Strings(keys)
+
+	result := &models.RetentionResult{
+		Keep:  []models.RetentionDecision{},
+		Prune: []models.RetentionDecision{},
+	}
+	for _, key := range keys {
+		group := ApplyRetentionPolicy(groups[key], policy, now)
+		result.Keep = append(result.Keep, group.Keep...)
+		result.Prune = append(result.Prune, group.Prune...)
+	}
+	return result
+}
+
+// markBuckets 按时间桶保留每个桶中最新的快照，最多保留 count 个桶
+func markBuckets(ready []snapshotv1.VolumeSnapshot, reasons [][]string, count int, reason string, bucketKey func(time.Time) string) {
+	if count <= 0 {
+		return
+	}
+
+	lastBucket := ""
+	kept := 0
+	for i, vs := range ready {
+		if kept >= count {
+			return
+		}
+		bucket := bucketKey(vs.CreationTimestamp.Time.Local())
+		if bucket == lastBucket {
+			continue
+		}
+		lastBucket = bucket
+		reasons[i] = append(reasons[i], reason)
+		kept++
+	}
+}
+
+func newRetentionDecision(vs snapshotv1.VolumeSnapshot, reasons ...string) models.RetentionDecision {
+	return models.RetentionDecision{
+		Name:      vs.Name,
+		Namespace: vs.Namespace,
+		CreatedAt: vs.CreationTimestamp.Time,
+		Reasons:   append([]string{}, reasons...),
+	}
+}
@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	snapshotclientset "github.com/kubernetes-csi/external-snapshotter/client/v6/clientset/versioned"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// 等待安全快照就绪的最长时间
+	SnapshotReadyTimeout = 10 * time.Minute
+	// 等待 Pod 退出、PVC 删除的最长时间
+	rollbackWaitTimeout = 5 * time.Minute
+	// 轮询间隔
+	rollbackPollInterval = 2 * time.Second
+	// 恢复副本数的重试次数和首次重试间隔（之后每次翻倍）
+	scaleUpAttempts = 5
+	scaleUpBackoff  = 2 * time.Second
+)
+
+// 回滚步骤名称
+const (
+	rollbackStepCheck          = "检查快照与 PVC"
+	rollbackStepFindWorkloads  = "查找使用 PVC 的工作负载"
+	rollbackStepSafetySnapshot = "创建安全快照"
+	rollbackStepScaleDown      = "缩容工作负载"
+	rollbackStepDeletePVC      = "删除原 PVC"
+	rollbackStepRecreatePVC    = "从快照重建 PVC"
+	rollbackStepScaleUp        = "恢复副本数"
+	rollbackStepRevert         = "失败回退"
+)
+
+// rollbackToVolumeSnapshot 将 PVC 原地回滚到指定快照（单集群与多集群服务共用）
+// 流程：查找挂载 PVC 的工作负载 -> 创建安全快照 -> 缩容到 0 -> 用快照重建同名 PVC -> 恢复副本数
+// 任一步骤失败都会尽量回退到操作前的状态
+func rollbackToVolumeSnapshot(ctx context.Context, clientSet kubernetes.Interface, snapshotClientSet snapshotclientset.Interface, info *models.VolumeSnapshotInfo, requestedBy string, report ProgressFunc) error {
+	vs := &info.VolumeSnapshot
+	namespace := vs.Namespace
+
+	// 1. 检查快照与 PVC
+	report(rollbackStepCheck, models.OperationStatusRunning, "")
+	if vs.Spec.Source.PersistentVolumeClaimName == nil {
+		err := fmt.Errorf("快照 %s 不是从 PVC 创建的，无法原地回滚", vs.Name)
+		report(rollbackStepCheck, models.OperationStatusFailed, err.Error())
+		return err
+	}
+	if vs.Status == nil || vs.Status.ReadyToUse == nil || !*vs.Status.ReadyToUse {
+		err := fmt.Errorf("快照 %s 尚未就绪，无法回滚", vs.Name)
+		report(rollbackStepCheck, models.OperationStatusFailed, err.Error())
+		return err
+	}
+	pvcName := *vs.Spec.Source.PersistentVolumeClaimName
+	originalPVC := info.PVC
+	if originalPVC == nil {
+		pvc, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{})
+		if err != nil {
+			err = fmt.Errorf("获取 PVC %s 失败: %v", pvcName, err)
+			report(rollbackStepCheck, models.OperationStatusFailed, err.Error())
+			return err
+		}
+		originalPVC = pvc
+	}
+	report(rollbackStepCheck, models.OperationStatusSucceeded, fmt.Sprintf("将 PVC %s 回滚到快照 %s", pvcName, vs.Name))
+
+	// 2. 查找使用 PVC 的工作负载
+	report(rollbackStepFindWorkloads, models.OperationStatusRunning, "")
+	workloads, err := findPVCWorkloads(ctx, clientSet, namespace, pvcName)
+	if err != nil {
+		report(rollbackStepFindWorkloads, models.OperationStatusFailed, err.Error())
+		return err
+	}
+	if len(workloads) == 0 {
+		report(rollbackStepFindWorkloads, models.OperationStatusSucceeded, "没有 Pod 挂载该 PVC")
+	} else {
+		report(rollbackStepFindWorkloads, models.OperationStatusSucceeded, "找到工作负载: "+describeWorkloads(workloads))
+	}
+
+	// 3. 创建安全快照，回滚失败或结果不符合预期时可以用它恢复
+	report(rollbackStepSafetySnapshot, models.OperationStatusRunning, "")
+	safetySnapshot, err := createSafetySnapshot(ctx, snapshotClientSet, vs, pvcName, requestedBy)
+	if err != nil {
+		report(rollbackStepSafetySnapshot, models.OperationStatusFailed, err.Error())
+		return err
+	}
+	if err := waitForSnapshotReady(ctx, snapshotClientSet, namespace, safetySnapshot.Name, SnapshotReadyTimeout); err != nil {
+		report(rollbackStepSafetySnapshot, models.OperationStatusFailed, err.Error())
+		return err
+	}
+	// 重新获取以拿到 restoreSize 等状态，失败回退时需要用它重建 PVC
+	safetySnapshot, err = snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, safetySnapshot.Name, metav1.GetOptions{})
+	if err != nil {
+		report(rollbackStepSafetySnapshot, models.OperationStatusFailed, err.Error())
+		return err
+	}
+	report(rollbackStepSafetySnapshot, models.OperationStatusSucceeded, "安全快照 "+safetySnapshot.Name+" 已就绪")
+
+	// 4. 缩容工作负载
+	report(rollbackStepScaleDown, models.OperationStatusRunning, "")
+	if err := scaleWorkloads(ctx, clientSet, namespace, workloads, true); err != nil {
+		report(rollbackStepScaleDown, models.OperationStatusFailed, err.Error())
+		return revertRollback(ctx, clientSet, namespace, workloads, nil, nil, report, err)
+	}
+	if err := waitForPVCUnused(ctx, clientSet, namespace, pvcName); err != nil {
+		report(rollbackStepScaleDown, models.OperationStatusFailed, err.Error())
+		return revertRollback(ctx, clientSet, namespace, workloads, nil, nil, report, err)
+	}
+	message := "所有挂载 PVC 的 Pod 已退出"
+	if len(workloads) > 0 {
+		// 记录原副本数，自动恢复失败时可据此手动恢复
+		message += "，原副本数: " + describeWorkloads(workloads)
+	}
+	report(rollbackStepScaleDown, models.OperationStatusSucceeded, message)
+
+	// 5. 删除原 PVC
+	report(rollbackStepDeletePVC, models.OperationStatusRunning, "")
+	if err := deletePVCAndWait(ctx, clientSet, namespace, pvcName); err != nil {
+		report(rollbackStepDeletePVC, models.OperationStatusFailed, err.Error())
+		return revertRollback(ctx, clientSet, namespace, workloads, originalPVC, safetySnapshot, report, err)
+	}
+	report(rollbackStepDeletePVC, models.OperationStatusSucceeded, "")
+
+	// 6. 从快照重建同名 PVC
+	report(rollbackStepRecreatePVC, models.OperationStatusRunning, "")
+	newPVC, err := buildRollbackPVC(vs, originalPVC)
+	if err == nil {
+		_, err = clientSet.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, newPVC, metav1.CreateOptions{})
+	}
+	if err != nil {
+		report(rollbackStepRecreatePVC, models.OperationStatusFailed, err.Error())
+		return revertRollback(ctx, clientSet, namespace, workloads, originalPVC, safetySnapshot, report, err)
+	}
+	report(rollbackStepRecreatePVC, models.OperationStatusSucceeded, "PVC "+pvcName+" 已从快照 "+vs.Name+" 重建")
+
+	// 7. 恢复副本数
+	report(rollbackStepScaleUp, models.OperationStatusRunning, "")
+	if err := restoreWorkloadReplicas(ctx, clientSet, namespace, workloads); err != nil {
+		report(rollbackStepScaleUp, models.OperationStatusFailed, err.Error())
+		return err
+	}
+	report(rollbackStepScaleUp, models.OperationStatusSucceeded, describeWorkloads(workloads))
+
+	return nil
+}
+
+// revertRollback 回滚失败时尽量恢复原状：必要时用安全快照重建 PVC，并恢复副本数
+func revertRollback(ctx context.Context, clientSet kubernetes.Interface, namespace string, workloads []models.WorkloadRef, originalPVC *corev1.PersistentVolumeClaim, safetySnapshot *snapshotv1.VolumeSnapshot, report ProgressFunc, cause error) error {
+	report(rollbackStepRevert, models.OperationStatusRunning, "")
+
+	var revertErrors []string
+
+	// 原 PVC 已被删除时，使用安全快照重建
+	if originalPVC != nil && safetySnapshot != nil {
+		_, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, originalPVC.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			pvc, buildErr := buildRollbackPVC(safetySnapshot, originalPVC)
+			if buildErr == nil {
+				_, buildErr = clientSet.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{})
+			}
+			if buildErr != nil {
+				revertErrors = append(revertErrors, fmt.Sprintf("使用安全快照 %s 重建 PVC 失败: %v", safetySnapshot.Name, buildErr))
+			}
+		}
+	}
+
+	if err := restoreWorkloadReplicas(ctx, clientSet, namespace, workloads); err != nil {
+		revertErrors = append(revertErrors, err.Error())
+	}
+
+	if len(revertErrors) > 0 {
+		report(rollbackStepRevert, models.OperationStatusFailed, strings.Join(revertErrors, "; "))
+		return fmt.Errorf("%v；回退失败: %s", cause, strings.Join(revertErrors, "; "))
+	}
+
+	report(rollbackStepRevert, models.OperationStatusSucceeded, "已恢复到回滚前的状态")
+	return cause
+}
+
+// findPVCWorkloads 查找挂载 PVC 的 Pod 所属的 Deployment/StatefulSet
+func findPVCWorkloads(ctx context.Context, clientSet kubernetes.Interface, namespace, pvcName string) ([]models.WorkloadRef, error) {
+	pods, err := podsUsingPVC(ctx, clientSet, namespace, pvcName)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var workloads []models.WorkloadRef
+	for _, pod := range pods {
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil {
+			return nil, fmt.Errorf("Pod %s 没有控制器，无法安全缩容", pod.Name)
+		}
+
+		var ref models.WorkloadRef
+		switch owner.Kind {
+		case "ReplicaSet":
+			rs, err := clientSet.AppsV1().ReplicaSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("获取 ReplicaSet %s 失败: %v", owner.Name, err)
+			}
+			rsOwner := metav1.GetControllerOf(rs)
+			if rsOwner == nil || rsOwner.Kind != "Deployment" {
+				return nil, fmt.Errorf("ReplicaSet %s 不属于 Deployment，无法安全缩容", rs.Name)
+			}
+			ref = models.WorkloadRef{Kind: "Deployment", Name: rsOwner.Name}
+		case "StatefulSet":
+			ref = models.WorkloadRef{Kind: "StatefulSet", Name: owner.Name}
+		default:
+			return nil, fmt.Errorf("Pod %s 由 %s %s 管理，暂不支持自动缩容", pod.Name, owner.Kind, owner.Name)
+		}
+
+		key := ref.Kind + "/" + ref.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		scale, err := getWorkloadScale(ctx, clientSet, namespace, ref)
+		if err != nil {
+			return nil, err
+		}
+		ref.Replicas = scale.Spec.Replicas
+		workloads = append(workloads, ref)
+	}
+
+	return workloads, nil
+}
+
+// podsUsingPVC 返回挂载了指定 PVC 且仍在运行的 Pod
+func podsUsingPVC(ctx context.Context, clientSet kubernetes.Interface, namespace, pvcName string) ([]corev1.Pod, error) {
+	podList, err := clientSet.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("获取 Pod 列表失败: %v", err)
+	}
+
+	var pods []corev1.Pod
+	for _, pod := range podList.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == pvcName {
+				pods = append(pods, pod)
+				break
+			}
+		}
+	}
+	return pods, nil
+}
+
+// getWorkloadScale 获取工作负载的 scale 子资源
+func getWorkloadScale(ctx context.Context, clientSet kubernetes.Interface, namespace string, ref models.WorkloadRef) (*autoscalingv1.Scale, error) {
+	switch ref.Kind {
+	case "Deployment":
+		return clientSet.AppsV1().Deployments(namespace).GetScale(ctx, ref.Name, metav1.GetOptions{})
+	case "StatefulSet":
+		return clientSet.AppsV1().StatefulSets(namespace).GetScale(ctx, ref.Name, metav1.GetOptions{})
+	}
+	return nil, fmt.Errorf("不支持的工作负载类型: %s", ref.Kind)
+}
+
+// scaleWorkloads 将工作负载缩容到 0，或恢复到记录的原副本数
+func scaleWorkloads(ctx context.Context, clientSet kubernetes.Interface, namespace string, workloads []models.WorkloadRef, toZero bool) error {
+	var errors []string
+	for _, ref := range workloads {
+		scale, err := getWorkloadScale(ctx, clientSet, namespace, ref)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s %s: %v", ref.Kind, ref.Name, err))
+			continue
+		}
+
+		if toZero {
+			scale.Spec.Replicas = 0
+		} else {
+			scale.Spec.Replicas = ref.Replicas
+		}
+
+		switch ref.Kind {
+		case "Deployment":
+			_, err = clientSet.AppsV1().Deployments(namespace).UpdateScale(ctx, ref.Name, scale, metav1.UpdateOptions{})
+		case "StatefulSet":
+			_, err = clientSet.AppsV1().StatefulSets(namespace).UpdateScale(ctx, ref.Name, scale, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s %s: %v", ref.Kind, ref.Name, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("调整副本数失败: %s", strings.Join(errors, "; "))
+	}
+	return nil
+}
+
+// restoreWorkloadReplicas 恢复工作负载的原副本数，失败时退避重试
+// 使用不可取消的 context，请求结束或超时后也不会让工作负载停留在 0 副本；
+// 最终失败时错误中带有原副本数，便于手动恢复
+func restoreWorkloadReplicas(ctx context.Context, clientSet kubernetes.Interface, namespace string, workloads []models.WorkloadRef) error {
+	if len(workloads) == 0 {
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	backoff := scaleUpBackoff
+	var err error
+	for attempt := 1; attempt <= scaleUpAttempts; attempt++ {
+		if err = scaleWorkloads(ctx, clientSet, namespace, workloads, false); err == nil {
+			return nil
+		}
+		fmt.Printf("Warning: restoring replicas in %s failed (attempt %d/%d): %v\n", namespace, attempt, scaleUpAttempts, err)
+		if attempt < scaleUpAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("%v（已重试 %d 次），请手动恢复原副本数: %s", err, scaleUpAttempts, describeWorkloads(workloads))
+}
+
+// waitForPVCUnused 等待所有挂载 PVC 的 Pod 退出
+func waitForPVCUnused(ctx context.Context, clientSet kubernetes.Interface, namespace, pvcName string) error {
+	return pollUntil(ctx, rollbackWaitTimeout, func() (bool, error) {
+		pods, err := podsUsingPVC(ctx, clientSet, namespace, pvcName)
+		if err != nil {
+			return false, err
+		}
+		return len(pods) == 0, nil
+	}, fmt.Sprintf("等待挂载 PVC %s 的 Pod 退出超时", pvcName))
+}
+
+// deletePVCAndWait 删除 PVC 并等待其真正消失（pvc-protection finalizer 移除）
+func deletePVCAndWait(ctx context.Context, clientSet kubernetes.Interface, namespace, pvcName string) error {
+	err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvcName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("删除 PVC %s 失败: %v", pvcName, err)
+	}
+
+	return pollUntil(ctx, rollbackWaitTimeout, func() (bool, error) {
+		_, err := clientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}, fmt.Sprintf("等待 PVC %s 删除超时", pvcName))
+}
+
+// createSafetySnapshot 在回滚前为当前 PVC 创建快照
+func createSafetySnapshot(ctx context.Context, snapshotClientSet snapshotclientset.Interface, target *snapshotv1.VolumeSnapshot, pvcName, createdBy string) (*snapshotv1.VolumeSnapshot, error) {
+	now := time.Now()
+	name := fmt.Sprintf("%s-pre-rollback-%d", pvcName, now.Unix())
+	source := pvcName
+
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: target.Namespace,
+			Labels: map[string]string{
+				"app": "k8s-volume-snapshots",
+			},
+			Annotations: map[string]string{
+				"k8s-volume-snapshots/rollback-to": target.Name,
+				"k8s-volume-snapshots/created-at":  now.Format(time.RFC3339),
+			},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &source,
+			},
+			VolumeSnapshotClassName: target.Spec.VolumeSnapshotClassName,
+		},
+	}
+	if createdBy != "" {
+		vs.Labels["created-by"] = CreatedByLabelValue(createdBy)
+		vs.Annotations["k8s-volume-snapshots/created-by"] = createdBy
+	}
+
+	created, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(target.Namespace).Create(ctx, vs, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("创建安全快照失败: %v", err)
+	}
+	return created, nil
+}
+
+// waitForSnapshotReady 等待快照 ReadyToUse，快照报错时立即返回
+func waitForSnapshotReady(ctx context.Context, snapshotClientSet snapshotclientset.Interface, namespace, name string, timeout time.Duration) error {
+	return pollUntil(ctx, timeout, func() (bool, error) {
+		vs, err := snapshotClientSet.SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if vs.Status == nil {
+			return false, nil
+		}
+		if vs.Status.Error != nil && vs.Status.Error.Message != nil {
+			return false, fmt.Errorf("快照 %s 创建失败: %s", name, *vs.Status.Error.Message)
+		}
+		return vs.Status.ReadyToUse != nil && *vs.Status.ReadyToUse, nil
+	}, fmt.Sprintf("等待快照 %s 就绪超时", name))
+}
+
+// buildRollbackPVC 基于原 PVC 的定义生成从快照恢复的同名 PVC
+func buildRollbackPVC(vs *snapshotv1.VolumeSnapshot, originalPVC *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
+	// 原 PVC 扩容过时保持原容量
+	req := models.RestoreVolumeSnapshotRequest{PVCName: originalPVC.Name}
+	if size, ok := originalPVC.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		if vs.Status == nil || vs.Status.RestoreSize == nil || size.Cmp(*vs.Status.RestoreSize) >= 0 {
+			req.Size = size.String()
+		}
+	}
+
+	pvc, err := buildRestorePVC(vs, originalPVC, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 保留原 PVC 的标签和用户注解，StatefulSet 等控制器依赖这些标签
+	pvc.Labels = make(map[string]string)
+	for k, v := range originalPVC.Labels {
+		pvc.Labels[k] = v
+	}
+	for k, v := range originalPVC.Annotations {
+		if strings.Contains(k, "kubernetes.io/") {
+			continue
+		}
+		if _, exists := pvc.Annotations[k]; !exists {
+			pvc.Annotations[k] = v
+		}
+	}
+	// 不复制 spec.selector：带快照 dataSource 的 PVC 由制备器动态创建卷，
+	// selector 会让其只能匹配静态 PV，导致新 PVC 永远无法绑定
+
+	return pvc, nil
+}
+
+// pollUntil 按固定间隔执行 check，直到返回 true、出错或超时
+func pollUntil(ctx context.Context, timeout time.Duration, check func() (bool, error), timeoutMessage string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(rollbackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s", timeoutMessage)
+		case <-ticker.C:
+		}
+	}
+}
+
+// describeWorkloads 生成工作负载描述，用于进度信息
+func describeWorkloads(workloads []models.WorkloadRef) string {
+	parts := make([]string, 0, len(workloads))
+	for _, ref := range workloads {
+		parts = append(parts, fmt.Sprintf("%s/%s(副本数 %d)", ref.Kind, ref.Name, ref.Replicas))
+	}
+	return strings.Join(parts, ", ")
+}
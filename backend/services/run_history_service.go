@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 定时任务执行历史存储文件路径
+	RunHistoryDataFile = "/data/scheduled_runs.json"
+	// 每个任务保留的最大执行记录数
+	MaxRunsPerTask = 200
+)
+
+// RunHistoryService 定时任务执行历史
+type RunHistoryService struct {
+	runs     map[string][]models.ScheduledRun // taskID -> 执行记录（从新到旧）
+	mutex    sync.RWMutex
+	dataFile string
+}
+
+func NewRunHistoryService() *RunHistoryService {
+	service := &RunHistoryService{
+		runs:     make(map[string][]models.ScheduledRun),
+		dataFile: RunHistoryDataFile,
+	}
+
+	service.loadRuns()
+
+	return service
+}
+
+// loadRuns 从文件加载执行历史
+func (s *RunHistoryService) loadRuns() {
+	if _, err := os.Stat(s.dataFile); os.IsNotExist(err) {
+		fmt.Printf("定时任务执行历史文件不存在，使用空的执行历史\n")
+		return
+	}
+
+	data, err := ioutil.ReadFile(s.dataFile)
+	if err != nil {
+		fmt.Printf("读取定时任务执行历史文件失败: %v\n", err)
+		return
+	}
+
+	if err := json.Unmarshal(data, &s.runs); err != nil {
+		fmt.Printf("解析定时任务执行历史失败: %v\n", err)
+		s.runs = make(map[string][]models.ScheduledRun)
+		return
+	}
+
+	fmt.Printf("成功加载 %d 个定时任务的执行历史\n", len(s.runs))
+}
+
+// saveRuns 保存执行历史到文件
+func (s *RunHistoryService) saveRuns() error {
+	// 确保目录存在
+	dir := filepath.Dir(s.dataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	data, err := json.MarshalIndent(s.runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化执行历史失败: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.dataFile, data, 0644); err != nil {
+		return fmt.Errorf("写入执行历史文件失败: %v", err)
+	}
+
+	return nil
+}
+
+// Record 新增或更新一条执行记录（按 ID 匹配）
+func (s *RunHistoryService) Record(run models.ScheduledRun) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	runs := s.runs[run.TaskID]
+	updated := false
+	for i := range runs {
+		if runs[i].ID == run.ID {
+			runs[i] = run
+			updated = true
+			break
+		}
+	}
+
+	if !updated {
+		runs = append([]models.ScheduledRun{run}, runs...)
+		if len(runs) > MaxRunsPerTask {
+			runs = runs[:MaxRunsPerTask]
+		}
+	}
+	s.runs[run.TaskID] = runs
+
+	return s.saveRuns()
+}
+
+// List 分页获取任务的执行记录，page 从 1 开始
+func (s *RunHistoryService) List(taskID string, page, pageSize int) models.ScheduledRunPage {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	runs := s.runs[taskID]
+	result := models.ScheduledRunPage{
+		Items:    []models.ScheduledRun{},
+		Total:    len(runs),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	start := (page - 1) * pageSize
+	if start >= len(runs) {
+		return result
+	}
+	end := start + pageSize
+	if end > len(runs) {
+		end = len(runs)
+	}
+	result.Items = append(result.Items, runs[start:end]...)
+
+	return result
+}
+
+// DeleteTask 删除任务的全部执行记录
+func (s *RunHistoryService) DeleteTask(taskID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.runs[taskID]; !exists {
+		return nil
+	}
+	delete(s.runs, taskID)
+
+	return s.saveRuns()
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"k8s-volume-snapshots/models"
+)
+
+// ValidateScheduledTarget 校验定时任务的目标 PVC：单个 PVC、命名空间内的 PVC 选择器、命名空间选择器三选一
+func ValidateScheduledTarget(task *models.ScheduledSnapshot) error {
+	if task.NamespaceSelector != "" {
+		if task.Namespace != "" {
+			return fmt.Errorf("namespace 和 namespaceSelector 不能同时指定")
+		}
+		if task.PVCName != "" {
+			return fmt.Errorf("使用 namespaceSelector 时只能通过 pvcSelector 选择 PVC")
+		}
+		if _, err := labels.Parse(task.NamespaceSelector); err != nil {
+			return fmt.Errorf("无效的 namespaceSelector: %v", err)
+		}
+	} else {
+		if task.Namespace == "" {
+			return fmt.Errorf("必须指定 namespace 或 namespaceSelector")
+		}
+		if (task.PVCName == "") == (task.PVCSelector == "") {
+			return fmt.Errorf("pvcName 和 pvcSelector 必须指定其中一个")
+		}
+	}
+
+	if task.PVCSelector != "" {
+		if _, err := labels.Parse(task.PVCSelector); err != nil {
+			return fmt.Errorf("无效的 pvcSelector: %v", err)
+		}
+	}
+	return nil
+}
+
+// IsSelectorTask 判断任务是否通过标签选择器选择 PVC
+func IsSelectorTask(task *models.ScheduledSnapshot) bool {
+	return task.PVCSelector != "" || task.NamespaceSelector != ""
+}
+
+// SelectPVCs 从 PVC 列表中选出选择器任务的目标：命名空间和标签都匹配、已绑定且未在删除中的 PVC，
+// 按命名空间和名称排序。namespaces 只在使用 namespaceSelector 时需要
+func SelectPVCs(task *models.ScheduledSnapshot, pvcs []corev1.PersistentVolumeClaim, namespaces []corev1.Namespace) ([]corev1.PersistentVolumeClaim, error) {
+	pvcSelector, err := labels.Parse(task.PVCSelector)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 pvcSelector: %v", err)
+	}
+
+	allowed := map[string]bool{task.Namespace: true}
+	if task.NamespaceSelector != "" {
+		namespaceSelector, err := labels.Parse(task.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 namespaceSelector: %v", err)
+		}
+		allowed = make(map[string]bool)
+		for _, ns := range namespaces {
+			if ns.DeletionTimestamp == nil && namespaceSelector.Matches(labels.Set(ns.Labels)) {
+				allowed[ns.Name] = true
+			}
+		}
+	}
+
+	selected := []corev1.PersistentVolumeClaim{}
+	for _, pvc := range pvcs {
+		if !allowed[pvc.Namespace] || !pvcSelector.Matches(labels.Set(pvc.Labels)) {
+			continue
+		}
+		// 新建的 PVC 绑定后才能创建快照，下次执行时再纳入
+		if pvc.DeletionTimestamp != nil || pvc.Status.Phase != corev1.ClaimBound {
+			continue
+		}
+		selected = append(selected, pvc)
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		if selected[i].Namespace != selected[j].Namespace {
+			return selected[i].Namespace < selected[j].Namespace
+		}
+		return selected[i].Name < selected[j].Name
+	})
+	return selected, nil
+}
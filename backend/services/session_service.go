@@ -0,0 +1,277 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 会话数据（刷新令牌和已吊销的 jti）存储文件路径
+	SessionDataFile = "/data/sessions.json"
+	// 刷新令牌默认有效期
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// ErrInvalidRefreshToken 刷新令牌不存在、已过期、已使用或已吊销
+var ErrInvalidRefreshToken = errors.New("登录已过期，请重新登录")
+
+// RefreshSession 刷新令牌记录，同一次登录轮换出的令牌属于同一个 Family
+type RefreshSession struct {
+	TokenHash  string    `json:"tokenHash"`
+	Family     string    `json:"family"`
+	Username   string    `json:"username"`
+	UserID     string    `json:"userId"`
+	Generation int       `json:"generation"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Used 已轮换过的令牌，再次出现说明令牌被盗用
+	Used bool `json:"used"`
+}
+
+type sessionData struct {
+	RefreshTokens []RefreshSession     `json:"refreshTokens"`
+	RevokedJTIs   map[string]time.Time `json:"revokedJtis"`
+}
+
+// SessionService 管理轮换的刷新令牌和按 jti 吊销的访问令牌
+type SessionService struct {
+	refreshTokens map[string]*RefreshSession // key 为令牌哈希
+	revokedJTIs   map[string]time.Time       // jti -> 访问令牌过期时间，过期后清理
+	mutex         sync.RWMutex
+	dataFile      string
+	refreshTTL    time.Duration
+}
+
+func NewSessionService() *SessionService {
+	service := &SessionService{
+		refreshTokens: make(map[string]*RefreshSession),
+		revokedJTIs:   make(map[string]time.Time),
+		dataFile:      SessionDataFile,
+		refreshTTL:    DefaultRefreshTokenTTL,
+	}
+
+	if ttl := os.Getenv("REFRESH_TOKEN_TTL"); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err == nil && d > 0 {
+			service.refreshTTL = d
+		} else {
+			fmt.Printf("警告: 无效的 REFRESH_TOKEN_TTL %q，使用默认值 %v\n", ttl, DefaultRefreshTokenTTL)
+		}
+	}
+
+	service.load()
+	return service
+}
+
+// RefreshTTL 刷新令牌有效期
+func (s *SessionService) RefreshTTL() time.Duration {
+	return s.refreshTTL
+}
+
+// load 从文件加载会话数据
+func (s *SessionService) load() {
+	data, err := ioutil.ReadFile(s.dataFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("读取会话数据文件失败: %v\n", err)
+		}
+		return
+	}
+
+	var stored sessionData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		fmt.Printf("解析会话数据失败: %v\n", err)
+		return
+	}
+
+	for _, session := range stored.RefreshTokens {
+		sessionCopy := session
+		s.refreshTokens[session.TokenHash] = &sessionCopy
+	}
+	for jti, expiresAt := range stored.RevokedJTIs {
+		s.revokedJTIs[jti] = expiresAt
+	}
+}
+
+// saveLocked 清理过期记录后保存到文件，调用方需持有锁
+func (s *SessionService) saveLocked() error {
+	now := time.Now()
+	for hash, session := range s.refreshTokens {
+		if now.After(session.ExpiresAt) {
+			delete(s.refreshTokens, hash)
+		}
+	}
+	for jti, expiresAt := range s.revokedJTIs {
+		if now.After(expiresAt) {
+			delete(s.revokedJTIs, jti)
+		}
+	}
+
+	dir := filepath.Dir(s.dataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	stored := sessionData{
+		RefreshTokens: make([]RefreshSession, 0, len(s.refreshTokens)),
+		RevokedJTIs:   s.revokedJTIs,
+	}
+	for _, session := range s.refreshTokens {
+		stored.RefreshTokens = append(stored.RefreshTokens, *session)
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化会话数据失败: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.dataFile, data, 0600); err != nil {
+		return fmt.Errorf("写入会话数据文件失败: %v", err)
+	}
+
+	return nil
+}
+
+// IssueRefreshToken 登录成功后签发新的刷新令牌
+func (s *SessionService) IssueRefreshToken(user *models.User) (string, error) {
+	family, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.issueLocked(user.Username, user.ID, user.TokenGeneration, family)
+}
+
+func (s *SessionService) issueLocked(username, userID string, generation int, family string) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("生成刷新令牌失败: %v", err)
+	}
+
+	now := time.Now()
+	session := &RefreshSession{
+		TokenHash:  hashRefreshToken(token),
+		Family:     family,
+		Username:   username,
+		UserID:     userID,
+		Generation: generation,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	s.refreshTokens[session.TokenHash] = session
+
+	if err := s.saveLocked(); err != nil {
+		delete(s.refreshTokens, session.TokenHash)
+		return "", err
+	}
+	return token, nil
+}
+
+// Rotate 使用刷新令牌换取新的刷新令牌，旧令牌作废
+// 已使用过的令牌再次出现时吊销整个 Family，迫使攻击者和合法用户都重新登录
+func (s *SessionService) Rotate(token string) (*RefreshSession, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.refreshTokens[hashRefreshToken(token)]
+	if !exists || time.Now().After(session.ExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	if session.Used {
+		fmt.Printf("检测到刷新令牌重复使用，吊销用户 %s 的会话 %s\n", session.Username, session.Family)
+		s.revokeFamilyLocked(session.Family)
+		if err := s.saveLocked(); err != nil {
+			fmt.Printf("保存会话数据失败: %v\n", err)
+		}
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	session.Used = true
+	newToken, err := s.issueLocked(session.Username, session.UserID, session.Generation, session.Family)
+	if err != nil {
+		session.Used = false
+		return nil, "", err
+	}
+
+	result := *session
+	return &result, newToken, nil
+}
+
+// RevokeRefreshToken 吊销刷新令牌所在的整个会话（退出登录）
+func (s *SessionService) RevokeRefreshToken(token string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, exists := s.refreshTokens[hashRefreshToken(token)]
+	if !exists {
+		return nil
+	}
+	s.revokeFamilyLocked(session.Family)
+	return s.saveLocked()
+}
+
+// RevokeFamily 吊销某次登录产生的所有刷新令牌
+func (s *SessionService) RevokeFamily(family string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.revokeFamilyLocked(family)
+	return s.saveLocked()
+}
+
+func (s *SessionService) revokeFamilyLocked(family string) {
+	for hash, session := range s.refreshTokens {
+		if session.Family == family {
+			delete(s.refreshTokens, hash)
+		}
+	}
+}
+
+// RevokeJTI 将访问令牌加入吊销列表，记录保留到令牌过期
+func (s *SessionService) RevokeJTI(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.revokedJTIs[jti] = expiresAt
+	return s.saveLocked()
+}
+
+// IsRevoked 访问令牌是否已被吊销
+func (s *SessionService) IsRevoked(jti string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, revoked := s.revokedJTIs[jti]
+	return revoked
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// buildVolumeSnapshotInfos 为每个快照附加绑定的 VolumeSnapshotContent 和源 PVC
+// getContent、getPVC 应从缓存或一次性 List 的结果中查找，避免逐个快照请求 apiserver
+func buildVolumeSnapshotInfos(snapshots []snapshotv1.VolumeSnapshot, getContent func(name string) *snapshotv1.VolumeSnapshotContent, getPVC func(namespace, name string) *corev1.PersistentVolumeClaim) []models.VolumeSnapshotInfo {
+	result := make([]models.VolumeSnapshotInfo, 0, len(snapshots))
+	for _, vs := range snapshots {
+		info := models.VolumeSnapshotInfo{
+			VolumeSnapshot: vs,
+		}
+
+		if vs.Status != nil && vs.Status.BoundVolumeSnapshotContentName != nil {
+			info.VolumeSnapshotContent = getContent(*vs.Status.BoundVolumeSnapshotContentName)
+		}
+
+		if vs.Spec.Source.PersistentVolumeClaimName != nil {
+			info.PVC = getPVC(vs.Namespace, *vs.Spec.Source.PersistentVolumeClaimName)
+		}
+
+		result = append(result, info)
+	}
+	return result
+}
+
+// newPVCWithPVInfo 组合 PVC 与其绑定 PV 的 VolumeAttributes，pv 为 nil 时返回空的 VolumeAttributes
+func newPVCWithPVInfo(pvc corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) models.PVCWithPVInfo {
+	info := models.PVCWithPVInfo{
+		PVC:              pvc,
+		VolumeAttributes: &models.PVVolumeAttributes{},
+	}
+	if pv != nil && pv.Spec.CSI != nil && pv.Spec.CSI.VolumeAttributes != nil {
+		info.VolumeAttributes = &models.PVVolumeAttributes{
+			ImageName: pv.Spec.CSI.VolumeAttributes["imageName"],
+			Pool:      pv.Spec.CSI.VolumeAttributes["pool"],
+		}
+	}
+	return info
+}
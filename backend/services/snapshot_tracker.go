@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v6/apis/volumesnapshot/v1"
+)
+
+const (
+	// 跟踪结果在内存中保留的时长
+	trackingRetention = time.Hour
+	// 轮询快照状态的间隔
+	trackingPollInterval = 3 * time.Second
+)
+
+// trackedSnapshot 单个快照的跟踪状态，done 在跟踪结束时关闭
+type trackedSnapshot struct {
+	result models.SnapshotTrackingResult
+	done   chan struct{}
+}
+
+// SnapshotTracker 跟踪新创建的 VolumeSnapshot，直到 ReadyToUse、失败或超时
+// CSI 驱动可能在 Create 返回之后才通过 status.error 报告失败，因此创建成功不代表快照可用
+type SnapshotTracker struct {
+	k8sService K8sServiceInterface
+	timeout    time.Duration
+	tracked    map[string]*trackedSnapshot
+	mutex      sync.RWMutex
+}
+
+func NewSnapshotTracker(k8sService K8sServiceInterface) *SnapshotTracker {
+	timeout := SnapshotReadyTimeout
+	// 超时时间可通过环境变量配置，如 "15m"
+	if value := os.Getenv("SNAPSHOT_READY_TIMEOUT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			timeout = parsed
+		} else {
+			log.Printf("Invalid SNAPSHOT_READY_TIMEOUT %q, using default %v", value, timeout)
+		}
+	}
+
+	return &SnapshotTracker{
+		k8sService: k8sService,
+		timeout:    timeout,
+		tracked:    make(map[string]*trackedSnapshot),
+	}
+}
+
+// Timeout 返回等待快照就绪的超时时间
+func (t *SnapshotTracker) Timeout() time.Duration {
+	return t.timeout
+}
+
+// Track 开始在后台跟踪快照，clusterName 为空时使用默认集群
+// 重复跟踪同一快照时返回已有的跟踪状态
+func (t *SnapshotTracker) Track(clusterName, namespace, name string) models.SnapshotTrackingResult {
+	clusterName = t.resolveCluster(clusterName)
+	key := trackingKey(clusterName, namespace, name)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pruneLocked()
+
+	if existing, exists := t.tracked[key]; exists {
+		return existing.result
+	}
+
+	entry := &trackedSnapshot{
+		result: models.SnapshotTrackingResult{
+			Cluster:   clusterName,
+			Namespace: namespace,
+			Name:      name,
+			Phase:     models.TrackingPhasePending,
+			StartedAt: time.Now(),
+		},
+		done: make(chan struct{}),
+	}
+	t.tracked[key] = entry
+
+	go t.run(key, entry)
+
+	return entry.result
+}
+
+// Wait 等待快照跟踪结束，ctx 取消时返回当前状态和 ctx 的错误
+func (t *SnapshotTracker) Wait(ctx context.Context, clusterName, namespace, name string) (models.SnapshotTrackingResult, error) {
+	key := trackingKey(t.resolveCluster(clusterName), namespace, name)
+
+	t.mutex.RLock()
+	entry, exists := t.tracked[key]
+	t.mutex.RUnlock()
+	if !exists {
+		return models.SnapshotTrackingResult{}, fmt.Errorf("snapshot %s/%s is not being tracked", namespace, name)
+	}
+
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		result, _ := t.Get(clusterName, namespace, name)
+		return result, ctx.Err()
+	}
+
+	result, _ := t.Get(clusterName, namespace, name)
+	return result, nil
+}
+
+// Get 获取快照的跟踪状态
+func (t *SnapshotTracker) Get(clusterName, namespace, name string) (models.SnapshotTrackingResult, bool) {
+	key := trackingKey(t.resolveCluster(clusterName), namespace, name)
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	entry, exists := t.tracked[key]
+	if !exists {
+		return models.SnapshotTrackingResult{}, false
+	}
+	return entry.result, true
+}
+
+// run 轮询快照状态直到结束
+func (t *SnapshotTracker) run(key string, entry *trackedSnapshot) {
+	defer close(entry.done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(trackingPollInterval)
+	defer ticker.Stop()
+
+	cluster, namespace, name := entry.result.Cluster, entry.result.Namespace, entry.result.Name
+
+	for {
+		vs, err := t.getVolumeSnapshot(ctx, cluster, namespace, name)
+		if err == nil && vs.Status != nil {
+			if vs.Status.Error != nil && vs.Status.Error.Message != nil {
+				t.finish(key, vs, models.TrackingPhaseFailed, *vs.Status.Error.Message)
+				return
+			}
+			if vs.Status.ReadyToUse != nil && *vs.Status.ReadyToUse {
+				t.finish(key, vs, models.TrackingPhaseReady, "")
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			message := fmt.Sprintf("timed out waiting for snapshot to become ready after %v", t.timeout)
+			if err != nil {
+				message = fmt.Sprintf("%s: %v", message, err)
+			}
+			t.finish(key, vs, models.TrackingPhaseTimeout, message)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// finish 记录跟踪结果
+func (t *SnapshotTracker) finish(key string, vs *snapshotv1.VolumeSnapshot, phase, message string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entry, exists := t.tracked[key]
+	if !exists {
+		return
+	}
+
+	now := time.Now()
+	entry.result.Phase = phase
+	entry.result.Error = message
+	entry.result.FinishedAt = &now
+	entry.result.DurationSeconds = now.Sub(entry.result.StartedAt).Seconds()
+
+	if vs != nil && vs.Status != nil {
+		if vs.Status.BoundVolumeSnapshotContentName != nil {
+			entry.result.BoundVolumeSnapshotContentName = *vs.Status.BoundVolumeSnapshotContentName
+		}
+		if vs.Status.RestoreSize != nil {
+			entry.result.RestoreSize = vs.Status.RestoreSize.String()
+		}
+	}
+}
+
+// getVolumeSnapshot 在指定集群（为空时为默认集群）中获取快照
+func (t *SnapshotTracker) getVolumeSnapshot(ctx context.Context, clusterName, namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
+	if multiClusterService, ok := t.k8sService.(MultiClusterK8sServiceInterface); ok && clusterName != "" {
+		return multiClusterService.GetVolumeSnapshotInCluster(ctx, clusterName, namespace, name)
+	}
+	return t.k8sService.GetVolumeSnapshot(ctx, namespace, name)
+}
+
+// resolveCluster 将空集群名解析为默认集群，使跟踪记录始终对应明确的集群
+func (t *SnapshotTracker) resolveCluster(clusterName string) string {
+	if clusterName != "" {
+		return clusterName
+	}
+	if multiClusterService, ok := t.k8sService.(MultiClusterK8sServiceInterface); ok {
+		return multiClusterService.GetDefaultCluster()
+	}
+	return ""
+}
+
+// pruneLocked 清理已结束且过期的跟踪记录
+func (t *SnapshotTracker) pruneLocked() {
+	for key, entry := range t.tracked {
+		if entry.result.FinishedAt != nil && time.Since(*entry.result.FinishedAt) > trackingRetention {
+			delete(t.tracked, key)
+		}
+	}
+}
+
+func trackingKey(clusterName, namespace, name string) string {
+	return clusterName + "/" + namespace + "/" + name
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+// StreamTicketTTL 事件流票据的有效期，客户端应在获取后立即建立连接
+const StreamTicketTTL = 30 * time.Second
+
+// streamTicket 一次性票据对应的用户身份
+type streamTicket struct {
+	username    string
+	userID      string
+	tokenScopes []string
+	expiresAt   time.Time
+}
+
+// StreamTicketService 签发事件流的一次性短期票据
+// 浏览器 EventSource 无法设置请求头，只能在查询参数中传递凭据；
+// 使用票据代替访问令牌，避免令牌出现在代理日志和浏览器历史中
+type StreamTicketService struct {
+	tickets map[string]streamTicket
+	mutex   sync.Mutex
+}
+
+func NewStreamTicketService() *StreamTicketService {
+	return &StreamTicketService{
+		tickets: make(map[string]streamTicket),
+	}
+}
+
+// Issue 为已认证的用户签发票据，API token 的作用范围随票据保留
+func (s *StreamTicketService) Issue(user *models.User) (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	ticket := hex.EncodeToString(bytes)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for key, existing := range s.tickets {
+		if now.After(existing.expiresAt) {
+			delete(s.tickets, key)
+		}
+	}
+	s.tickets[ticket] = streamTicket{
+		username:    user.Username,
+		userID:      user.ID,
+		tokenScopes: user.TokenScopes,
+		expiresAt:   now.Add(StreamTicketTTL),
+	}
+	return ticket, nil
+}
+
+// Redeem 使用票据，票据无论是否过期都只能使用一次
+func (s *StreamTicketService) Redeem(ticket string) (username, userID string, tokenScopes []string, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, exists := s.tickets[ticket]
+	if !exists {
+		return "", "", nil, false
+	}
+	delete(s.tickets, ticket)
+	if time.Now().After(entry.expiresAt) {
+		return "", "", nil, false
+	}
+	return entry.username, entry.userID, entry.tokenScopes, true
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,26 +24,70 @@ const (
 	UserDataFile = "/data/users.json"
 	// bcrypt 成本参数
 	BcryptCost = 12
+	// 邀请码数据存储文件路径
+	InviteDataFile = "/data/invites.json"
+	// 邀请码默认有效期
+	DefaultInviteTTL = 72 * time.Hour
+)
+
+var (
+	// ErrRegistrationDisabled 自助注册已关闭
+	ErrRegistrationDisabled = errors.New("自助注册已关闭，请联系管理员创建账户")
+	// ErrUserDisabled 用户已被禁用
+	ErrUserDisabled = errors.New("账户已被禁用，请联系管理员")
+	// ErrAuthUnavailable 认证后端（如 LDAP）暂时不可用
+	ErrAuthUnavailable = errors.New("认证服务暂时不可用，请稍后重试")
+	// ErrExternalUser 外部身份提供方的账户没有本地密码
+	ErrExternalUser = errors.New("该账户通过单点登录认证，不能设置本地密码")
+	// ErrPasswordChangeRequired 必须先修改初始密码
+	ErrPasswordChangeRequired = errors.New("请先修改初始密码")
 )
 
 type UserService struct {
-	users    map[string]*models.User
-	mutex    sync.RWMutex
-	dataFile string
+	users            map[string]*models.User
+	invites          map[string]*models.Invite
+	mutex            sync.RWMutex
+	dataFile         string
+	inviteDataFile   string
+	registrationMode string
+	passwordPolicy   models.PasswordPolicy
+	providers        []AuthProvider
+
+	// 两步验证，按用户 ID 保存
+	twoFactor         map[string]*models.TwoFactor
+	twoFactorDataFile string
+	twoFactorPolicy   string
+	totpIssuer        string
+	challenges        map[string]*loginChallenge
 }
 
 func NewUserService() *UserService {
 	service := &UserService{
-		users:    make(map[string]*models.User),
-		dataFile: UserDataFile,
+		users:            make(map[string]*models.User),
+		invites:          make(map[string]*models.Invite),
+		dataFile:         UserDataFile,
+		inviteDataFile:   InviteDataFile,
+		registrationMode: registrationModeFromEnv(),
+		passwordPolicy:   passwordPolicyFromEnv(),
+
+		twoFactor:         make(map[string]*models.TwoFactor),
+		twoFactorDataFile: TwoFactorDataFile,
+		twoFactorPolicy:   twoFactorPolicyFromEnv(),
+		totpIssuer:        envOrDefault("TOTP_ISSUER", "k8s-volume-snapshots"),
+		challenges:        make(map[string]*loginChallenge),
 	}
+	service.providers = []AuthProvider{&localAuthProvider{service: service}}
 
 	// 加载用户数据
 	service.loadUsers()
+	service.loadInvites()
+	service.loadTwoFactor()
 
 	// 如果没有用户，创建默认管理员账户
 	if len(service.users) == 0 {
 		service.createDefaultAdmin()
+	} else {
+		service.flagDefaultAdminPassword()
 	}
 
 	return service
@@ -103,18 +149,85 @@ func (s *UserService) saveUsers() error {
 	return nil
 }
 
+// registrationModeFromEnv 从 REGISTRATION_MODE 读取自助注册模式，默认关闭
+func registrationModeFromEnv() string {
+	mode := os.Getenv("REGISTRATION_MODE")
+	switch mode {
+	case models.RegistrationModeDisabled, models.RegistrationModeReadonly, models.RegistrationModeInvite:
+		return mode
+	case "":
+		return models.RegistrationModeDisabled
+	default:
+		fmt.Printf("警告: 无效的 REGISTRATION_MODE %q，已关闭自助注册\n", mode)
+		return models.RegistrationModeDisabled
+	}
+}
+
+// RegistrationMode 获取自助注册模式
+func (s *UserService) RegistrationMode() string {
+	return s.registrationMode
+}
+
+// loadInvites 从文件加载邀请码
+func (s *UserService) loadInvites() {
+	data, err := ioutil.ReadFile(s.inviteDataFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("读取邀请码数据文件失败: %v\n", err)
+		}
+		return
+	}
+
+	var invites []models.Invite
+	if err := json.Unmarshal(data, &invites); err != nil {
+		fmt.Printf("解析邀请码数据失败: %v\n", err)
+		return
+	}
+
+	for _, invite := range invites {
+		inviteCopy := invite
+		s.invites[invite.ID] = &inviteCopy
+	}
+}
+
+// saveInvites 保存邀请码到文件
+func (s *UserService) saveInvites() error {
+	dir := filepath.Dir(s.inviteDataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	invites := make([]models.Invite, 0, len(s.invites))
+	for _, invite := range s.invites {
+		invites = append(invites, *invite)
+	}
+
+	data, err := json.MarshalIndent(invites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化邀请码数据失败: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.inviteDataFile, data, 0600); err != nil {
+		return fmt.Errorf("写入邀请码数据文件失败: %v", err)
+	}
+
+	return nil
+}
+
 // createDefaultAdmin 创建默认管理员账户
 func (s *UserService) createDefaultAdmin() {
 	defaultAdmin := &models.User{
-		ID:        s.generateID(),
-		Username:  "admin",
-		Role:      "admin",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:       s.generateID(),
+		Username: "admin",
+		Role:     "admin",
+		// 首次登录后必须修改默认密码
+		MustChangePassword: true,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
 	}
 
 	// 设置默认密码为 "admin123"
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("admin123"), BcryptCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(defaultAdminPassword), BcryptCost)
 	if err != nil {
 		fmt.Printf("创建默认管理员账户失败: %v\n", err)
 		return
@@ -129,7 +242,30 @@ func (s *UserService) createDefaultAdmin() {
 		return
 	}
 
-	fmt.Printf("创建默认管理员账户: admin / admin123\n")
+	fmt.Printf("创建默认管理员账户: admin / admin123，首次登录后需要修改密码\n")
+}
+
+// flagDefaultAdminPassword 升级前创建的默认管理员仍在使用默认密码时，要求下次登录后修改
+func (s *UserService) flagDefaultAdminPassword() {
+	admin, exists := s.users["admin"]
+	if !exists || admin.AuthSource != models.AuthSourceLocal || admin.MustChangePassword {
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(defaultAdminPassword)) != nil {
+		return
+	}
+
+	admin.MustChangePassword = true
+	if err := s.saveUsers(); err != nil {
+		fmt.Printf("保存用户数据失败: %v\n", err)
+		return
+	}
+	fmt.Printf("警告: 管理员 admin 仍在使用默认密码，下次登录后需要修改密码\n")
+}
+
+// PasswordPolicy 获取密码策略
+func (s *UserService) PasswordPolicy() models.PasswordPolicy {
+	return s.passwordPolicy
 }
 
 // generateID 生成随机ID
@@ -139,18 +275,68 @@ func (s *UserService) generateID() string {
 	return hex.EncodeToString(bytes)
 }
 
-// Register 用户注册
+// Register 用户自助注册，可注册的角色由注册模式决定
 func (s *UserService) Register(req models.RegisterRequest) (*models.User, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	var invite *models.Invite
+	role := "readonly"
+	switch s.registrationMode {
+	case models.RegistrationModeReadonly:
+		if req.Role != "" && req.Role != "readonly" {
+			return nil, errors.New("自助注册只能创建只读用户")
+		}
+	case models.RegistrationModeInvite:
+		var err error
+		invite, err = s.findInviteLocked(req.InviteToken)
+		if err != nil {
+			return nil, err
+		}
+		role = invite.Role
+	default:
+		return nil, ErrRegistrationDisabled
+	}
+
+	user, err := s.createUserLocked(req.Username, req.Password, role)
+	if err != nil {
+		return nil, err
+	}
+
+	// 邀请码只能使用一次
+	if invite != nil {
+		now := time.Now()
+		invite.UsedBy = user.Username
+		invite.UsedAt = &now
+		if err := s.saveInvites(); err != nil {
+			fmt.Printf("保存邀请码数据失败: %v\n", err)
+		}
+	}
+
+	return user, nil
+}
+
+// CreateUser 管理员创建用户
+func (s *UserService) CreateUser(req models.CreateUserRequest) (*models.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.createUserLocked(req.Username, req.Password, req.Role)
+}
+
+// createUserLocked 创建并保存用户，调用方需持有写锁
+func (s *UserService) createUserLocked(username, password, role string) (*models.User, error) {
 	// 检查用户名是否已存在
-	if _, exists := s.users[req.Username]; exists {
+	if _, exists := s.users[username]; exists {
 		return nil, errors.New("用户名已存在")
 	}
 
+	if err := validatePassword(s.passwordPolicy, username, password); err != nil {
+		return nil, err
+	}
+
 	// 密码加密
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), BcryptCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
 	if err != nil {
 		return nil, fmt.Errorf("密码加密失败: %v", err)
 	}
@@ -158,9 +344,9 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.User, error)
 	// 创建新用户
 	user := &models.User{
 		ID:        s.generateID(),
-		Username:  req.Username,
+		Username:  username,
 		Password:  string(hashedPassword),
-		Role:      req.Role,
+		Role:      role,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -181,26 +367,47 @@ func (s *UserService) Register(req models.RegisterRequest) (*models.User, error)
 	return &userResult, nil
 }
 
-// Login 用户登录验证
+// Login 用户登录验证，按顺序尝试各认证后端
 func (s *UserService) Login(req models.LoginRequest) (*models.User, error) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
-	// 查找用户
-	user, exists := s.users[req.Username]
-	if !exists {
-		return nil, errors.New("用户名或密码错误")
+	// 空密码在 LDAP 中是匿名绑定，会被当作认证成功
+	if req.Password == "" {
+		return nil, errInvalidCredentials
 	}
 
-	// 验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("用户名或密码错误")
+	for _, provider := range s.providers {
+		identity, err := provider.Authenticate(req.Username, req.Password)
+		if errors.Is(err, ErrUnknownUser) {
+			continue
+		}
+		if errors.Is(err, errInvalidCredentials) {
+			return nil, err
+		}
+		if err != nil {
+			// 连接失败等后端错误只记录日志，不暴露给客户端
+			fmt.Printf("认证后端 %s 出错: %v\n", provider.Name(), err)
+			return nil, ErrAuthUnavailable
+		}
+
+		if provider.Name() != models.AuthSourceLocal {
+			return s.ProvisionExternalUser(provider.Name(), identity)
+		}
+
+		user, err := s.GetUser(identity.Username)
+		if err != nil {
+			return nil, errInvalidCredentials
+		}
+		if user.Disabled {
+			return nil, ErrUserDisabled
+		}
+		return user, nil
 	}
 
-	// 返回用户信息时不包含密码
-	userResult := *user
-	userResult.Password = ""
-	return &userResult, nil
+	return nil, errInvalidCredentials
+}
+
+// AddAuthProvider 在本地账户之后追加认证后端
+func (s *UserService) AddAuthProvider(provider AuthProvider) {
+	s.providers = append(s.providers, provider)
 }
 
 // GetUser 根据用户名获取用户信息
@@ -243,20 +450,32 @@ func (s *UserService) ChangePassword(username string, req models.ChangePasswordR
 	if !exists {
 		return errors.New("用户不存在")
 	}
+	if user.AuthSource != models.AuthSourceLocal {
+		return ErrExternalUser
+	}
 
 	// 验证旧密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)); err != nil {
 		return errors.New("原密码错误")
 	}
 
+	if req.NewPassword == req.OldPassword {
+		return errors.New("新密码不能与原密码相同")
+	}
+	if err := validatePassword(s.passwordPolicy, username, req.NewPassword); err != nil {
+		return err
+	}
+
 	// 加密新密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), BcryptCost)
 	if err != nil {
 		return fmt.Errorf("密码加密失败: %v", err)
 	}
 
-	// 更新密码
+	// 更新密码，并使之前签发的登录令牌失效
 	user.Password = string(hashedPassword)
+	user.MustChangePassword = false
+	user.TokenGeneration++
 	user.UpdatedAt = time.Now()
 
 	// 保存到文件
@@ -272,13 +491,13 @@ func (s *UserService) DeleteUser(username string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// 不能删除管理员账户
-	if username == "admin" {
-		return errors.New("不能删除默认管理员账户")
+	user, exists := s.users[username]
+	if !exists {
+		return errors.New("用户不存在")
 	}
 
-	if _, exists := s.users[username]; !exists {
-		return errors.New("用户不存在")
+	if user.Role == "admin" && !user.Disabled && s.activeAdminCountLocked() <= 1 {
+		return errors.New("不能删除最后一个可用的管理员")
 	}
 
 	// 从内存中删除
@@ -289,6 +508,14 @@ func (s *UserService) DeleteUser(username string) error {
 		return fmt.Errorf("保存用户数据失败: %v", err)
 	}
 
+	// 同时删除两步验证配置
+	if _, exists := s.twoFactor[user.ID]; exists {
+		delete(s.twoFactor, user.ID)
+		if err := s.saveTwoFactor(); err != nil {
+			fmt.Printf("保存两步验证数据失败: %v\n", err)
+		}
+	}
+
 	return nil
 }
 
@@ -296,3 +523,294 @@ func (s *UserService) DeleteUser(username string) error {
 func (s *UserService) ValidateUser(username string) (*models.User, error) {
 	return s.GetUser(username)
 }
+
+// SetDefaultCluster 设置用户的默认集群，clusterName 为空表示使用系统默认集群
+func (s *UserService) SetDefaultCluster(username, clusterName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return errors.New("用户不存在")
+	}
+
+	user.DefaultCluster = clusterName
+	user.UpdatedAt = time.Now()
+
+	if err := s.saveUsers(); err != nil {
+		return fmt.Errorf("保存用户数据失败: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateRole 修改用户角色（仅管理员可用），不能降级最后一个可用的管理员
+func (s *UserService) UpdateRole(username, role string) (*models.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errors.New("用户不存在")
+	}
+
+	if user.Role == role {
+		userResult := *user
+		userResult.Password = ""
+		return &userResult, nil
+	}
+
+	if user.Role == "admin" && !user.Disabled && s.activeAdminCountLocked() <= 1 {
+		return nil, errors.New("不能降级最后一个可用的管理员")
+	}
+
+	previousRole := user.Role
+	user.Role = role
+	user.TokenGeneration++
+	user.UpdatedAt = time.Now()
+
+	if err := s.saveUsers(); err != nil {
+		user.Role = previousRole
+		user.TokenGeneration--
+		return nil, fmt.Errorf("保存用户数据失败: %v", err)
+	}
+
+	userResult := *user
+	userResult.Password = ""
+	return &userResult, nil
+}
+
+// ResetPassword 管理员重置用户密码，不需要原密码
+func (s *UserService) ResetPassword(username, newPassword string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return errors.New("用户不存在")
+	}
+	if user.AuthSource != models.AuthSourceLocal {
+		return ErrExternalUser
+	}
+
+	if err := validatePassword(s.passwordPolicy, username, newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), BcryptCost)
+	if err != nil {
+		return fmt.Errorf("密码加密失败: %v", err)
+	}
+
+	user.Password = string(hashedPassword)
+	user.TokenGeneration++
+	user.UpdatedAt = time.Now()
+
+	if err := s.saveUsers(); err != nil {
+		return fmt.Errorf("保存用户数据失败: %v", err)
+	}
+
+	return nil
+}
+
+// SetDisabled 禁用或启用用户（保留账户数据），不能禁用最后一个可用的管理员
+func (s *UserService) SetDisabled(username string, disabled bool) (*models.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errors.New("用户不存在")
+	}
+
+	if disabled && !user.Disabled && user.Role == "admin" && s.activeAdminCountLocked() <= 1 {
+		return nil, errors.New("不能禁用最后一个可用的管理员")
+	}
+
+	if user.Disabled != disabled {
+		user.Disabled = disabled
+		user.DisabledAt = nil
+		if disabled {
+			now := time.Now()
+			user.DisabledAt = &now
+		}
+		user.UpdatedAt = time.Now()
+
+		if err := s.saveUsers(); err != nil {
+			return nil, fmt.Errorf("保存用户数据失败: %v", err)
+		}
+	}
+
+	userResult := *user
+	userResult.Password = ""
+	return &userResult, nil
+}
+
+// activeAdminCountLocked 统计未被禁用的管理员数量，调用方需持有锁
+func (s *UserService) activeAdminCountLocked() int {
+	count := 0
+	for _, user := range s.users {
+		if user.Role == "admin" && !user.Disabled {
+			count++
+		}
+	}
+	return count
+}
+
+// CreateInvite 创建注册邀请码，令牌明文只在此处返回
+func (s *UserService) CreateInvite(req models.CreateInviteRequest, createdBy string) (*models.CreateInviteResponse, error) {
+	tokenBytes := make([]byte, 24)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, fmt.Errorf("生成邀请码失败: %v", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	ttl := DefaultInviteTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	now := time.Now()
+	invite := &models.Invite{
+		ID:        s.generateID(),
+		TokenHash: hashInviteToken(token),
+		Role:      req.Role,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.invites[invite.ID] = invite
+	if err := s.saveInvites(); err != nil {
+		delete(s.invites, invite.ID)
+		return nil, err
+	}
+
+	response := &models.CreateInviteResponse{Invite: *invite, Token: token}
+	response.TokenHash = ""
+	return response, nil
+}
+
+// GetInvites 获取所有邀请码（不包含令牌），按创建时间倒序
+func (s *UserService) GetInvites() []models.Invite {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	invites := make([]models.Invite, 0, len(s.invites))
+	for _, invite := range s.invites {
+		inviteCopy := *invite
+		inviteCopy.TokenHash = ""
+		invites = append(invites, inviteCopy)
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.After(invites[j].CreatedAt)
+	})
+	return invites
+}
+
+// DeleteInvite 撤销邀请码
+func (s *UserService) DeleteInvite(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.invites[id]; !exists {
+		return errors.New("邀请码不存在")
+	}
+
+	delete(s.invites, id)
+	return s.saveInvites()
+}
+
+// findInviteLocked 查找可用的邀请码，调用方需持有锁
+func (s *UserService) findInviteLocked(token string) (*models.Invite, error) {
+	if token == "" {
+		return nil, errors.New("需要邀请码才能注册")
+	}
+
+	tokenHash := hashInviteToken(token)
+	for _, invite := range s.invites {
+		if invite.TokenHash != tokenHash {
+			continue
+		}
+		if invite.UsedAt != nil {
+			return nil, errors.New("邀请码已被使用")
+		}
+		if time.Now().After(invite.ExpiresAt) {
+			return nil, errors.New("邀请码已过期")
+		}
+		return invite, nil
+	}
+	return nil, errors.New("无效的邀请码")
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetGroups 设置用户所属组（仅管理员可用），用于匹配按组授予的角色绑定
+func (s *UserService) SetGroups(username string, groups []string) (*models.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errors.New("用户不存在")
+	}
+
+	user.Groups = groups
+	user.UpdatedAt = time.Now()
+
+	if err := s.saveUsers(); err != nil {
+		return nil, fmt.Errorf("保存用户数据失败: %v", err)
+	}
+
+	userResult := *user
+	userResult.Password = ""
+	return &userResult, nil
+}
+
+// ProvisionExternalUser 外部账户首次登录时创建本地用户，之后每次登录同步角色、组和邮箱
+// 不会接管同名的本地账户或其他来源的账户
+func (s *UserService) ProvisionExternalUser(source string, identity *ExternalIdentity) (*models.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[identity.Username]
+	if !exists {
+		user = &models.User{
+			ID:         s.generateID(),
+			Username:   identity.Username,
+			AuthSource: source,
+			Subject:    identity.Subject,
+			CreatedAt:  time.Now(),
+		}
+		s.users[user.Username] = user
+		fmt.Printf("%s 用户首次登录，创建用户: %s (%s)\n", source, identity.Username, identity.Role)
+	} else if user.AuthSource != source || user.Subject != identity.Subject {
+		return nil, fmt.Errorf("用户名 %s 已被其他账户使用", identity.Username)
+	}
+
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	if exists && user.Role != identity.Role {
+		user.TokenGeneration++
+	}
+	user.Email = identity.Email
+	user.Role = identity.Role
+	user.Groups = identity.Groups
+	user.UpdatedAt = time.Now()
+
+	if err := s.saveUsers(); err != nil {
+		return nil, fmt.Errorf("保存用户数据失败: %v", err)
+	}
+
+	userResult := *user
+	userResult.Password = ""
+	return &userResult, nil
+}
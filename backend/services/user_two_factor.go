@@ -0,0 +1,424 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s-volume-snapshots/models"
+)
+
+const (
+	// 两步验证数据存储文件路径
+	TwoFactorDataFile = "/data/two_factor.json"
+	// 登录第二步的有效期
+	LoginChallengeTTL = 5 * time.Minute
+	// 每个登录挑战允许的验证码尝试次数
+	loginChallengeMaxAttempts = 5
+	// 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrTwoFactorRequired 按策略必须先启用两步验证
+	ErrTwoFactorRequired = errors.New("当前账户拥有写权限，请先启用两步验证")
+	// ErrInvalidTwoFactorCode 验证码或恢复码错误
+	ErrInvalidTwoFactorCode = errors.New("验证码错误")
+	// ErrInvalidLoginChallenge 登录挑战不存在、已过期或尝试次数过多
+	ErrInvalidLoginChallenge = errors.New("登录已过期，请重新输入用户名和密码")
+)
+
+// loginChallenge 密码验证通过、等待输入验证码的登录
+type loginChallenge struct {
+	username  string
+	userID    string
+	expiresAt time.Time
+	attempts  int
+}
+
+// twoFactorPolicyFromEnv 从 TWO_FACTOR_POLICY 读取两步验证策略，默认由用户自行选择
+func twoFactorPolicyFromEnv() string {
+	policy := os.Getenv("TWO_FACTOR_POLICY")
+	switch policy {
+	case models.TwoFactorPolicyOptional, models.TwoFactorPolicyWriters:
+		return policy
+	case "":
+		return models.TwoFactorPolicyOptional
+	default:
+		fmt.Printf("警告: 无效的 TWO_FACTOR_POLICY %q，两步验证改为可选\n", policy)
+		return models.TwoFactorPolicyOptional
+	}
+}
+
+// loadTwoFactor 从文件加载两步验证配置
+func (s *UserService) loadTwoFactor() {
+	data, err := ioutil.ReadFile(s.twoFactorDataFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("读取两步验证数据文件失败: %v\n", err)
+		}
+		return
+	}
+
+	var configs []models.TwoFactor
+	if err := json.Unmarshal(data, &configs); err != nil {
+		fmt.Printf("解析两步验证数据失败: %v\n", err)
+		return
+	}
+
+	for _, config := range configs {
+		configCopy := config
+		s.twoFactor[config.UserID] = &configCopy
+	}
+}
+
+// saveTwoFactor 保存两步验证配置到文件，调用方需持有写锁
+func (s *UserService) saveTwoFactor() error {
+	dir := filepath.Dir(s.twoFactorDataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	configs := make([]models.TwoFactor, 0, len(s.twoFactor))
+	for _, config := range s.twoFactor {
+		configs = append(configs, *config)
+	}
+
+	data, err := json.MarshalIndent(configs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化两步验证数据失败: %v", err)
+	}
+
+	if err := ioutil.WriteFile(s.twoFactorDataFile, data, 0600); err != nil {
+		return fmt.Errorf("写入两步验证数据文件失败: %v", err)
+	}
+
+	return nil
+}
+
+// TwoFactorRequired 按策略判断用户是否必须启用两步验证
+// 单点登录账户的多因素认证由身份提供方负责，不受此策略约束
+func (s *UserService) TwoFactorRequired(user *models.User, hasWritePermission bool) bool {
+	return s.twoFactorPolicy == models.TwoFactorPolicyWriters &&
+		hasWritePermission &&
+		user.AuthSource != models.AuthSourceOIDC
+}
+
+// TwoFactorStatus 获取用户的两步验证状态
+func (s *UserService) TwoFactorStatus(user *models.User, hasWritePermission bool) models.TwoFactorStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	status := models.TwoFactorStatus{Required: s.TwoFactorRequired(user, hasWritePermission)}
+	if config, exists := s.twoFactor[user.ID]; exists && config.Enabled {
+		status.Enabled = true
+		status.RecoveryCodesRemaining = len(config.RecoveryCodes)
+	}
+	return status
+}
+
+// BeginTwoFactorEnrollment 生成新的 TOTP 密钥，输入验证码确认后才会启用
+func (s *UserService) BeginTwoFactorEnrollment(username string) (*models.TwoFactorEnrollResponse, error) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errors.New("用户不存在")
+	}
+	if user.AuthSource == models.AuthSourceOIDC {
+		return nil, errors.New("单点登录账户的两步验证由身份提供方负责")
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.New("已启用两步验证，如需更换设备请先关闭")
+	}
+
+	s.twoFactor[user.ID] = &models.TwoFactor{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if err := s.saveTwoFactor(); err != nil {
+		delete(s.twoFactor, user.ID)
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(s.totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTwoFactorEnrollment 校验验证器应用生成的验证码后启用两步验证，返回恢复码
+func (s *UserService) ConfirmTwoFactorEnrollment(username, code string) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errors.New("用户不存在")
+	}
+	config, exists := s.twoFactor[user.ID]
+	if !exists || config.Enabled {
+		return nil, errors.New("请先开始绑定两步验证")
+	}
+
+	step, ok := verifyTOTP(config.Secret, code, time.Now(), config.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	config.Enabled = true
+	config.EnabledAt = &now
+	config.LastUsedStep = step
+	config.RecoveryCodes = hashes
+	if err := s.saveTwoFactor(); err != nil {
+		config.Enabled = false
+		return nil, err
+	}
+
+	user.TwoFactorEnabled = true
+	user.UpdatedAt = now
+	if err := s.saveUsers(); err != nil {
+		return nil, fmt.Errorf("保存用户数据失败: %v", err)
+	}
+
+	fmt.Printf("用户 %s 已启用两步验证\n", username)
+	return codes, nil
+}
+
+// DisableTwoFactor 用户使用验证码或恢复码关闭两步验证
+func (s *UserService) DisableTwoFactor(username, code string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return errors.New("用户不存在")
+	}
+	config, exists := s.twoFactor[user.ID]
+	if !exists || !config.Enabled {
+		return errors.New("未启用两步验证")
+	}
+
+	if err := s.verifySecondFactorLocked(config, code); err != nil {
+		return err
+	}
+
+	return s.removeTwoFactorLocked(user)
+}
+
+// ResetTwoFactor 管理员为丢失设备的用户关闭两步验证
+func (s *UserService) ResetTwoFactor(username string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return errors.New("用户不存在")
+	}
+	if _, exists := s.twoFactor[user.ID]; !exists {
+		return errors.New("未启用两步验证")
+	}
+
+	return s.removeTwoFactorLocked(user)
+}
+
+func (s *UserService) removeTwoFactorLocked(user *models.User) error {
+	config := s.twoFactor[user.ID]
+	delete(s.twoFactor, user.ID)
+	if err := s.saveTwoFactor(); err != nil {
+		s.twoFactor[user.ID] = config
+		return err
+	}
+
+	user.TwoFactorEnabled = false
+	user.UpdatedAt = time.Now()
+	if err := s.saveUsers(); err != nil {
+		return fmt.Errorf("保存用户数据失败: %v", err)
+	}
+
+	fmt.Printf("用户 %s 已关闭两步验证\n", user.Username)
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，之前的恢复码全部作废
+func (s *UserService) RegenerateRecoveryCodes(username, code string) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errors.New("用户不存在")
+	}
+	config, exists := s.twoFactor[user.ID]
+	if !exists || !config.Enabled {
+		return nil, errors.New("未启用两步验证")
+	}
+
+	if err := s.verifySecondFactorLocked(config, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	config.RecoveryCodes = hashes
+	if err := s.saveTwoFactor(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// NewLoginChallenge 密码验证通过后创建登录挑战，凭挑战和验证码完成登录
+func (s *UserService) NewLoginChallenge(user *models.User) (string, error) {
+	challenge, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("生成登录挑战失败: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for key, pending := range s.challenges {
+		if now.After(pending.expiresAt) {
+			delete(s.challenges, key)
+		}
+	}
+
+	s.challenges[hashChallenge(challenge)] = &loginChallenge{
+		username:  user.Username,
+		userID:    user.ID,
+		expiresAt: now.Add(LoginChallengeTTL),
+	}
+	return challenge, nil
+}
+
+// LoginChallengeUsername 获取登录挑战对应的用户名，用于在校验验证码前检查登录限制
+func (s *UserService) LoginChallengeUsername(challenge string) (string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	pending, exists := s.challenges[hashChallenge(challenge)]
+	if !exists || time.Now().After(pending.expiresAt) {
+		return "", ErrInvalidLoginChallenge
+	}
+	return pending.username, nil
+}
+
+// CompleteLoginChallenge 校验验证码或恢复码，成功后挑战作废并返回用户
+func (s *UserService) CompleteLoginChallenge(challenge, code string) (*models.User, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := hashChallenge(challenge)
+	pending, exists := s.challenges[key]
+	if !exists || time.Now().After(pending.expiresAt) {
+		delete(s.challenges, key)
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	user, exists := s.users[pending.username]
+	if !exists || user.ID != pending.userID {
+		delete(s.challenges, key)
+		return nil, ErrInvalidLoginChallenge
+	}
+	if user.Disabled {
+		delete(s.challenges, key)
+		return nil, ErrUserDisabled
+	}
+
+	config, exists := s.twoFactor[user.ID]
+	if !exists || !config.Enabled {
+		delete(s.challenges, key)
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	if err := s.verifySecondFactorLocked(config, code); err != nil {
+		pending.attempts++
+		if pending.attempts >= loginChallengeMaxAttempts {
+			delete(s.challenges, key)
+		}
+		return nil, err
+	}
+
+	delete(s.challenges, key)
+	userResult := *user
+	userResult.Password = ""
+	return &userResult, nil
+}
+
+// verifySecondFactorLocked 校验 TOTP 验证码或恢复码（恢复码使用后作废），调用方需持有写锁
+func (s *UserService) verifySecondFactorLocked(config *models.TwoFactor, code string) error {
+	if step, ok := verifyTOTP(config.Secret, code, time.Now(), config.LastUsedStep); ok {
+		config.LastUsedStep = step
+		if err := s.saveTwoFactor(); err != nil {
+			fmt.Printf("保存两步验证数据失败: %v\n", err)
+		}
+		return nil
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, stored := range config.RecoveryCodes {
+		if stored != hash {
+			continue
+		}
+		config.RecoveryCodes = append(config.RecoveryCodes[:i:i], config.RecoveryCodes[i+1:]...)
+		if err := s.saveTwoFactor(); err != nil {
+			return err
+		}
+		fmt.Printf("用户 %s 使用了恢复码，剩余 %d 个\n", config.Username, len(config.RecoveryCodes))
+		return nil
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// generateRecoveryCodes 生成恢复码明文和对应的哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		random, err := randomHex(5)
+		if err != nil {
+			return nil, nil, fmt.Errorf("生成恢复码失败: %v", err)
+		}
+		code := random[:5] + "-" + random[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 忽略大小写、空格和连字符后计算哈希
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashChallenge(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return hex.EncodeToString(sum[:])
+}
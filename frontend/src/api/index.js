@@ -30,6 +30,10 @@ api.interceptors.request.use(
 // 响应拦截器
 api.interceptors.response.use(
   response => {
+    // 文件下载直接返回响应，由调用方保存
+    if (response.config.responseType === 'blob') {
+      return response
+    }
     const { data } = response
     if (data.code !== 200) {
       ElMessage.error(data.message || '请求失败')
@@ -37,9 +41,19 @@ api.interceptors.response.use(
     }
     return data.data
   },
-  error => {
+  async error => {
     const message = error.response?.data?.message || error.message || '网络错误'
 
+    // 访问令牌过期时先尝试刷新，成功后重试原请求
+    if (error.response?.status === 401 && !error.config?._retried) {
+      const authStore = useAuthStore()
+      if (await authStore.refresh()) {
+        error.config._retried = true
+        error.config.headers.Authorization = `Bearer ${authStore.token}`
+        return api(error.config)
+      }
+    }
+
     // 处理401认证错误
     if (error.response?.status === 401) {
       console.error('API - response interceptor: 401 Unauthorized received')
@@ -65,12 +79,23 @@ export const getVolumeSnapshotClasses = () => {
 }
 
 // VolumeSnapshot 相关 API
+// 分页查询快照，返回 { items, total, continue }
+// params: namespace, labelSelector, pvcName, ready, createdBy, scheduledTaskId,
+//         createdAfter, createdBefore, sortBy (age/size/name), order, limit, continue
+export const listVolumeSnapshots = (params = {}) => {
+  return api.get('/volumesnapshots', { params })
+}
+
 export const getVolumeSnapshots = (namespace = '') => {
-  return api.get('/volumesnapshots', { params: { namespace } })
+  return listVolumeSnapshots({ namespace }).then(page => page.items)
+}
+
+export const createVolumeSnapshot = (data, params) => {
+  return api.post('/volumesnapshots', data, { params })
 }
 
-export const createVolumeSnapshot = (data) => {
-  return api.post('/volumesnapshots', data)
+export const getVolumeSnapshotTracking = (namespace, name) => {
+  return api.get(`/volumesnapshots/${namespace}/${name}/tracking`)
 }
 
 export const deleteVolumeSnapshot = (namespace, name) => {
@@ -81,18 +106,50 @@ export const forceDeleteVolumeSnapshot = (namespace, name) => {
   return api.post(`/volumesnapshots/${namespace}/${name}/force-delete`)
 }
 
+export const restoreVolumeSnapshot = (namespace, name, data) => {
+  return api.post(`/volumesnapshots/${namespace}/${name}/restore`, data)
+}
+
+export const rollbackVolumeSnapshot = (namespace, name, confirm) => {
+  return api.post(`/volumesnapshots/${namespace}/${name}/rollback`, { confirm })
+}
+
+// 异步操作进度 API
+export const getOperation = (id) => {
+  return api.get(`/operations/${id}`)
+}
+
+// 订阅资源变更事件（SSE），返回 Promise<EventSource>，调用 close() 取消订阅
+// 先获取一次性票据再建立连接，访问令牌不出现在 URL 中；重连需重新调用
+// params: namespace, kinds（如 'VolumeSnapshot,PersistentVolumeClaim'）
+export const subscribeEvents = async (params = {}, onEvent) => {
+  const { ticket } = await api.post('/events/ticket')
+  const query = new URLSearchParams({ ...params, ticket })
+  const source = new EventSource(`${api.defaults.baseURL}/events/stream?${query}`)
+  source.onmessage = (message) => {
+    onEvent(JSON.parse(message.data))
+  }
+  return source
+}
+
 // VolumeSnapshotContent 相关 API
 export const getVolumeSnapshotContent = (name) => {
   return api.get(`/volumesnapshotcontents/${name}`)
 }
 
 // PVC 相关 API
+// 分页查询 PVC，返回 { items, total, continue }
+// params: namespace, labelSelector, createdBy, createdAfter, createdBefore, sortBy, order, limit, continue
+export const listPVCs = (params = {}) => {
+  return api.get('/pvcs', { params })
+}
+
 export const getPVCs = (namespace = 'default') => {
   console.log('API - getPVCs: requesting PVCs for namespace:', namespace)
-  return api.get('/pvcs', { params: { namespace } })
+  return listPVCs({ namespace })
     .then(response => {
       console.log('API - getPVCs: response received:', response)
-      return response
+      return response.items
     })
     .catch(error => {
       console.error('API - getPVCs: request failed:', error)
@@ -100,6 +157,10 @@ export const getPVCs = (namespace = 'default') => {
     })
 }
 
+export const clonePVC = (namespace, name, data) => {
+  return api.post(`/pvcs/${namespace}/${name}/clone`, data)
+}
+
 // Namespace 相关 API
 export const getNamespaces = () => {
   return api.get('/namespaces')
@@ -131,6 +192,14 @@ export const toggleScheduledSnapshot = (id) => {
   return api.post(`/scheduled-snapshots/${id}/toggle`)
 }
 
+export const getScheduledSnapshotRuns = (id, page = 1, pageSize = 20) => {
+  return api.get(`/scheduled-snapshots/${id}/runs`, { params: { page, pageSize } })
+}
+
+export const previewScheduledSnapshotRetention = (id) => {
+  return api.get(`/scheduled-snapshots/${id}/retention/preview`)
+}
+
 // 用户认证相关 API
 export const login = (credentials) => {
   return api.post('/auth/login', credentials)
@@ -157,6 +226,152 @@ export const deleteUser = (username) => {
   return api.delete(`/user/${username}`)
 }
 
+export const createUser = (userData) => {
+  return api.post('/user', userData)
+}
+
+export const updateUserRole = (username, role) => {
+  return api.put(`/user/${username}/role`, { role })
+}
+
+export const resetUserPassword = (username, newPassword) => {
+  return api.post(`/user/${username}/reset-password`, { newPassword })
+}
+
+export const disableUser = (username) => {
+  return api.post(`/user/${username}/disable`)
+}
+
+export const enableUser = (username) => {
+  return api.post(`/user/${username}/enable`)
+}
+
+export const resetUserTwoFactor = (username) => {
+  return api.post(`/user/${username}/2fa/reset`)
+}
+
+export const getTwoFactorStatus = () => {
+  return api.get('/user/2fa')
+}
+
+export const enrollTwoFactor = () => {
+  return api.post('/user/2fa/enroll')
+}
+
+export const verifyTwoFactor = (code) => {
+  return api.post('/user/2fa/verify', { code })
+}
+
+export const disableTwoFactor = (code) => {
+  return api.post('/user/2fa/disable', { code })
+}
+
+export const regenerateRecoveryCodes = (code) => {
+  return api.post('/user/2fa/recovery-codes', { code })
+}
+
+export const getLoginLockouts = () => {
+  return api.get('/user/lockouts')
+}
+
+export const unlockUser = (username) => {
+  return api.post(`/user/${username}/unlock`)
+}
+
+export const getInvites = () => {
+  return api.get('/user/invites')
+}
+
+export const createInvite = (data) => {
+  return api.post('/user/invites', data)
+}
+
+export const deleteInvite = (id) => {
+  return api.delete(`/user/invites/${id}`)
+}
+
+export const getRegistrationInfo = () => {
+  return api.get('/auth/registration')
+}
+
+export const getOIDCConfig = () => {
+  return api.get('/auth/oidc/config')
+}
+
+export const updateUserGroups = (username, groups) => {
+  return api.put(`/user/${username}/groups`, { groups })
+}
+
+// 权限相关 API
+export const getMyPermissions = () => {
+  return api.get('/user/permissions')
+}
+
+export const getAPITokens = () => {
+  return api.get('/user/tokens')
+}
+
+export const getAllAPITokens = () => {
+  return api.get('/user/tokens/all')
+}
+
+export const createAPIToken = (data) => {
+  return api.post('/user/tokens', data)
+}
+
+export const createUserAPIToken = (username, data) => {
+  return api.post(`/user/${username}/tokens`, data)
+}
+
+export const revokeAPIToken = (id) => {
+  return api.delete(`/user/tokens/${id}`)
+}
+
+export const getRoles = () => {
+  return api.get('/rbac/roles')
+}
+
+export const createRole = (data) => {
+  return api.post('/rbac/roles', data)
+}
+
+export const updateRole = (name, data) => {
+  return api.put(`/rbac/roles/${name}`, data)
+}
+
+export const deleteRole = (name) => {
+  return api.delete(`/rbac/roles/${name}`)
+}
+
+export const getRoleBindings = () => {
+  return api.get('/rbac/bindings')
+}
+
+export const createRoleBinding = (data) => {
+  return api.post('/rbac/bindings', data)
+}
+
+export const updateRoleBinding = (id, data) => {
+  return api.put(`/rbac/bindings/${id}`, data)
+}
+
+export const deleteRoleBinding = (id) => {
+  return api.delete(`/rbac/bindings/${id}`)
+}
+
+// 审计日志相关 API
+export const getAuditRecords = (params = {}) => {
+  return api.get('/audit', { params })
+}
+
+export const exportAuditRecords = (params = {}, format = 'csv') => {
+  return api.get('/audit', { params: { ...params, format }, responseType: 'blob' })
+}
+
+export const verifyAuditLog = () => {
+  return api.get('/audit/verify')
+}
+
 // 集群管理相关 API
 export const getClusters = () => {
   return api.get('/clusters')
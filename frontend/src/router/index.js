@@ -9,6 +9,7 @@ import CephCluster from '../views/CephCluster.vue'
 import Login from '../views/Login.vue'
 import UserManagement from '../views/UserManagement.vue'
 import ClusterManagement from '../views/ClusterManagement.vue'
+import AuditLog from '../views/AuditLog.vue'
 
 const routes = [
   {
@@ -88,6 +89,16 @@ const routes = [
       title: '用户管理'
     }
   },
+  {
+    path: '/audit',
+    name: 'AuditLog',
+    component: AuditLog,
+    meta: {
+      requiresAuth: true,
+      requiresAdmin: true,
+      title: '审计日志'
+    }
+  },
   {
     path: '/clusters',
     name: 'ClusterManagement',
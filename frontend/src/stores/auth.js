@@ -1,10 +1,14 @@
 import { defineStore } from 'pinia'
 import { ElMessage, ElMessageBox } from 'element-plus'
 
+// 正在进行的刷新请求
+let refreshPromise = null
+
 export const useAuthStore = defineStore('auth', {
   state: () => ({
     user: null,
     token: localStorage.getItem('token') || null,
+    refreshToken: localStorage.getItem('refreshToken') || null,
     isAuthenticated: false
   }),
 
@@ -17,20 +21,32 @@ export const useAuthStore = defineStore('auth', {
 
   actions: {
     // 设置认证信息
-    setAuth (token, user) {
+    setAuth (token, user, refreshToken) {
       this.token = token
       this.user = user
       this.isAuthenticated = true
       localStorage.setItem('token', token)
       localStorage.setItem('user', JSON.stringify(user))
+      if (refreshToken) {
+        this.refreshToken = refreshToken
+        localStorage.setItem('refreshToken', refreshToken)
+      }
     },
 
-    // 清除认证信息
+    // 更新当前用户信息（如启用两步验证后）
+    updateUser (fields) {
+      this.user = { ...this.user, ...fields }
+      localStorage.setItem('user', JSON.stringify(this.user))
+    },
+
+        // 清除认证信息
     clearAuth () {
       this.token = null
+      this.refreshToken = null
       this.user = null
       this.isAuthenticated = false
       localStorage.removeItem('token')
+      localStorage.removeItem('refreshToken')
       localStorage.removeItem('user')
     },
 
@@ -80,6 +96,11 @@ export const useAuthStore = defineStore('auth', {
           }
         }
 
+        // 访问令牌过期时用刷新令牌换取新的令牌
+        if (response.status === 401 && await this.refresh()) {
+          return true
+        }
+
         // Token 无效，清除认证信息
         this.clearAuth()
         return false
@@ -104,8 +125,13 @@ export const useAuthStore = defineStore('auth', {
 
         const data = await response.json()
 
+        // 启用了两步验证，需要继续提交验证码
+        if (data.code === 200 && data.data?.twoFactorRequired) {
+          return { success: false, twoFactorRequired: true, challenge: data.data.challenge }
+        }
+
         if (data.code === 200) {
-          this.setAuth(data.data.token, data.data.user)
+          this.setAuth(data.data.token, data.data.user, data.data.refreshToken)
           ElMessage.success('登录成功')
           return { success: true, data: data.data }
         } else {
@@ -119,6 +145,95 @@ export const useAuthStore = defineStore('auth', {
       }
     },
 
+    // 登录第二步：提交验证码或恢复码
+    async loginTwoFactor (challenge, code) {
+      try {
+        const baseURL = process.env.NODE_ENV === 'production' ? '/api' : 'http://localhost:8081/api'
+        const response = await fetch(`${baseURL}/auth/login/2fa`, {
+          method: 'POST',
+          headers: {
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify({ challenge, code })
+        })
+
+        const data = await response.json()
+
+        if (data.code === 200) {
+          this.setAuth(data.data.token, data.data.user, data.data.refreshToken)
+          ElMessage.success('登录成功')
+          return { success: true, data: data.data }
+        } else {
+          ElMessage.error(data.message || '验证失败')
+          return { success: false, message: data.message }
+        }
+      } catch (error) {
+        const message = '网络错误，请稍后重试'
+        ElMessage.error(message)
+        return { success: false, message }
+      }
+    },
+
+    // 单点登录回调后使用服务端签发的 token 登录
+    async loginWithToken (token, refreshToken) {
+      this.token = token
+      localStorage.setItem('token', token)
+      if (refreshToken) {
+        this.refreshToken = refreshToken
+        localStorage.setItem('refreshToken', refreshToken)
+      }
+      const valid = await this.checkToken()
+      if (valid) {
+        ElMessage.success('登录成功')
+      } else {
+        ElMessage.error('单点登录失败，请重试')
+      }
+      return valid
+    },
+
+    // 使用刷新令牌换取新的访问令牌；刷新令牌每次使用后轮换，
+    // 并发请求共用同一次刷新，避免旧令牌被重复使用导致会话被吊销
+    refresh () {
+      if (!this.refreshToken) {
+        return Promise.resolve(false)
+      }
+      if (refreshPromise) {
+        return refreshPromise
+      }
+
+      const baseURL = process.env.NODE_ENV === 'production' ? '/api' : 'http://localhost:8081/api'
+      refreshPromise = fetch(`${baseURL}/auth/refresh`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ refreshToken: this.refreshToken })
+      })
+        .then(response => response.json())
+        .then(data => {
+          if (data.code === 200) {
+            this.setAuth(data.data.token, data.data.user, data.data.refreshToken)
+            return true
+          }
+          return false
+        })
+        .catch(error => {
+          console.error('Token refresh failed:', error)
+          return false
+        })
+        .finally(() => {
+          refreshPromise = null
+        })
+
+      return refreshPromise
+    },
+
+    // 单点登录入口地址，由后端跳转到身份提供方
+    ssoLoginURL () {
+      const baseURL = process.env.NODE_ENV === 'production' ? '/api' : 'http://localhost:8081/api'
+      return `${baseURL}/auth/oidc/login`
+    },
+
     // 注册
     async register (userData) {
       try {
@@ -149,7 +264,21 @@ export const useAuthStore = defineStore('auth', {
 
     // 退出登录
     async logout (showConfirm = true) {
-      const doLogout = () => {
+      const doLogout = async () => {
+        // 通知服务端吊销访问令牌和刷新令牌，失败时仍然清除本地状态
+        try {
+          const baseURL = process.env.NODE_ENV === 'production' ? '/api' : 'http://localhost:8081/api'
+          await fetch(`${baseURL}/auth/logout`, {
+            method: 'POST',
+            headers: {
+              'Content-Type': 'application/json',
+              Authorization: `Bearer ${this.token}`
+            },
+            body: JSON.stringify({ refreshToken: this.refreshToken })
+          })
+        } catch (error) {
+          console.error('Logout request failed:', error)
+        }
         this.clearAuth()
         ElMessage.success('已退出登录')
         // 跳转到登录页
@@ -163,12 +292,25 @@ export const useAuthStore = defineStore('auth', {
             cancelButtonText: '取消',
             type: 'warning'
           })
-          doLogout()
+          await doLogout()
         } catch {
           // 用户取消
         }
       } else {
-        doLogout()
+        await doLogout()
+      }
+    },
+
+    // 获取密码策略
+    async getPasswordPolicy () {
+      try {
+        const baseURL = process.env.NODE_ENV === 'production' ? '/api' : 'http://localhost:8081/api'
+        const response = await fetch(`${baseURL}/auth/password-policy`)
+        const data = await response.json()
+        return data.code === 200 ? data.data : null
+      } catch (error) {
+        console.error('Failed to load password policy:', error)
+        return null
       }
     },
 
@@ -188,6 +330,10 @@ export const useAuthStore = defineStore('auth', {
         const data = await response.json()
 
         if (data.code === 200) {
+          // 修改密码后旧令牌全部失效，使用服务端为当前会话签发的新令牌
+          if (data.data?.token) {
+            this.setAuth(data.data.token, data.data.user || this.user, data.data.refreshToken)
+          }
           ElMessage.success('密码修改成功')
           return { success: true }
         } else {